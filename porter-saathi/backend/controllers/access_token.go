@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// newAccessToken returns a random, unguessable token for reading one record without signing in
+func newAccessToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashAccessToken is what gets stored, so a database dump does not hand out access
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// accessTokenMatches checks a presented token against a stored hash in constant time.
+// Records without a hash can never be read by token.
+func accessTokenMatches(token, hash string) bool {
+	return hash != "" && subtle.ConstantTimeCompare([]byte(hashAccessToken(token)), []byte(hash)) == 1
+}
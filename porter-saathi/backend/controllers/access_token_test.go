@@ -0,0 +1,36 @@
+package controllers
+
+import "testing"
+
+func TestAccessTokenMatches(t *testing.T) {
+	token, err := newAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := newAccessToken()
+	if token == other || len(token) != 43 {
+		t.Fatalf("tokens %q and %q should be distinct 32-byte values", token, other)
+	}
+	hash := hashAccessToken(token)
+
+	tests := []struct {
+		name  string
+		token string
+		hash  string
+		want  bool
+	}{
+		{"right token", token, hash, true},
+		{"other token", other, hash, false},
+		{"no token", "", hash, false},
+		{"hash instead of token", hash, hash, false},
+		{"record without a hash", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accessTokenMatches(tt.token, tt.hash); got != tt.want {
+				t.Errorf("accessTokenMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"strings"
 	"time"
 
 	"porter-saathi-backend/config"
@@ -134,9 +135,12 @@ func Signup(c *gin.Context) {
 		Mobile:           request.Mobile,
 		Name:             request.Name,
 		AadharNumber:     request.AadharNumber,
+		DateOfBirth:      request.DateOfBirth,
 		LicenseNumber:    request.LicenseNumber,
 		VehicleNumber:    request.VehicleNumber,
 		EmergencyContact: request.EmergencyContact,
+		Timezone:         utils.DefaultTimezone,
+		WeekStart:        strings.ToLower(utils.DefaultWeekStart.String()),
 		IsVerified:       false,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// benchmarkWindowDays is the period benchmarks are computed over
+	benchmarkWindowDays = 28
+	// benchmarkMinDays is how many days a driver must have worked in the window to be counted
+	benchmarkMinDays = 7
+	// benchmarkMinCohortFloor is the smallest cohort that can ever be published,
+	// whatever BENCHMARK_MIN_COHORT is set to
+	benchmarkMinCohortFloor = 5
+	// benchmarkTimezone is the calendar benchmark windows follow
+	benchmarkTimezone = "Asia/Kolkata"
+	// benchmarkNoiseShare scales the noise added to stored percentiles, as a share
+	// of the cohort's interquartile range
+	benchmarkNoiseShare = 0.1
+)
+
+// driverBenchmarkTotals is one driver's totals over the benchmark window
+type driverBenchmarkTotals struct {
+	UserID   primitive.ObjectID `bson:"_id"`
+	Days     int                `bson:"days"`
+	Revenue  float64            `bson:"revenue"`
+	Expenses float64            `bson:"expenses"`
+	Trips    int                `bson:"trips"`
+}
+
+func (t driverBenchmarkTotals) netPerDay() float64 {
+	return (t.Revenue - t.Expenses) / float64(t.Days)
+}
+
+func (t driverBenchmarkTotals) tripsPerDay() float64 {
+	return float64(t.Trips) / float64(t.Days)
+}
+
+// GetEarningsBenchmarks compares the driver's last four weeks with drivers of the same
+// vehicle class in their city, or across all cities when their city has too few drivers
+func GetEarningsBenchmarks(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	err = config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": objectID},
+		options.FindOne().SetProjection(bson.M{"city": 1, "vehicle_class": 1, "address": 1}),
+	).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	from, to := benchmarkWindow(time.Now())
+	response := models.BenchmarkResponse{WindowFrom: from, WindowTo: to}
+	cityKey := utils.CityKey(driverCity(user))
+	if cityKey == "" || user.VehicleClass == "" {
+		response.Message = "Set your city and vehicle class in your profile to compare with other drivers"
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	response.City = utils.CityName(cityKey)
+	response.VehicleClass = user.VehicleClass
+
+	totals, err := benchmarkTotals(bson.M{"user_id": objectID}, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load earnings"})
+		return
+	}
+	if len(totals) > 0 {
+		response.DaysWorked = totals[0].Days
+	}
+	if response.DaysWorked < benchmarkMinDays {
+		response.Message = "Record at least 7 working days in the last 4 weeks to compare with other drivers"
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// The city cohort is preferred; small cohorts are never stored, so fall back to all cities
+	var benchmark models.EarningsBenchmark
+	collection := config.GetDB().Collection("earnings_benchmarks")
+	err = collection.FindOne(context.Background(), bson.M{"_id": benchmarkCohortKey(cityKey, user.VehicleClass)}).Decode(&benchmark)
+	if err == mongo.ErrNoDocuments {
+		err = collection.FindOne(context.Background(), bson.M{"_id": benchmarkCohortKey("", user.VehicleClass)}).Decode(&benchmark)
+	}
+	if err == mongo.ErrNoDocuments {
+		response.Message = "Not enough drivers with your vehicle class yet to compare fairly"
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	driver := totals[0]
+	computedAt := benchmark.ComputedAt
+	response.Available = true
+	response.Cohort = benchmark.Level
+	response.CohortSize = benchmark.Drivers
+	response.WindowFrom, response.WindowTo = benchmark.WindowFrom.In(from.Location()), benchmark.WindowTo.In(from.Location())
+	response.ComputedAt = &computedAt
+	response.NetPerDay = benchmarkMetric(benchmark.NetPerDay, utils.RoundMoney(driver.netPerDay()), true)
+	response.TripsPerDay = benchmarkMetric(benchmark.TripsPerDay, utils.RoundMoney(driver.tripsPerDay()), true)
+	if driver.Revenue > 0 && len(benchmark.ExpenseRatio) > 0 {
+		response.ExpenseRatio = benchmarkMetric(benchmark.ExpenseRatio, utils.RoundMoney(driver.Expenses/driver.Revenue*100), false)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// StartBenchmarkScheduler recomputes benchmarks at startup and every
+// BENCHMARK_REFRESH_HOURS (default 24). A run is skipped when another instance
+// has refreshed them recently.
+func StartBenchmarkScheduler(ctx context.Context) {
+	interval := time.Duration(envInt("BENCHMARK_REFRESH_HOURS", 24)) * time.Hour
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := refreshBenchmarksIfStale(ctx, interval); err != nil {
+				log.Printf("Error refreshing earnings benchmarks: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func refreshBenchmarksIfStale(ctx context.Context, interval time.Duration) error {
+	var latest models.EarningsBenchmark
+	err := config.GetDB().Collection("earnings_benchmarks").FindOne(
+		ctx,
+		bson.M{},
+		options.FindOne().SetSort(bson.D{{Key: "computed_at", Value: -1}}).SetProjection(bson.M{"computed_at": 1, "net_per_day": 1}),
+	).Decode(&latest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	// Benchmarks stored with a different percentile grid are replaced straight away
+	current := len(latest.NetPerDay) == len(models.BenchmarkPercentiles)
+	if err == nil && current && time.Since(latest.ComputedAt) < interval/2 {
+		return nil
+	}
+	return refreshBenchmarks(ctx, time.Now())
+}
+
+// refreshBenchmarks groups drivers who worked enough days in the window by city and
+// vehicle class, and by vehicle class across all cities, and stores the percentiles of
+// each cohort with at least BENCHMARK_MIN_COHORT (default 10) drivers. Cohorts that
+// have become too small are removed.
+func refreshBenchmarks(ctx context.Context, now time.Time) error {
+	minCohort := max(envInt("BENCHMARK_MIN_COHORT", 10), benchmarkMinCohortFloor)
+	from, to := benchmarkWindow(now)
+	totals, err := benchmarkTotals(bson.M{}, from, to)
+	if err != nil {
+		return err
+	}
+
+	// Only drivers who worked enough days to have a typical day are counted
+	totals = slices.DeleteFunc(totals, func(driver driverBenchmarkTotals) bool {
+		return driver.Days < benchmarkMinDays
+	})
+	userIDs := make([]primitive.ObjectID, len(totals))
+	for i, driver := range totals {
+		userIDs[i] = driver.UserID
+	}
+	cursor, err := config.GetDB().Collection("users").Find(
+		ctx,
+		bson.M{"_id": bson.M{"$in": userIDs}, "vehicle_class": bson.M{"$in": models.VehicleClasses}},
+		options.Find().SetProjection(bson.M{"city": 1, "vehicle_class": 1, "address": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+	profiles := make(map[primitive.ObjectID]models.User, len(users))
+	for _, user := range users {
+		profiles[user.ID] = user
+	}
+
+	cohorts := make(map[string]*models.EarningsBenchmark)
+	type cohortValues struct{ net, trips, ratio []float64 }
+	values := make(map[string]*cohortValues)
+	add := func(key, level, city, class string, driver driverBenchmarkTotals) {
+		if cohorts[key] == nil {
+			cohorts[key] = &models.EarningsBenchmark{CohortKey: key, Level: level, City: city, VehicleClass: class}
+			values[key] = &cohortValues{}
+		}
+		cohorts[key].Drivers++
+		v := values[key]
+		v.net = append(v.net, driver.netPerDay())
+		v.trips = append(v.trips, driver.tripsPerDay())
+		if driver.Revenue > 0 {
+			v.ratio = append(v.ratio, driver.Expenses/driver.Revenue*100)
+		}
+	}
+	for _, driver := range totals {
+		user, ok := profiles[driver.UserID]
+		if !ok {
+			continue
+		}
+		add(benchmarkCohortKey("", user.VehicleClass), models.CohortNational, "", user.VehicleClass, driver)
+		if cityKey := utils.CityKey(driverCity(user)); cityKey != "" {
+			add(benchmarkCohortKey(cityKey, user.VehicleClass), models.CohortCity, utils.CityName(cityKey), user.VehicleClass, driver)
+		}
+	}
+
+	collection := config.GetDB().Collection("earnings_benchmarks")
+	computedAt := now.Truncate(time.Millisecond)
+	published := 0
+	for key, benchmark := range cohorts {
+		if benchmark.Drivers < minCohort {
+			continue
+		}
+		v := values[key]
+		benchmark.WindowFrom, benchmark.WindowTo, benchmark.ComputedAt = from, to, computedAt
+		benchmark.NetPerDay = roundedPercentiles(v.net)
+		benchmark.TripsPerDay = roundedPercentiles(v.trips)
+		benchmark.ExpenseRatio = []float64{}
+		if len(v.ratio) >= minCohort {
+			benchmark.ExpenseRatio = roundedPercentiles(v.ratio)
+		}
+		if _, err := collection.ReplaceOne(ctx, bson.M{"_id": key}, benchmark, options.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+		published++
+	}
+	if _, err := collection.DeleteMany(ctx, bson.M{"computed_at": bson.M{"$lt": computedAt}}); err != nil {
+		return err
+	}
+
+	log.Printf("Refreshed earnings benchmarks: %d drivers, %d of %d cohorts published", len(profiles), published, len(cohorts))
+	return nil
+}
+
+// benchmarkTotals adds up each driver's entries in [from, to), counting the days they worked.
+// Flagged entries are left out.
+func benchmarkTotals(match bson.M, from, to time.Time) ([]driverBenchmarkTotals, error) {
+	match["deleted_at"] = bson.M{"$exists": false}
+	match["date"] = bson.M{"$gte": from, "$lt": to}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: excludeFlagged(match)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"user_id": "$user_id", "date": "$date"},
+			"revenue":  bson.M{"$sum": "$revenue"},
+			"expenses": bson.M{"$sum": "$expenses"},
+			"trips":    bson.M{"$sum": "$trips"},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$_id.user_id",
+			"days":     bson.M{"$sum": 1},
+			"revenue":  bson.M{"$sum": "$revenue"},
+			"expenses": bson.M{"$sum": "$expenses"},
+			"trips":    bson.M{"$sum": "$trips"},
+		}}},
+	}
+	cursor, err := config.GetDB().Collection("earnings").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var totals []driverBenchmarkTotals
+	if err := cursor.All(context.Background(), &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// benchmarkMetric places value on a cohort's percentile grid
+func benchmarkMetric(grid []float64, value float64, higherIsBetter bool) *models.BenchmarkMetric {
+	if len(grid) != len(models.BenchmarkPercentiles) {
+		return nil
+	}
+	return &models.BenchmarkMetric{
+		Value:          value,
+		Percentile:     utils.RoundMoney(utils.PercentileRank(grid, models.BenchmarkPercentiles, value)),
+		Median:         grid[1],
+		P25:            grid[0],
+		P75:            grid[2],
+		HigherIsBetter: higherIsBetter,
+	}
+}
+
+// roundedPercentiles is the cohort's grid at BenchmarkPercentiles with Laplace noise
+// added, so a stored figure cannot be traced back to the driver it fell on
+func roundedPercentiles(values []float64) []float64 {
+	grid := utils.PercentileValues(values, models.BenchmarkPercentiles)
+	scale := benchmarkNoiseShare * (grid[len(grid)-1] - grid[0])
+	for i := range grid {
+		grid[i] = utils.RoundMoney(grid[i] + utils.LaplaceNoise(scale))
+	}
+	sort.Float64s(grid)
+	return grid
+}
+
+// benchmarkWindow is the 28 days before today, in IST
+func benchmarkWindow(now time.Time) (time.Time, time.Time) {
+	loc := utils.LoadTimezone(benchmarkTimezone)
+	to := utils.StartOfDay(now, loc)
+	return to.AddDate(0, 0, -benchmarkWindowDays), to
+}
+
+func benchmarkCohortKey(cityKey, vehicleClass string) string {
+	if cityKey == "" {
+		return "all|" + vehicleClass
+	}
+	return cityKey + "|" + vehicleClass
+}
+
+// driverCity is the city on the profile, or the district of the verified address
+func driverCity(user models.User) string {
+	if user.City != "" {
+		return user.City
+	}
+	if user.Address != nil {
+		return user.Address.District
+	}
+	return ""
+}
@@ -1,16 +1,18 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"porter-saathi-backend/config"
+	"porter-saathi-backend/llm"
 	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -33,32 +35,7 @@ func SendMessage(c *gin.Context) {
 		return
 	}
 
-	// Get or create chat session
-	sessionID := c.Query("sessionId")
-	var session models.ChatSession
-	collection := config.GetDB().Collection("chat_sessions")
-
-	if sessionID != "" {
-		sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
-		if err == nil {
-			collection.FindOne(context.Background(), bson.M{"_id": sessionObjectID}).Decode(&session)
-		}
-	}
-
-	// Create new session if not found
-	if session.ID.IsZero() {
-		session = models.ChatSession{
-			ID:          primitive.NewObjectID(),
-			UserID:      objectID,
-			SessionType: request.SessionType,
-			Language:    request.Language,
-			Messages:    []models.ChatMessage{},
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		}
-	}
-
-	// Add user message
+	session, stored := loadChatSession(objectID, c.Query("sessionId"), request)
 	userMessage := models.ChatMessage{
 		Sender:    "user",
 		Text:      request.Message,
@@ -66,37 +43,22 @@ func SendMessage(c *gin.Context) {
 	}
 	session.Messages = append(session.Messages, userMessage)
 
-	// Fetch user earnings data for AI context
-	earningsData, weeklyData := fetchUserEarningsData(objectID)
-
 	// Get AI response
-	aiResponse, err := getAIResponse(request.Message, session.Messages, request.SessionType, request.Language, earningsData, weeklyData)
+	aiResponse, err := getAIResponse(session, request.SessionType, request.Language, fetchChatContext(objectID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get AI response"})
 		return
 	}
 
-	// Add AI message
 	aiMessage := models.ChatMessage{
 		Sender:    "ai",
 		Text:      aiResponse,
 		Timestamp: time.Now(),
 	}
-	session.Messages = append(session.Messages, aiMessage)
-	session.UpdatedAt = time.Now()
-
-	// Save session
-	if sessionID == "" {
-		collection.InsertOne(context.Background(), session)
+	if err := saveChatTurn(&session, stored, userMessage, aiMessage); err != nil {
+		log.Printf("Error saving chat session: %v", err)
 	} else {
-		collection.UpdateOne(
-			context.Background(),
-			bson.M{"_id": session.ID},
-			bson.M{"$set": bson.M{
-				"messages":   session.Messages,
-				"updated_at": session.UpdatedAt,
-			}},
-		)
+		go services.SummarizeChatSession(session, llm.ForSession(request.SessionType))
 	}
 
 	response := models.ChatResponse{
@@ -108,6 +70,85 @@ func SendMessage(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// loadChatSession finds the driver's session named by sessionID, or starts a new one;
+// stored reports whether the session is already in the database
+func loadChatSession(userID primitive.ObjectID, sessionID string, request models.ChatRequest) (models.ChatSession, bool) {
+	var session models.ChatSession
+	if sessionObjectID, err := primitive.ObjectIDFromHex(sessionID); err == nil {
+		err := config.GetDB().Collection("chat_sessions").FindOne(context.Background(), bson.M{
+			"_id":     sessionObjectID,
+			"user_id": userID,
+		}).Decode(&session)
+		if err == nil {
+			return session, true
+		}
+	}
+
+	now := time.Now()
+	return models.ChatSession{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		SessionType: request.SessionType,
+		Language:    request.Language,
+		Messages:    []models.ChatMessage{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, false
+}
+
+// saveChatTurn stores a question and its answer together. session.Messages must
+// already end with userMessage; aiMessage is appended to it. Existing sessions are
+// pushed to rather than overwritten, so two turns sent at once both survive.
+func saveChatTurn(session *models.ChatSession, stored bool, userMessage, aiMessage models.ChatMessage) error {
+	session.Messages = append(session.Messages, aiMessage)
+	session.UpdatedAt = time.Now()
+
+	collection := config.GetDB().Collection("chat_sessions")
+	if !stored {
+		_, err := collection.InsertOne(context.Background(), session)
+		return err
+	}
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": session.ID},
+		bson.M{
+			"$push": bson.M{"messages": bson.M{"$each": []models.ChatMessage{userMessage, aiMessage}}},
+			"$set":  bson.M{"updated_at": session.UpdatedAt},
+		},
+	)
+	return err
+}
+
+// chatContext is what the assistant knows about the driver; any part may be nil
+type chatContext struct {
+	earnings *models.EarningsResponse
+	weekly   *models.WeeklyEarningsResponse
+	goals    *models.GoalsResponse
+	insights *models.EarningsInsights
+}
+
+// fetchChatContext gathers what the assistant knows about the driver. Goals and
+// insights take long aggregations, so they are cached with the earnings snapshot
+// rather than rebuilt for every message.
+func fetchChatContext(userID primitive.ObjectID) chatContext {
+	var driver chatContext
+	driver.earnings, driver.weekly = fetchUserEarningsData(userID)
+	now := time.Now()
+	goals, err := services.Cached(services.Earnings(), userID, "goals", now, func() (models.GoalsResponse, error) {
+		return buildGoalsResponse(userID, now)
+	})
+	if err == nil {
+		driver.goals = &goals
+	}
+	insights, err := services.Cached(services.Earnings(), userID, "insights", now, func() (models.EarningsInsights, error) {
+		return buildEarningsInsights(userID, now)
+	})
+	if err == nil {
+		driver.insights = &insights
+	}
+	return driver
+}
+
 // GetChatHistory returns chat history for a session
 func GetChatHistory(c *gin.Context) {
 	userID := c.GetString("userID")
@@ -144,13 +185,50 @@ func GetChatHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, session)
 }
 
-func getAIResponse(userQuery string, chatHistory []models.ChatMessage, sessionType, language string, earningsData *models.EarningsResponse, weeklyData *models.WeeklyEarningsResponse) (string, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("GEMINI_API_KEY not set")
+// getAIResponse answers the last message of the session, sending the newest turns
+// that fit in the session type's history budget along with the summary of older ones
+func getAIResponse(session models.ChatSession, sessionType, language string, driver chatContext) (string, error) {
+	client, err := llm.Default()
+	if err != nil {
+		return "", err
 	}
 
-	apiUrl := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash-preview-05-20:generateContent?key=%s", apiKey)
+	settings := llm.ForSession(sessionType)
+	ctx, cancel := context.WithTimeout(context.Background(), settings.Timeout)
+	defer cancel()
+
+	request := settings.Request()
+	request.System = chatSystemPrompt(session.Summary, sessionType, language, driver, true)
+	request.Messages = services.ChatHistory(session, settings.HistoryTokens)
+
+	var reply struct {
+		ResponseText string `json:"response_text"`
+	}
+	response, err := client.ChatJSON(ctx, request, &reply)
+	if errors.Is(err, llm.ErrEmptyResponse) {
+		return "Sorry, I couldn't process your request.", nil
+	}
+	if err != nil && response.Text == "" {
+		return "", err
+	}
+	if reply.ResponseText == "" {
+		return response.Text, nil // Return as-is if not valid JSON
+	}
+	return reply.ResponseText, nil
+}
+
+// chatSystemPrompt builds the instructions for a session type. Streamed answers are
+// read aloud as they arrive, so they are asked for plain text instead of JSON.
+func chatSystemPrompt(summary, sessionType, language string, driver chatContext, jsonOutput bool) string {
+	earningsData, weeklyData, goals, insights := driver.earnings, driver.weekly, driver.goals, driver.insights
+	outputRule := `**JSON Output:** Your entire output MUST be a single, valid JSON object with the key "response_text".`
+	if !jsonOutput {
+		outputRule = `**Plain Text Output:** Reply with the answer text only, in short sentences, without JSON or markdown.`
+	}
+	if summary != "" {
+		summary = fmt.Sprintf(`
+		**EARLIER IN THIS CONVERSATION:** %s`, summary)
+	}
 
 	// Create earnings context string
 	earningsContext := ""
@@ -162,17 +240,24 @@ func getAIResponse(userQuery string, chatHistory []models.ChatMessage, sessionTy
 		Current Week Total: Revenue: ₹%.2f, Expenses: ₹%.2f, Net Earnings: ₹%.2f, Trips: %d
 		Previous Week Total: Revenue: ₹%.2f, Expenses: ₹%.2f, Net Earnings: ₹%.2f, Trips: %d
 		Weekly Growth: %.1f%%
+		Current Week Expenses by Category: %s
+		Goal Progress: %s
+		Forecast and Best Times: %s
 		
 		**WHEN USER ASKS ABOUT EARNINGS:**
 		- Provide specific numbers from the data above
 		- Compare today vs last week, current week vs previous week
 		- Mention growth percentage if relevant
+		- Point out expense categories that take a large share or grew sharply
+		- If goals are set, say how close they are to their targets, whether they are on track, and their streak
+		- For "how much will I make" questions, give the projected range, not a single promise
+		- Suggest their best days and hours when they ask when to work
 		- Be encouraging and supportive about their progress`,
 			earningsData.Today.Revenue, earningsData.Today.Expenses, earningsData.Today.NetEarnings, earningsData.Today.Trips,
 			earningsData.LastWeek.Revenue, earningsData.LastWeek.Expenses, earningsData.LastWeek.NetEarnings, earningsData.LastWeek.Trips,
 			weeklyData.CurrentWeek.Revenue, weeklyData.CurrentWeek.Expenses, weeklyData.CurrentWeek.NetEarnings, weeklyData.CurrentWeek.Trips,
 			weeklyData.PreviousWeek.Revenue, weeklyData.PreviousWeek.Expenses, weeklyData.PreviousWeek.NetEarnings, weeklyData.PreviousWeek.Trips,
-			weeklyData.GrowthPercentage)
+			weeklyData.GrowthPercentage, formatCategoryContext(weeklyData.CurrentWeek.Categories), formatGoalContext(goals), formatInsightsContext(insights))
 	}
 
 	// Create system prompt based on session type
@@ -184,219 +269,45 @@ func getAIResponse(userQuery string, chatHistory []models.ChatMessage, sessionTy
 		**Core Directives:**
 		1. **Diagnose First:** Always ask questions before giving answers.
 		2. **Language Discipline:** Respond ONLY in the language: %s.
-		3. **JSON Output:** Your entire output MUST be a single, valid JSON object with the key "response_text".
-		4. **Use Chat History:** Consider the previous messages in this conversation: %s.`, earningsContext, language, formatChatHistory(chatHistory))
+		3. %s
+		4. **Use Chat History:** Build on what the driver has already told you in this conversation.%s`, earningsContext, language, outputRule, summary)
 	} else {
 		systemPrompt = fmt.Sprintf(`You are "Porter Saathi," an expert AI customer support agent for truck drivers in India. Your only goal is to provide clear, precise, and helpful answers.
 		%s
 		**Core Directives:**
 		1. **Precision is Key:** Do NOT give vague answers. If you don't know, say so.
 		2. **Language Discipline:** Respond ONLY in the language: %s.
-		3. **JSON Output:** Your entire output MUST be a single, valid JSON object with the key "response_text".`, earningsContext, language)
+		3. %s
+		4. **Use Chat History:** Answer follow-up questions in the light of the earlier turns.%s`, earningsContext, language, outputRule, summary)
 	}
 
-	payload := map[string]interface{}{
-		"systemInstruction": map[string]interface{}{
-			"parts": []map[string]string{
-				{"text": systemPrompt},
-			},
-		},
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": userQuery},
-				},
-			},
-		},
-		"generationConfig": map[string]string{
-			"responseMimeType": "application/json",
-		},
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := http.Post(apiUrl, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
+	return systemPrompt
+}
 
-	// Extract response text from Gemini API response
-	candidates, ok := result["candidates"].([]interface{})
-	if !ok || len(candidates) == 0 {
-		return "Sorry, I couldn't process your request.", nil
+// formatCategoryContext describes expense categories for the AI prompt
+func formatCategoryContext(categories []models.CategoryTotal) string {
+	var parts []string
+	for _, category := range categories {
+		part := fmt.Sprintf("%s ₹%.2f (%.1f%%", category.Category, category.Amount, category.Percentage)
+		if category.ChangePercentage != nil {
+			part += fmt.Sprintf(", %+.1f%% vs previous week", *category.ChangePercentage)
+		}
+		parts = append(parts, part+")")
 	}
-
-	candidate := candidates[0].(map[string]interface{})
-	content := candidate["content"].(map[string]interface{})
-	parts := content["parts"].([]interface{})
 	if len(parts) == 0 {
-		return "Sorry, I couldn't process your request.", nil
+		return "none recorded"
 	}
-
-	responseText := parts[0].(map[string]interface{})["text"].(string)
-
-	// Parse JSON response
-	var jsonResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(responseText), &jsonResponse); err != nil {
-		return responseText, nil // Return as-is if not valid JSON
-	}
-
-	if response, ok := jsonResponse["response_text"].(string); ok {
-		return response, nil
-	}
-
-	return responseText, nil
+	return strings.Join(parts, ", ")
 }
 
-func formatChatHistory(messages []models.ChatMessage) string {
-	historyJSON, _ := json.Marshal(messages)
-	return string(historyJSON)
-}
-
-// fetchUserEarningsData retrieves user's earnings data for AI context
+// fetchUserEarningsData retrieves user's earnings data for AI context; entries waiting for confirmation are left out
 func fetchUserEarningsData(userID primitive.ObjectID) (*models.EarningsResponse, *models.WeeklyEarningsResponse) {
-	collection := config.GetDB().Collection("earnings")
-
-	// Get today's earnings
-	today := time.Now()
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	var todayEarnings []models.Earnings
-	cursor, err := collection.Find(context.Background(), bson.M{
-		"user_id": userID,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &todayEarnings)
-	}
-
-	// Get last week's earnings
-	lastWeekStart := startOfDay.AddDate(0, 0, -7)
-	var lastWeekEarnings []models.Earnings
-	cursor, err = collection.Find(context.Background(), bson.M{
-		"user_id": userID,
-		"date": bson.M{
-			"$gte": lastWeekStart,
-			"$lt":  startOfDay,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &lastWeekEarnings)
-	}
-
-	// Calculate today and last week summaries
-	todaySummary := calculateSummary(todayEarnings)
-	lastWeekSummary := calculateSummary(lastWeekEarnings)
-
-	earningsResponse := &models.EarningsResponse{
-		Today:    todaySummary,
-		LastWeek: lastWeekSummary,
-	}
-
-	// Get current week's earnings
-	weekday := int(today.Weekday())
-	if weekday == 0 { // Sunday
-		weekday = 7
-	}
-	startOfWeek := today.AddDate(0, 0, -(weekday - 1))
-	startOfWeek = time.Date(startOfWeek.Year(), startOfWeek.Month(), startOfWeek.Day(), 0, 0, 0, 0, startOfWeek.Location())
-	endOfWeek := startOfWeek.AddDate(0, 0, 7)
-
-	// Get previous week for comparison
-	prevWeekStart := startOfWeek.AddDate(0, 0, -7)
-
-	// Fetch current week's earnings
-	var currentWeekEarnings []models.Earnings
-	cursor, err = collection.Find(context.Background(), bson.M{
-		"user_id": userID,
-		"date": bson.M{
-			"$gte": startOfWeek,
-			"$lt":  endOfWeek,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &currentWeekEarnings)
-	}
-
-	// Fetch previous week's earnings for comparison
-	var prevWeekEarnings []models.Earnings
-	cursor, err = collection.Find(context.Background(), bson.M{
-		"user_id": userID,
-		"date": bson.M{
-			"$gte": prevWeekStart,
-			"$lt":  startOfWeek,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &prevWeekEarnings)
-	}
-
-	// Group earnings by day
-	dailyEarnings := make(map[string]models.DailyEarnings)
-
-	// Initialize all days of the week
-	for i := 0; i < 7; i++ {
-		day := startOfWeek.AddDate(0, 0, i)
-		dayKey := day.Format("2006-01-02")
-		dailyEarnings[dayKey] = models.DailyEarnings{
-			Date:        day,
-			DayName:     day.Format("Mon"),
-			Revenue:     0,
-			Expenses:    0,
-			Trips:       0,
-			NetEarnings: 0,
-		}
-	}
-
-	// Fill in actual earnings data
-	for _, earning := range currentWeekEarnings {
-		dayKey := earning.Date.Format("2006-01-02")
-		if daily, exists := dailyEarnings[dayKey]; exists {
-			daily.Revenue += earning.Revenue
-			daily.Expenses += earning.Expenses
-			daily.Trips += earning.Trips
-			daily.NetEarnings += earning.NetEarnings
-			dailyEarnings[dayKey] = daily
-		}
-	}
-
-	// Convert map to ordered slice
-	var weeklyData []models.DailyEarnings
-	for i := 0; i < 7; i++ {
-		day := startOfWeek.AddDate(0, 0, i)
-		dayKey := day.Format("2006-01-02")
-		weeklyData = append(weeklyData, dailyEarnings[dayKey])
-	}
-
-	// Calculate totals and growth
-	currentWeekSummary := calculateSummary(currentWeekEarnings)
-	prevWeekSummary := calculateSummary(prevWeekEarnings)
-
-	var growthPercentage float64
-	if prevWeekSummary.NetEarnings > 0 {
-		growthPercentage = ((currentWeekSummary.NetEarnings - prevWeekSummary.NetEarnings) / prevWeekSummary.NetEarnings) * 100
-	}
-
-	weeklyResponse := &models.WeeklyEarningsResponse{
-		WeeklyData:       weeklyData,
-		CurrentWeek:      currentWeekSummary,
-		PreviousWeek:     prevWeekSummary,
-		GrowthPercentage: growthPercentage,
-		WeekStartDate:    startOfWeek,
+	snapshot, err := services.Earnings().Snapshot(userID, time.Now(), false)
+	if err != nil {
+		log.Printf("Error loading earnings for chat context: %v", err)
+		return nil, nil
 	}
-
-	return earningsResponse, weeklyResponse
+	earningsResponse := snapshot.Overview()
+	weeklyResponse := snapshot.Weekly()
+	return &earningsResponse, &weeklyResponse
 }
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"porter-saathi-backend/llm"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StreamMessage answers like SendMessage but streams the reply as Server-Sent Events:
+// "delta" events carry text as it arrives, "sentence" events each sentence once it is
+// complete (for text-to-speech), and "done" the session ID and the saved AI message.
+// Failures after the stream has started are sent as an "error" event. The question
+// and answer are saved together only once the whole answer has arrived, so a
+// cancelled or failed stream leaves the session exactly as it was.
+func StreamMessage(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.ChatRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := llm.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get AI response"})
+		return
+	}
+
+	session, stored := loadChatSession(objectID, c.Query("sessionId"), request)
+	userMessage := models.ChatMessage{
+		Sender:    "user",
+		Text:      request.Message,
+		Timestamp: time.Now(),
+	}
+	session.Messages = append(session.Messages, userMessage)
+
+	settings := llm.ForSession(request.SessionType)
+	llmRequest := settings.Request()
+	llmRequest.System = chatSystemPrompt(session.Summary, request.SessionType, request.Language, fetchChatContext(objectID), false)
+	llmRequest.Messages = services.ChatHistory(session, settings.HistoryTokens)
+
+	// The request context ends when the driver disconnects
+	ctx, cancel := context.WithTimeout(c.Request.Context(), settings.Timeout)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(event string, data interface{}) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+	var splitter sentenceSplitter
+	sentences := 0
+	sendText := func(delta string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		send("delta", models.ChatStreamDelta{Text: delta})
+		for _, sentence := range splitter.Add(delta) {
+			send("sentence", models.ChatStreamSentence{Index: sentences, Text: sentence})
+			sentences++
+		}
+		return nil
+	}
+
+	response, err := client.Stream(ctx, llmRequest, sendText)
+	if errors.Is(err, llm.ErrEmptyResponse) {
+		response.Text = "Sorry, I couldn't process your request."
+		err = sendText(response.Text)
+	}
+	if err != nil {
+		if c.Request.Context().Err() != nil {
+			log.Printf("Chat stream cancelled by client, session %s not changed", session.ID.Hex())
+			return
+		}
+		log.Printf("Error streaming AI response: %v", err)
+		send("error", gin.H{"error": "Failed to get AI response"})
+		return
+	}
+	if sentence := splitter.Flush(); sentence != "" {
+		send("sentence", models.ChatStreamSentence{Index: sentences, Text: sentence})
+	}
+
+	aiMessage := models.ChatMessage{
+		Sender:    "ai",
+		Text:      response.Text,
+		Timestamp: time.Now(),
+	}
+	// The whole answer has arrived, so it is saved even if the driver has just disconnected
+	if err := saveChatTurn(&session, stored, userMessage, aiMessage); err != nil {
+		log.Printf("Error saving chat session: %v", err)
+		send("error", gin.H{"error": "Failed to save chat"})
+		return
+	}
+	send("done", models.ChatStreamDone{SessionID: session.ID.Hex(), Message: aiMessage})
+	go services.SummarizeChatSession(session, settings)
+}
+
+// sentenceSplitter collects streamed text and hands back whole sentences. A sentence
+// ends at a line break, or at . ! ? or the Devanagari danda followed by a space, so
+// amounts like ₹2.50 are not split.
+type sentenceSplitter struct {
+	pending string
+}
+
+// Add appends a delta and returns the sentences it completed
+func (s *sentenceSplitter) Add(delta string) []string {
+	s.pending += delta
+	var sentences []string
+	start := 0
+	for i, r := range s.pending {
+		end := -1
+		switch r {
+		case '\n':
+			end = i + 1
+		case '.', '!', '?', '।', '॥':
+			next := i + utf8.RuneLen(r)
+			if following, _ := utf8.DecodeRuneInString(s.pending[next:]); next < len(s.pending) && unicode.IsSpace(following) {
+				end = next
+			}
+		}
+		if end < 0 {
+			continue
+		}
+		if sentence := strings.TrimSpace(s.pending[start:end]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = end
+	}
+	s.pending = s.pending[start:]
+	return sentences
+}
+
+// Flush returns whatever is left once the reply has ended
+func (s *sentenceSplitter) Flush() string {
+	sentence := strings.TrimSpace(s.pending)
+	s.pending = ""
+	return sentence
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/llm"
+	"porter-saathi-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSentenceSplitter(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []string
+		want   []string
+		rest   string
+	}{
+		{
+			name:   "sentences across deltas",
+			deltas: []string{"Namaste. Aaj ", "aapne achha ", "kamaya! Aur kuch", "?"},
+			want:   []string{"Namaste.", "Aaj aapne achha kamaya!"},
+			rest:   "Aur kuch?",
+		},
+		{
+			name:   "amounts are not split",
+			deltas: []string{"Toll par ₹2.50 ", "extra lage. Theek hai?", " "},
+			want:   []string{"Toll par ₹2.50 extra lage.", "Theek hai?"},
+		},
+		{
+			name:   "devanagari danda",
+			deltas: []string{"आज ₹1,200 कमाए। ", "कल ", "छुट्टी है।"},
+			want:   []string{"आज ₹1,200 कमाए।"},
+			rest:   "कल छुट्टी है।",
+		},
+		{
+			name:   "line breaks",
+			deltas: []string{"Tyre check karein\nAir filter", " saaf karein\n"},
+			want:   []string{"Tyre check karein", "Air filter saaf karein"},
+		},
+		{
+			name:   "nothing complete",
+			deltas: []string{"Ek minute"},
+			rest:   "Ek minute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var splitter sentenceSplitter
+			var got []string
+			for _, delta := range tt.deltas {
+				got = append(got, splitter.Add(delta)...)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("sentences = %q, want %q", got, tt.want)
+			}
+			if rest := splitter.Flush(); rest != tt.rest {
+				t.Errorf("Flush() = %q, want %q", rest, tt.rest)
+			}
+			if rest := splitter.Flush(); rest != "" {
+				t.Errorf("second Flush() = %q, want nothing", rest)
+			}
+		})
+	}
+}
+
+// serverSentEvent is one event read back from a streamed response
+type serverSentEvent struct {
+	name string
+	data string
+}
+
+func readServerSentEvents(t *testing.T, body string) []serverSentEvent {
+	t.Helper()
+	var events []serverSentEvent
+	var current serverSentEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			current.name = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			current.data = strings.TrimPrefix(line, "data:")
+		case line == "" && current.name != "":
+			events = append(events, current)
+			current = serverSentEvent{}
+		}
+	}
+	return events
+}
+
+// streamMockResponse answers any command: an empty cursor for reads, one document for writes
+var streamMockResponse = mtest.CreateSuccessResponse(
+	bson.E{Key: "n", Value: 1},
+	bson.E{Key: "nModified", Value: 1},
+	bson.E{Key: "cursor", Value: bson.D{
+		{Key: "id", Value: int64(0)},
+		{Key: "ns", Value: "porter_saathi.earnings"},
+		{Key: "firstBatch", Value: bson.A{}},
+	}},
+)
+
+func TestStreamMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	defer func(db *mongo.Database) { config.DB = db }(config.DB)
+
+	stream := func(mt *mtest.T, fake *llm.FakeClient, message string) ([]serverSentEvent, []bson.Raw) {
+		llm.SetDefault(fake)
+		config.DB = mt.DB
+		// The driver has no earnings, goals or trips; every read comes back empty
+		for i := 0; i < 100; i++ {
+			mt.AddMockResponses(streamMockResponse)
+		}
+
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Set("userID", primitive.NewObjectID().Hex())
+		body := `{"message": "` + message + `", "sessionType": "general", "language": "hinglish"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/chat/message/stream", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		StreamMessage(c)
+
+		if recorder.Code != http.StatusOK {
+			mt.Fatalf("status = %d, want 200", recorder.Code)
+		}
+		var inserts []bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "insert" && started.Command.Lookup("insert").StringValue() == "chat_sessions" {
+				inserts = append(inserts, started.Command)
+			}
+		}
+		return readServerSentEvents(t, recorder.Body.String()), inserts
+	}
+
+	mt.Run("streams sentences and saves the turn", func(mt *mtest.T) {
+		fake := llm.NewFakeClient("Aaj diesel ₹87.62 litre hai. Toll ke liye FASTag rakhein! Aur kuch?")
+		events, inserts := stream(mt, fake, "Diesel ka rate kya hai?")
+
+		var deltas strings.Builder
+		var sentences []string
+		var done models.ChatStreamDone
+		for _, event := range events {
+			switch event.name {
+			case "delta":
+				var delta models.ChatStreamDelta
+				json.Unmarshal([]byte(event.data), &delta)
+				deltas.WriteString(delta.Text)
+			case "sentence":
+				var sentence models.ChatStreamSentence
+				json.Unmarshal([]byte(event.data), &sentence)
+				if sentence.Index != len(sentences) {
+					mt.Errorf("sentence %q has index %d, want %d", sentence.Text, sentence.Index, len(sentences))
+				}
+				sentences = append(sentences, sentence.Text)
+			case "done":
+				json.Unmarshal([]byte(event.data), &done)
+			case "error":
+				mt.Errorf("unexpected error event: %s", event.data)
+			}
+		}
+
+		if deltas.String() != "Aaj diesel ₹87.62 litre hai. Toll ke liye FASTag rakhein! Aur kuch?" {
+			mt.Errorf("deltas add up to %q", deltas.String())
+		}
+		want := []string{"Aaj diesel ₹87.62 litre hai.", "Toll ke liye FASTag rakhein!", "Aur kuch?"}
+		if strings.Join(sentences, "|") != strings.Join(want, "|") {
+			mt.Errorf("sentences = %q, want %q", sentences, want)
+		}
+		if done.SessionID == "" || done.Message.Sender != "ai" || done.Message.Text != deltas.String() {
+			mt.Errorf("done = %+v, want the saved AI message", done)
+		}
+		if last := events[len(events)-1].name; last != "done" {
+			mt.Errorf("last event = %q, want done", last)
+		}
+
+		requests := fake.Requests()
+		if len(requests) != 1 {
+			mt.Fatalf("sent %d requests to the model, want 1", len(requests))
+		}
+		turns := requests[0].Messages
+		if len(turns) != 1 || turns[0].Role != llm.RoleUser || turns[0].Content != "Diesel ka rate kya hai?" {
+			mt.Errorf("model was sent %+v, want just the question", turns)
+		}
+
+		if len(inserts) != 1 {
+			mt.Fatalf("saved the session %d times, want once", len(inserts))
+		}
+		var session models.ChatSession
+		if err := bson.Unmarshal(inserts[0].Lookup("documents").Array().Index(0).Value().Document(), &session); err != nil {
+			mt.Fatal(err)
+		}
+		if session.ID.Hex() != done.SessionID || len(session.Messages) != 2 ||
+			session.Messages[0].Text != "Diesel ka rate kya hai?" || session.Messages[1].Text != done.Message.Text {
+			mt.Errorf("saved session = %+v, want the question and answer", session)
+		}
+	})
+
+	mt.Run("failed reply is not saved", func(mt *mtest.T) {
+		fake := llm.NewFakeClient()
+		fake.Err = &llm.APIError{Provider: "fake", StatusCode: http.StatusServiceUnavailable, Message: "overloaded"}
+		events, inserts := stream(mt, fake, "Namaste")
+
+		if len(events) == 0 || events[len(events)-1].name != "error" {
+			mt.Errorf("events = %+v, want an error event last", events)
+		}
+		if len(inserts) != 0 {
+			mt.Errorf("saved the session %d times, want a failed turn left out", len(inserts))
+		}
+	})
+
+	mt.Run("empty reply gets a fallback", func(mt *mtest.T) {
+		fake := llm.NewFakeClient()
+		fake.Err = llm.ErrEmptyResponse
+		events, inserts := stream(mt, fake, "Namaste")
+
+		if len(events) == 0 || events[len(events)-1].name != "done" {
+			mt.Fatalf("events = %+v, want done last", events)
+		}
+		var done models.ChatStreamDone
+		json.Unmarshal([]byte(events[len(events)-1].data), &done)
+		if done.Message.Text != "Sorry, I couldn't process your request." || len(inserts) != 1 {
+			mt.Errorf("done = %+v with %d saves, want the fallback saved once", done, len(inserts))
+		}
+	})
+}
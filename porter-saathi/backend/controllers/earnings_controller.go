@@ -2,17 +2,30 @@ package controllers
 
 import (
 	"context"
+	"log"
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	"porter-saathi-backend/config"
 	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
+	"porter-saathi-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// maxDailyAmount is the most revenue or expenses one entry can hold; anything above is almost always an extra zero
+const maxDailyAmount = 100000
+
+// maxDailyTrips is the most trips one entry can hold
+const maxDailyTrips = 100
+
 // GetEarnings returns today's and last week's earnings
 func GetEarnings(c *gin.Context) {
 	userID := c.GetString("userID")
@@ -22,53 +35,48 @@ func GetEarnings(c *gin.Context) {
 		return
 	}
 
-	collection := config.GetDB().Collection("earnings")
+	// Days run midnight to midnight in the driver's timezone
+	snapshot, err := services.Earnings().Snapshot(objectID, time.Now(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load earnings"})
+		return
+	}
 
-	// Get today's earnings
-	today := time.Now()
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	var todayEarnings []models.Earnings
-	cursor, err := collection.Find(context.Background(), bson.M{
-		"user_id": objectID,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &todayEarnings)
-	}
-
-	// Get last week's earnings
-	lastWeekStart := startOfDay.AddDate(0, 0, -7)
-	var lastWeekEarnings []models.Earnings
-	cursor, err = collection.Find(context.Background(), bson.M{
-		"user_id": objectID,
-		"date": bson.M{
-			"$gte": lastWeekStart,
-			"$lt":  startOfDay,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &lastWeekEarnings)
+	response := snapshot.Overview()
+	response.Loans = earningsLoans(objectID, snapshot.Today, snapshot.LastWeek, snapshot.StartOfDay, snapshot.EndOfDay, snapshot.LastWeekStart, snapshot.StartOfDay)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWeeklyEarnings returns daily earnings for the current week
+func GetWeeklyEarnings(c *gin.Context) {
+	userID := c.GetString("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
 	}
 
-	// Calculate totals
-	todaySummary := calculateSummary(todayEarnings)
-	lastWeekSummary := calculateSummary(lastWeekEarnings)
+	// The current week is in the driver's timezone, starting on their chosen day
+	snapshot, err := services.Earnings().Snapshot(objectID, time.Now(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load earnings"})
+		return
+	}
+	response := snapshot.Weekly()
 
-	response := models.EarningsResponse{
-		Today:    todaySummary,
-		LastWeek: lastWeekSummary,
+	// EMIs are part of what it costs to keep earning, so growth is compared after them
+	response.Loans = earningsLoans(objectID, snapshot.CurrentWeek, snapshot.PreviousWeek, snapshot.StartOfWeek, snapshot.EndOfWeek, snapshot.PrevWeekStart, snapshot.StartOfWeek)
+	if response.Loans != nil {
+		response.GrowthPercentage = services.GrowthPercentage(response.Loans.NetAfterLoans, response.Loans.PreviousNetAfterLoans)
 	}
+	response.Vehicle = weeklyVehicleMetrics(objectID, snapshot.StartOfWeek, snapshot.EndOfWeek, snapshot.CurrentWeek)
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetWeeklyEarnings returns daily earnings for the current week
-func GetWeeklyEarnings(c *gin.Context) {
+// AddEarnings adds new earnings record
+func AddEarnings(c *gin.Context) {
 	userID := c.GetString("userID")
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
@@ -76,151 +84,533 @@ func GetWeeklyEarnings(c *gin.Context) {
 		return
 	}
 
+	var request models.EarningsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retries of the same entry carry the same key and get the original back
+	loc, _ := userCalendar(objectID)
+	clientID := request.ClientID
+	if clientID == "" {
+		clientID = c.GetHeader("Idempotency-Key")
+	}
+	if clientID != "" {
+		existing, found, err := findEarningsByClientID(objectID, clientID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if found {
+			existing.Date = existing.Date.In(loc)
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "Earnings already added",
+				"earnings":  existing,
+				"duplicate": true,
+			})
+			return
+		}
+	}
+
+	earnings := models.Earnings{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		ClientID:  clientID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if errMessage := applyEarningsRequest(&earnings, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+	reviewEarnings(&earnings)
+	if err := stampEarningsWrite(&earnings, earnings.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save earnings"})
+		return
+	}
+	defer releaseEarningsWrite(&earnings)
+
 	collection := config.GetDB().Collection("earnings")
+	_, err = collection.InsertOne(context.Background(), earnings)
+	if mongo.IsDuplicateKeyError(err) && clientID != "" {
+		// A concurrent retry won the race
+		if existing, found, _ := findEarningsByClientID(objectID, clientID); found {
+			existing.Date = existing.Date.In(loc)
+			c.JSON(http.StatusOK, gin.H{
+				"message":   "Earnings already added",
+				"earnings":  existing,
+				"duplicate": true,
+			})
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save earnings"})
+		return
+	}
+	recordEarningsChange(models.EarningsChangeCreate, objectID, earnings.ID, nil, &earnings, "")
 
-	// Get current week's start (Monday)
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 { // Sunday
-		weekday = 7
-	}
-	startOfWeek := now.AddDate(0, 0, -(weekday - 1))
-	startOfWeek = time.Date(startOfWeek.Year(), startOfWeek.Month(), startOfWeek.Day(), 0, 0, 0, 0, startOfWeek.Location())
-	endOfWeek := startOfWeek.AddDate(0, 0, 7)
-
-	// Get previous week for comparison
-	prevWeekStart := startOfWeek.AddDate(0, 0, -7)
-
-	// Fetch current week's earnings
-	var currentWeekEarnings []models.Earnings
-	cursor, err := collection.Find(context.Background(), bson.M{
-		"user_id": objectID,
-		"date": bson.M{
-			"$gte": startOfWeek,
-			"$lt":  endOfWeek,
-		},
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "Earnings added successfully",
+		"earnings":          earnings,
+		"needsConfirmation": earnings.ReviewStatus == models.ReviewFlagged,
 	})
-	if err == nil {
-		cursor.All(context.Background(), &currentWeekEarnings)
+}
+
+// GetEarningsByID returns a single earnings entry owned by the driver
+func GetEarningsByID(c *gin.Context) {
+	earnings, ok := findUserEarnings(c)
+	if !ok {
+		return
 	}
 
-	// Fetch previous week's earnings for comparison
-	var prevWeekEarnings []models.Earnings
-	cursor, err = collection.Find(context.Background(), bson.M{
-		"user_id": objectID,
-		"date": bson.M{
-			"$gte": prevWeekStart,
-			"$lt":  startOfWeek,
-		},
-	})
-	if err == nil {
-		cursor.All(context.Background(), &prevWeekEarnings)
-	}
-
-	// Group earnings by day
-	dailyEarnings := make(map[string]models.DailyEarnings)
-
-	// Initialize all days of the week
-	for i := 0; i < 7; i++ {
-		day := startOfWeek.AddDate(0, 0, i)
-		dayKey := day.Format("2006-01-02")
-		dailyEarnings[dayKey] = models.DailyEarnings{
-			Date:        day,
-			DayName:     day.Format("Mon"),
-			Revenue:     0,
-			Expenses:    0,
-			Trips:       0,
-			NetEarnings: 0,
-		}
+	c.JSON(http.StatusOK, earnings)
+}
+
+// UpdateEarnings edits an earnings entry, recomputing net earnings and recording the change
+func UpdateEarnings(c *gin.Context) {
+	before, ok := findUserEarnings(c)
+	if !ok {
+		return
+	}
+	if before.Source == models.EarningsSourceTrips {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry is calculated from your trips. Edit or delete the trips instead."})
+		return
 	}
 
-	// Fill in actual earnings data
-	for _, earning := range currentWeekEarnings {
-		dayKey := earning.Date.Format("2006-01-02")
-		if daily, exists := dailyEarnings[dayKey]; exists {
-			daily.Revenue += earning.Revenue
-			daily.Expenses += earning.Expenses
-			daily.Trips += earning.Trips
-			daily.NetEarnings += earning.NetEarnings
-			dailyEarnings[dayKey] = daily
+	var request models.EarningsUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	after := before
+	if request.Date != nil {
+		loc, _ := userCalendar(before.UserID)
+		date, err := utils.ParseLocalDate(*request.Date, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		if errMessage := checkEarningsDate(date, loc); errMessage != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+			return
 		}
+		after.Date = date
+	}
+	if request.Revenue != nil {
+		after.Revenue = *request.Revenue
+	}
+	switch {
+	case request.ExpenseItems != nil:
+		expenses := 0.0
+		if request.Expenses != nil {
+			expenses = *request.Expenses
+		}
+		total, errMessage := sumExpenseItems(*request.ExpenseItems, expenses)
+		if errMessage != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+			return
+		}
+		after.ExpenseItems = *request.ExpenseItems
+		after.Expenses = total
+	case request.Expenses != nil:
+		itemized, _ := sumExpenseItems(after.ExpenseItems, 0)
+		if *request.Expenses < itemized {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expenses cannot be less than the itemized expenses; send expenseItems instead"})
+			return
+		}
+		after.Expenses = *request.Expenses
+	}
+	if request.Trips != nil {
+		after.Trips = *request.Trips
+	}
+	after.NetEarnings = after.Revenue - after.Expenses
+	after.UpdatedAt = time.Now()
+	if !sameEarningsFigures(before, after) {
+		reviewEarnings(&after)
 	}
+	if err := stampEarningsWrite(&after, after.UpdatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update earnings"})
+		return
+	}
+	defer releaseEarningsWrite(&after)
 
-	// Convert map to ordered slice
-	var weeklyData []models.DailyEarnings
-	for i := 0; i < 7; i++ {
-		day := startOfWeek.AddDate(0, 0, i)
-		dayKey := day.Format("2006-01-02")
-		weeklyData = append(weeklyData, dailyEarnings[dayKey])
+	collection := config.GetDB().Collection("earnings")
+	_, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": before.ID, "user_id": before.UserID},
+		withReviewFields(bson.M{
+			"date":          after.Date,
+			"revenue":       after.Revenue,
+			"expenses":      after.Expenses,
+			"expense_items": after.ExpenseItems,
+			"trips":         after.Trips,
+			"net_earnings":  after.NetEarnings,
+			"updated_at":    after.UpdatedAt,
+			"modified_at":   after.ModifiedAt,
+			"sync_seq":      after.SyncSeq,
+		}, after),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update earnings"})
+		return
 	}
+	recordEarningsChange(models.EarningsChangeUpdate, before.UserID, before.ID, &before, &after, request.Reason)
 
-	// Calculate totals and growth
-	currentWeekSummary := calculateSummary(currentWeekEarnings)
-	prevWeekSummary := calculateSummary(prevWeekEarnings)
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Earnings updated successfully",
+		"earnings":          after,
+		"needsConfirmation": after.ReviewStatus == models.ReviewFlagged,
+	})
+}
 
-	var growthPercentage float64
-	if prevWeekSummary.NetEarnings > 0 {
-		growthPercentage = ((currentWeekSummary.NetEarnings - prevWeekSummary.NetEarnings) / prevWeekSummary.NetEarnings) * 100
+// DeleteEarnings soft-deletes an earnings entry so it drops out of all totals; the
+// history is kept. Pass ?permanent=true with a ?reason= to remove the figures of an
+// entry that is already deleted for good, leaving a tombstone for syncing devices.
+func DeleteEarnings(c *gin.Context) {
+	if c.Query("permanent") == "true" {
+		purgeEarnings(c)
+		return
 	}
 
-	response := models.WeeklyEarningsResponse{
-		WeeklyData:       weeklyData,
-		CurrentWeek:      currentWeekSummary,
-		PreviousWeek:     prevWeekSummary,
-		GrowthPercentage: growthPercentage,
-		WeekStartDate:    startOfWeek,
+	earnings, ok := findUserEarnings(c)
+	if !ok {
+		return
+	}
+	if earnings.Source == models.EarningsSourceTrips {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry is calculated from your trips. Edit or delete the trips instead."})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	now := time.Now()
+	after := earnings
+	after.DeletedAt = &now
+	after.UpdatedAt = now
+	if err := stampEarningsWrite(&after, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete earnings"})
+		return
+	}
+	defer releaseEarningsWrite(&after)
+	_, err := config.GetDB().Collection("earnings").UpdateOne(
+		context.Background(),
+		bson.M{"_id": earnings.ID, "user_id": earnings.UserID, "deleted_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{
+			"deleted_at":  now,
+			"updated_at":  now,
+			"modified_at": now,
+			"sync_seq":    after.SyncSeq,
+		}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete earnings"})
+		return
+	}
+	recordEarningsChange(models.EarningsChangeDelete, earnings.UserID, earnings.ID, &earnings, &after, c.Query("reason"))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Earnings deleted successfully"})
 }
 
-// AddEarnings adds new earnings record
-func AddEarnings(c *gin.Context) {
-	userID := c.GetString("userID")
-	objectID, err := primitive.ObjectIDFromHex(userID)
+// purgeEarnings drops the figures of a deleted entry. The entry has to be deleted
+// first, so a slip cannot wipe live figures, and the reason goes on the history.
+func purgeEarnings(c *gin.Context) {
+	reason := strings.TrimSpace(c.Query("reason"))
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to permanently delete earnings"})
+		return
+	}
+	earnings, ok := findDeletedUserEarnings(c)
+	if !ok {
+		return
+	}
+	if earnings.Source == models.EarningsSourceTrips {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry is calculated from your trips. Edit or delete the trips instead."})
+		return
+	}
+
+	// The figures are dropped but a tombstone stays, with a new sync sequence, so devices
+	// that already have the entry remove it and cannot sync it back
+	now := time.Now()
+	tombstone := models.Earnings{
+		ID:        earnings.ID,
+		UserID:    earnings.UserID,
+		Date:      earnings.Date,
+		ClientID:  earnings.ClientID,
+		CreatedAt: earnings.CreatedAt,
+		UpdatedAt: now,
+		DeletedAt: earnings.DeletedAt,
+		PurgedAt:  &now,
+	}
+	if err := stampEarningsWrite(&tombstone, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete earnings"})
+		return
+	}
+	defer releaseEarningsWrite(&tombstone)
+	result, err := config.GetDB().Collection("earnings").ReplaceOne(context.Background(), bson.M{
+		"_id":        earnings.ID,
+		"user_id":    earnings.UserID,
+		"deleted_at": bson.M{"$exists": true},
+		"purged_at":  bson.M{"$exists": false},
+	}, tombstone)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete earnings"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry was restored or removed in the meantime"})
 		return
 	}
+	recordEarningsChange(models.EarningsChangePurge, earnings.UserID, earnings.ID, &earnings, &tombstone, reason)
 
-	var request models.EarningsRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"message": "Earnings permanently deleted"})
+}
+
+// RestoreEarnings brings back a deleted entry that has not been permanently deleted
+func RestoreEarnings(c *gin.Context) {
+	earnings, ok := findDeletedUserEarnings(c)
+	if !ok {
+		return
+	}
+	if earnings.Source == models.EarningsSourceTrips {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry is calculated from your trips. Add the trips again instead."})
 		return
 	}
 
-	// Parse date
-	date, err := time.Parse("2006-01-02", request.Date)
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	after := earnings
+	after.DeletedAt = nil
+	after.UpdatedAt = now
+	if err := stampEarningsWrite(&after, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore earnings"})
+		return
+	}
+	defer releaseEarningsWrite(&after)
+	result, err := config.GetDB().Collection("earnings").UpdateOne(
+		context.Background(),
+		bson.M{
+			"_id":        earnings.ID,
+			"user_id":    earnings.UserID,
+			"deleted_at": bson.M{"$exists": true},
+			"purged_at":  bson.M{"$exists": false},
+		},
+		bson.M{
+			"$set": bson.M{
+				"updated_at":  now,
+				"modified_at": now,
+				"sync_seq":    after.SyncSeq,
+			},
+			"$unset": bson.M{"deleted_at": ""},
+		},
+	)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore earnings"})
 		return
 	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry was restored or removed in the meantime"})
+		return
+	}
+	recordEarningsChange(models.EarningsChangeRestore, earnings.UserID, earnings.ID, &earnings, &after, request.Reason)
 
-	earnings := models.Earnings{
-		ID:          primitive.NewObjectID(),
-		UserID:      objectID,
-		Date:        date,
-		Revenue:     request.Revenue,
-		Expenses:    request.Expenses,
-		Trips:       request.Trips,
-		NetEarnings: request.Revenue - request.Expenses,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Earnings restored successfully",
+		"earnings": after,
+	})
+}
+
+// GetEarningsHistory returns the audit history of an earnings entry, including deleted ones
+func GetEarningsHistory(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	earningsID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid earnings ID"})
+		return
 	}
 
-	collection := config.GetDB().Collection("earnings")
-	_, err = collection.InsertOne(context.Background(), earnings)
+	collection := config.GetDB().Collection("earnings_history")
+	cursor, err := collection.Find(
+		context.Background(),
+		bson.M{"earnings_id": earningsID, "user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "changed_at", Value: 1}}),
+	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save earnings"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":  "Earnings added successfully",
-		"earnings": earnings,
+	history := []models.EarningsChange{}
+	if err := cursor.All(context.Background(), &history); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if len(history) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Earnings not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+// findUserEarnings loads the active earnings entry named in the URL, scoped to the
+// signed-in driver. Entries belonging to someone else are reported as not found.
+func findUserEarnings(c *gin.Context) (models.Earnings, bool) {
+	return findUserEarningsMatching(c, bson.M{"deleted_at": bson.M{"$exists": false}})
+}
+
+// findDeletedUserEarnings loads a deleted entry named in the URL that still has its figures
+func findDeletedUserEarnings(c *gin.Context) (models.Earnings, bool) {
+	return findUserEarningsMatching(c, bson.M{
+		"deleted_at": bson.M{"$exists": true},
+		"purged_at":  bson.M{"$exists": false},
 	})
 }
 
+// findUserEarningsMatching loads the driver's entry named in the URL if it also matches filter
+func findUserEarningsMatching(c *gin.Context, filter bson.M) (models.Earnings, bool) {
+	var earnings models.Earnings
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return earnings, false
+	}
+	earningsID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid earnings ID"})
+		return earnings, false
+	}
+
+	collection := config.GetDB().Collection("earnings")
+	filter["_id"] = earningsID
+	filter["user_id"] = userID
+	err = collection.FindOne(context.Background(), filter).Decode(&earnings)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Earnings not found"})
+			return earnings, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return earnings, false
+	}
+
+	loc, _ := userCalendar(userID)
+	earnings.Date = earnings.Date.In(loc)
+
+	return earnings, true
+}
+
+// applyEarningsRequest sets an entry's figures from a full request. The date is a
+// day in the driver's timezone.
+func applyEarningsRequest(earnings *models.Earnings, request models.EarningsRequest, loc *time.Location) string {
+	date, err := utils.ParseLocalDate(request.Date, loc)
+	if err != nil {
+		return "Invalid date format. Use YYYY-MM-DD"
+	}
+	if errMessage := checkEarningsDate(date, loc); errMessage != "" {
+		return errMessage
+	}
+
+	expenses := request.Expenses
+	if len(request.ExpenseItems) > 0 {
+		total, errMessage := sumExpenseItems(request.ExpenseItems, request.Expenses)
+		if errMessage != "" {
+			return errMessage
+		}
+		expenses = total
+	}
+
+	earnings.Date = date
+	earnings.Revenue = request.Revenue
+	earnings.Expenses = expenses
+	earnings.ExpenseItems = request.ExpenseItems
+	earnings.Trips = request.Trips
+	earnings.NetEarnings = request.Revenue - expenses
+	return ""
+}
+
+// checkEarningsDate rejects days that have not happened yet in the driver's timezone
+func checkEarningsDate(date time.Time, loc *time.Location) string {
+	if date.After(utils.StartOfDay(time.Now(), loc)) {
+		return "date cannot be in the future"
+	}
+	return ""
+}
+
+// stampEarningsWrite gives a write the driver's next sync sequence and records when it was made.
+// The sequence holds back syncs until releaseEarningsWrite is called.
+func stampEarningsWrite(earnings *models.Earnings, modifiedAt time.Time) error {
+	seq, err := utils.NextSyncSequence(context.Background(), earnings.UserID)
+	if err != nil {
+		log.Printf("Error assigning sync sequence for %s: %v", earnings.UserID.Hex(), err)
+		return err
+	}
+	earnings.SyncSeq = seq
+	earnings.ModifiedAt = modifiedAt
+	return nil
+}
+
+// releaseEarningsWrite lets syncs read past a write's sequence number; deferred once
+// stampEarningsWrite succeeds so it runs after the write, saved or not
+func releaseEarningsWrite(earnings *models.Earnings) {
+	if err := utils.ReleaseSyncSequence(context.Background(), earnings.UserID, earnings.SyncSeq); err != nil {
+		log.Printf("Error releasing sync sequence for %s: %v", earnings.UserID.Hex(), err)
+	}
+}
+
+// findEarningsByClientID looks up an entry by the app's own ID, deleted ones included
+func findEarningsByClientID(userID primitive.ObjectID, clientID string) (models.Earnings, bool, error) {
+	var earnings models.Earnings
+	err := config.GetDB().Collection("earnings").FindOne(context.Background(), bson.M{
+		"user_id":   userID,
+		"client_id": clientID,
+	}).Decode(&earnings)
+	if err == mongo.ErrNoDocuments {
+		return earnings, false, nil
+	}
+	return earnings, err == nil, err
+}
+
+// recordEarningsChange appends to the audit history and drops the driver's cached totals.
+// It follows every earnings write; a failure is logged but does not fail the request.
+func recordEarningsChange(action string, userID, earningsID primitive.ObjectID, before, after *models.Earnings, reason string) {
+	services.Earnings().Invalidate(userID)
+
+	change := models.EarningsChange{
+		ID:         primitive.NewObjectID(),
+		EarningsID: earningsID,
+		UserID:     userID,
+		Action:     action,
+		Before:     before,
+		After:      after,
+		Reason:     reason,
+		ChangedAt:  time.Now(),
+	}
+
+	if _, err := config.GetDB().Collection("earnings_history").InsertOne(context.Background(), change); err != nil {
+		log.Printf("Error recording earnings %s for %s: %v", action, earningsID.Hex(), err)
+	}
+}
+
+// userCalendar returns the driver's timezone and week start, defaulting to IST and Monday
+func userCalendar(userID primitive.ObjectID) (*time.Location, time.Weekday) {
+	return services.UserCalendar(userID)
+}
+
 func calculateSummary(earnings []models.Earnings) models.EarningsSummary {
 	var totalRevenue, totalExpenses float64
 	var totalTrips int
@@ -236,5 +626,38 @@ func calculateSummary(earnings []models.Earnings) models.EarningsSummary {
 		Expenses:    totalExpenses,
 		Trips:       totalTrips,
 		NetEarnings: totalRevenue - totalExpenses,
+		Categories:  services.CategoryTotals(expensesByCategory(earnings), totalExpenses),
+	}
+}
+
+// expensesByCategory sums expense lines by category. Whatever part of an entry's
+// total is not itemized (including all of it for older entries) counts as other.
+func expensesByCategory(earnings []models.Earnings) map[string]float64 {
+	amounts := make(map[string]float64)
+	for _, e := range earnings {
+		itemized := 0.0
+		for _, item := range e.ExpenseItems {
+			amounts[item.Category] += item.Amount
+			itemized += item.Amount
+		}
+		if remainder := e.Expenses - itemized; remainder > 0.005 {
+			amounts[models.ExpenseOther] += remainder
+		}
+	}
+	return amounts
+}
+
+// sumExpenseItems totals expense lines and checks them against a total the client also sent
+func sumExpenseItems(items []models.ExpenseItem, expenses float64) (float64, string) {
+	total := 0.0
+	for _, item := range items {
+		total += item.Amount
+	}
+	if expenses != 0 && math.Abs(expenses-total) > 0.01 {
+		return 0, "expenses does not match the sum of expenseItems"
+	}
+	if total > maxDailyAmount {
+		return 0, "expenses cannot be more than ₹1,00,000 for a day"
 	}
+	return total, ""
 }
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// anomalyBaselineDays is how far back the entries an entry is compared with go
+	anomalyBaselineDays = 60
+	// anomalyMinBaseline is how many entries are needed before anything is flagged
+	anomalyMinBaseline = 10
+	// anomalyMinSpreadShare is the smallest spread used, as a share of the usual amount
+	anomalyMinSpreadShare = 0.15
+	// anomalyMinSpreadRupees is the smallest spread used for money, for drivers who earn little
+	anomalyMinSpreadRupees = 100.0
+)
+
+// GetFlaggedEarnings lists entries waiting for the driver to confirm them
+func GetFlaggedEarnings(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	cursor, err := config.GetDB().Collection("earnings").Find(
+		context.Background(),
+		bson.M{
+			"user_id":       objectID,
+			"deleted_at":    bson.M{"$exists": false},
+			"review_status": models.ReviewFlagged,
+		},
+		options.Find().SetSort(bson.D{{Key: "date", Value: -1}}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	flagged := []models.Earnings{}
+	if err := cursor.All(context.Background(), &flagged); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	for i := range flagged {
+		flagged[i].Date = flagged[i].Date.In(loc)
+	}
+	c.JSON(http.StatusOK, gin.H{"earnings": flagged})
+}
+
+// ConfirmEarnings marks a flagged entry as correct so it counts everywhere again
+func ConfirmEarnings(c *gin.Context) {
+	before, ok := findUserEarnings(c)
+	if !ok {
+		return
+	}
+	if before.ReviewStatus != models.ReviewFlagged {
+		c.JSON(http.StatusConflict, gin.H{"error": "This entry does not need confirming"})
+		return
+	}
+
+	now := time.Now()
+	after := before
+	after.ReviewStatus = models.ReviewConfirmed
+	after.ConfirmedAt = &now
+	after.UpdatedAt = now
+	if err := stampEarningsWrite(&after, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm earnings"})
+		return
+	}
+	defer releaseEarningsWrite(&after)
+
+	_, err := config.GetDB().Collection("earnings").UpdateOne(
+		context.Background(),
+		bson.M{"_id": before.ID, "user_id": before.UserID},
+		bson.M{"$set": bson.M{
+			"review_status": after.ReviewStatus,
+			"confirmed_at":  now,
+			"updated_at":    now,
+			"modified_at":   after.ModifiedAt,
+			"sync_seq":      after.SyncSeq,
+		}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm earnings"})
+		return
+	}
+	recordEarningsChange(models.EarningsChangeUpdate, before.UserID, before.ID, &before, &after, "Confirmed unusual entry")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Earnings confirmed",
+		"earnings": after,
+	})
+}
+
+// reviewEarnings compares an entry with the driver's entries over the previous 60
+// days and flags it when revenue, expenses or trips are far outside the usual.
+// Unusually low figures are only flagged when they are not zero, as a day off is
+// normal but a dropped digit is not. Any earlier confirmation is cleared, since the
+// figures it confirmed have changed.
+func reviewEarnings(earnings *models.Earnings) {
+	earnings.ReviewStatus = ""
+	earnings.AnomalyReasons = nil
+	earnings.ConfirmedAt = nil
+
+	reasons, err := earningsAnomalies(*earnings)
+	if err != nil {
+		// Never hold up saving an entry because the check could not run
+		log.Printf("Error checking earnings for anomalies: %v", err)
+		return
+	}
+	if len(reasons) > 0 {
+		earnings.ReviewStatus = models.ReviewFlagged
+		earnings.AnomalyReasons = reasons
+	}
+}
+
+func earningsAnomalies(earnings models.Earnings) ([]string, error) {
+	cursor, err := config.GetDB().Collection("earnings").Find(
+		context.Background(),
+		excludeFlagged(bson.M{
+			"user_id":    earnings.UserID,
+			"_id":        bson.M{"$ne": earnings.ID},
+			"deleted_at": bson.M{"$exists": false},
+			"date":       bson.M{"$gte": earnings.Date.AddDate(0, 0, -anomalyBaselineDays), "$lt": earnings.Date},
+		}),
+		options.Find().SetProjection(bson.M{"revenue": 1, "expenses": 1, "trips": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var recent []models.Earnings
+	if err := cursor.All(context.Background(), &recent); err != nil {
+		return nil, err
+	}
+	if len(recent) < anomalyMinBaseline {
+		return nil, nil
+	}
+
+	var revenues, expenses, trips []float64
+	for _, entry := range recent {
+		revenues = append(revenues, entry.Revenue)
+		expenses = append(expenses, entry.Expenses)
+		trips = append(trips, float64(entry.Trips))
+	}
+
+	var reasons []string
+	if reason := anomalyReason("revenue", earnings.Revenue, utils.NewBaseline(revenues), true, true); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	if reason := anomalyReason("expenses", earnings.Expenses, utils.NewBaseline(expenses), true, false); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	if reason := anomalyReason("trips", float64(earnings.Trips), utils.NewBaseline(trips), false, true); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	return reasons, nil
+}
+
+// anomalyReason explains why value stands out from baseline, or returns "" if it does not
+func anomalyReason(field string, value float64, baseline utils.Baseline, money, checkLow bool) string {
+	minSpread := math.Max(baseline.Mean*anomalyMinSpreadShare, 1)
+	format := "%s of %.0f is much %s than your usual %.0f"
+	if money {
+		minSpread = math.Max(minSpread, anomalyMinSpreadRupees)
+		format = "%s of ₹%.0f is much %s than your usual ₹%.0f"
+	}
+
+	z := baseline.ZScore(value, minSpread)
+	switch {
+	case z >= utils.AnomalyZThreshold:
+		return fmt.Sprintf(format, field, value, "higher", baseline.Mean)
+	case checkLow && value > 0 && z <= -utils.AnomalyZThreshold:
+		return fmt.Sprintf(format, field, value, "lower", baseline.Mean)
+	}
+	return ""
+}
+
+// excludeFlagged leaves entries waiting for confirmation out of a query, so
+// unconfirmed figures never reach statements, tax estimates or AI advice
+func excludeFlagged(filter bson.M) bson.M {
+	filter["review_status"] = bson.M{"$ne": models.ReviewFlagged}
+	return filter
+}
+
+// withReviewFields builds an update that sets fields plus the entry's review state,
+// removing the review fields once the entry is no longer flagged or confirmed
+func withReviewFields(set bson.M, earnings models.Earnings) bson.M {
+	unset := bson.M{}
+	if earnings.ReviewStatus != "" {
+		set["review_status"] = earnings.ReviewStatus
+	} else {
+		unset["review_status"] = ""
+	}
+	if len(earnings.AnomalyReasons) > 0 {
+		set["anomaly_reasons"] = earnings.AnomalyReasons
+	} else {
+		unset["anomaly_reasons"] = ""
+	}
+	if earnings.ConfirmedAt != nil {
+		set["confirmed_at"] = earnings.ConfirmedAt
+	} else {
+		unset["confirmed_at"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
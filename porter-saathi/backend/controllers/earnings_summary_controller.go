@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxSummaryBuckets keeps a day-by-day request over a very long range from producing a huge response
+const maxSummaryBuckets = 4000
+
+// summaryFacets is the shape of the $facet stage in the summary pipeline
+type summaryFacets struct {
+	Buckets []struct {
+		Start    time.Time `bson:"_id"`
+		Revenue  float64   `bson:"revenue"`
+		Expenses float64   `bson:"expenses"`
+		Trips    int       `bson:"trips"`
+	} `bson:"buckets"`
+	Categories []struct {
+		Category string  `bson:"_id"`
+		Amount   float64 `bson:"amount"`
+	} `bson:"categories"`
+	Unitemized []struct {
+		Amount float64 `bson:"amount"`
+	} `bson:"unitemized"`
+}
+
+// GetEarningsSummary totals earnings between ?from= and ?to= (inclusive, YYYY-MM-DD)
+// in day, week or month buckets. Days without entries are returned as zero buckets.
+func GetEarningsSummary(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "day")
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be day, week or month"})
+		return
+	}
+
+	// Days, weeks and months follow the driver's timezone and week start
+	loc, weekStart := userCalendar(objectID)
+	to := utils.StartOfDay(time.Now(), loc)
+	if value := c.Query("to"); value != "" {
+		if to, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := c.Query("from"); value != "" {
+		if from, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	bucketStarts := summaryBucketStarts(from, end, granularity, weekStart)
+	if len(bucketStarts) > maxSummaryBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Range is too long for this granularity. Use week or month."})
+		return
+	}
+
+	facets, err := aggregateEarningsSummary(objectID, from, end, granularity, loc, weekStart, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarise earnings"})
+		return
+	}
+
+	// Keyed by Unix time, as the database returns bucket starts in UTC
+	totals := make(map[int64]models.EarningsBucket, len(facets.Buckets))
+	for _, bucket := range facets.Buckets {
+		totals[bucket.Start.Unix()] = models.EarningsBucket{
+			Revenue:     bucket.Revenue,
+			Expenses:    bucket.Expenses,
+			Trips:       bucket.Trips,
+			NetEarnings: bucket.Revenue - bucket.Expenses,
+		}
+	}
+
+	response := models.EarningsRangeResponse{
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Buckets:     make([]models.EarningsBucket, 0, len(bucketStarts)),
+	}
+	for _, start := range bucketStarts {
+		bucket := totals[start.Unix()]
+		// The first and last week or month are clipped to the requested range
+		bucket.Start = start
+		if bucket.Start.Before(from) {
+			bucket.Start = from
+		}
+		bucket.End = nextSummaryBucket(start, granularity)
+		if bucket.End.After(end) {
+			bucket.End = end
+		}
+		bucket.Label = summaryBucketLabel(start, granularity)
+		response.Buckets = append(response.Buckets, bucket)
+
+		response.Total.Revenue += bucket.Revenue
+		response.Total.Expenses += bucket.Expenses
+		response.Total.Trips += bucket.Trips
+	}
+	response.Total.NetEarnings = response.Total.Revenue - response.Total.Expenses
+
+	categoryAmounts := make(map[string]float64)
+	for _, category := range facets.Categories {
+		categoryAmounts[category.Category] += category.Amount
+	}
+	for _, unitemized := range facets.Unitemized {
+		categoryAmounts[models.ExpenseOther] += unitemized.Amount
+	}
+	response.Total.Categories = services.CategoryTotals(categoryAmounts, response.Total.Expenses)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// aggregateEarningsSummary runs one pipeline that buckets the totals and breaks
+// expenses down by category, so only the results leave the database. Entries
+// waiting for confirmation are only counted when includeFlagged is set.
+func aggregateEarningsSummary(userID primitive.ObjectID, from, end time.Time, granularity string, loc *time.Location, weekStart time.Weekday, includeFlagged bool) (summaryFacets, error) {
+	match := bson.M{
+		"user_id":    userID,
+		"deleted_at": bson.M{"$exists": false},
+		"date":       bson.M{"$gte": from, "$lt": end},
+	}
+	if !includeFlagged {
+		match = excludeFlagged(match)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"buckets": bson.A{
+				bson.M{"$group": bson.M{
+					"_id": bson.M{"$dateTrunc": bson.M{
+						"date":        "$date",
+						"unit":        granularity,
+						"startOfWeek": strings.ToLower(weekStart.String()),
+						"timezone":    loc.String(),
+					}},
+					"revenue":  bson.M{"$sum": "$revenue"},
+					"expenses": bson.M{"$sum": "$expenses"},
+					"trips":    bson.M{"$sum": "$trips"},
+				}},
+			},
+			"categories": bson.A{
+				bson.M{"$unwind": "$expense_items"},
+				bson.M{"$group": bson.M{
+					"_id":    "$expense_items.category",
+					"amount": bson.M{"$sum": "$expense_items.amount"},
+				}},
+			},
+			// Expenses recorded only as a total count as other
+			"unitemized": bson.A{
+				bson.M{"$project": bson.M{
+					"remainder": bson.M{"$subtract": bson.A{"$expenses", bson.M{"$sum": "$expense_items.amount"}}},
+				}},
+				bson.M{"$match": bson.M{"remainder": bson.M{"$gt": 0.005}}},
+				bson.M{"$group": bson.M{"_id": nil, "amount": bson.M{"$sum": "$remainder"}}},
+			},
+		}}},
+	}
+
+	var facets summaryFacets
+	cursor, err := config.GetDB().Collection("earnings").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return facets, err
+	}
+	defer cursor.Close(context.Background())
+
+	if cursor.Next(context.Background()) {
+		err = cursor.Decode(&facets)
+	}
+	if err == nil {
+		err = cursor.Err()
+	}
+	return facets, err
+}
+
+// summaryBucketStarts lists the start of every bucket overlapping [from, end)
+func summaryBucketStarts(from, end time.Time, granularity string, weekStart time.Weekday) []time.Time {
+	var starts []time.Time
+	for start := truncateToBucket(from, granularity, weekStart); start.Before(end); start = nextSummaryBucket(start, granularity) {
+		starts = append(starts, start)
+		if len(starts) > maxSummaryBuckets {
+			break
+		}
+	}
+	return starts
+}
+
+// truncateToBucket matches $dateTrunc in date's own timezone
+func truncateToBucket(date time.Time, granularity string, weekStart time.Weekday) time.Time {
+	day := utils.StartOfDay(date, date.Location())
+	switch granularity {
+	case "week":
+		return utils.StartOfWeek(day, day.Location(), weekStart)
+	case "month":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+func nextSummaryBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func summaryBucketLabel(start time.Time, granularity string) string {
+	switch granularity {
+	case "week":
+		return "Week of " + start.Format("02 Jan 2006")
+	case "month":
+		return start.Format("Jan 2006")
+	}
+	return start.Format("Mon 02 Jan")
+}
+
+// EnsureEarningsIndexes creates the (user_id, date) index every earnings query relies on,
+// the indexes behind offline sync (one entry per client ID, and the sync cursor), and
+// one trip rollup per driver and day
+func EnsureEarningsIndexes() {
+	_, err := config.GetDB().Collection("earnings").Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: -1}}},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"client_id": bson.M{"$type": "string"},
+			}),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "sync_seq", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}, {Key: "source", Value: 1}},
+			Options: options.Index().SetName("trip_rollup_per_day").SetUnique(true).SetPartialFilterExpression(bson.M{
+				"source": models.EarningsSourceTrips,
+			}),
+		},
+	})
+	if err != nil {
+		log.Printf("Error creating earnings indexes: %v", err)
+	}
+}
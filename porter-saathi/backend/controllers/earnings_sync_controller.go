@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// syncPageSize caps how many changed entries one sync response carries
+const syncPageSize = 500
+
+// SyncEarnings applies changes queued on the device and returns everything written
+// since the device's cursor. Conflicts are resolved the same way however often a
+// batch is retried:
+//   - operations run in client timestamp order, ties broken by opId; timestamps
+//     ahead of the server clock count as now
+//   - edits are last-writer-wins on the client timestamp; an older edit is reported
+//     as a conflict and the server copy is kept
+//   - deletes win over edits, and deleted entries are not brought back
+//   - a create or edit that was already applied is reported as a duplicate
+func SyncEarnings(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.SyncRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	// Timestamps are kept to the millisecond, as MongoDB stores them, so a retried
+	// operation compares equal to the one already saved
+	now := time.Now().Truncate(time.Millisecond)
+	for i := range request.Operations {
+		op := &request.Operations[i]
+		op.ClientTimestamp = op.ClientTimestamp.Truncate(time.Millisecond)
+		if op.ClientTimestamp.After(now) {
+			op.ClientTimestamp = now
+		}
+	}
+
+	order := make([]int, len(request.Operations))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		first, second := request.Operations[order[a]], request.Operations[order[b]]
+		if !first.ClientTimestamp.Equal(second.ClientTimestamp) {
+			return first.ClientTimestamp.Before(second.ClientTimestamp)
+		}
+		return first.OpID < second.OpID
+	})
+
+	// Results are returned in the order the operations were sent
+	response := models.SyncResponse{Results: make([]models.SyncResult, len(request.Operations))}
+	for _, i := range order {
+		result := applySyncOperation(objectID, request.Operations[i], loc, now)
+		if result.Earnings != nil {
+			result.Earnings.Date = result.Earnings.Date.In(loc)
+		}
+		response.Results[i] = result
+	}
+
+	// Only read up to the last sequence number whose write, and every earlier one, has finished
+	lowWater, err := utils.SyncLowWaterMark(context.Background(), objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	cursor, err := config.GetDB().Collection("earnings").Find(
+		context.Background(),
+		bson.M{"user_id": objectID, "sync_seq": bson.M{"$gt": request.Cursor, "$lte": lowWater}},
+		options.Find().SetSort(bson.D{{Key: "sync_seq", Value: 1}}).SetLimit(syncPageSize+1),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	response.Changes = []models.Earnings{}
+	if err := cursor.All(context.Background(), &response.Changes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if len(response.Changes) > syncPageSize {
+		response.Changes = response.Changes[:syncPageSize]
+		response.HasMore = true
+	}
+
+	response.Cursor = request.Cursor
+	for i := range response.Changes {
+		response.Changes[i].Date = response.Changes[i].Date.In(loc)
+		response.Cursor = max(response.Cursor, response.Changes[i].SyncSeq)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// applySyncOperation applies one queued change and reports what happened to it
+func applySyncOperation(userID primitive.ObjectID, op models.SyncOperation, loc *time.Location, now time.Time) models.SyncResult {
+	result := models.SyncResult{OpID: op.OpID, ClientID: op.ClientID}
+	reject := func(message string) models.SyncResult {
+		result.Status = models.SyncRejected
+		result.Error = message
+		return result
+	}
+
+	if op.Type != models.SyncDelete {
+		if op.Data == nil {
+			return reject("data is required for " + op.Type)
+		}
+		if err := binding.Validator.ValidateStruct(op.Data); err != nil {
+			return reject(err.Error())
+		}
+	}
+
+	var existing models.Earnings
+	var found bool
+	var err error
+	switch {
+	case op.ID != "":
+		earningsID, parseErr := primitive.ObjectIDFromHex(op.ID)
+		if parseErr != nil {
+			return reject("Invalid earnings ID")
+		}
+		err = config.GetDB().Collection("earnings").FindOne(context.Background(), bson.M{"_id": earningsID, "user_id": userID}).Decode(&existing)
+		found = err == nil
+		if err == mongo.ErrNoDocuments {
+			err = nil
+		}
+	case op.ClientID != "":
+		existing, found, err = findEarningsByClientID(userID, op.ClientID)
+	default:
+		return reject("id or clientId is required")
+	}
+	if err != nil {
+		return reject("Database error")
+	}
+
+	if found {
+		result.ID = existing.ID.Hex()
+		result.ClientID = existing.ClientID
+		if existing.Source == models.EarningsSourceTrips {
+			result.Earnings = &existing
+			return reject("This entry is calculated from your trips. Edit or delete the trips instead.")
+		}
+	}
+
+	switch op.Type {
+	case models.SyncCreate:
+		if found {
+			// A retried create, or one racing an edit from another device
+			return syncUpdate(existing, op, loc, now, result)
+		}
+		if op.ClientID == "" {
+			return reject("clientId is required for create")
+		}
+		return syncCreate(userID, op, loc, now, result)
+	case models.SyncUpdate:
+		if !found {
+			result.Status = models.SyncNotFound
+			return result
+		}
+		return syncUpdate(existing, op, loc, now, result)
+	case models.SyncDelete:
+		if !found {
+			result.Status = models.SyncNotFound
+			return result
+		}
+		return syncDelete(existing, op, now, result)
+	default:
+		return reject("Unknown operation type " + op.Type)
+	}
+}
+
+func syncCreate(userID primitive.ObjectID, op models.SyncOperation, loc *time.Location, now time.Time, result models.SyncResult) models.SyncResult {
+	earnings := models.Earnings{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		ClientID:  op.ClientID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if errMessage := applyEarningsRequest(&earnings, *op.Data, loc); errMessage != "" {
+		result.Status = models.SyncRejected
+		result.Error = errMessage
+		return result
+	}
+	reviewEarnings(&earnings)
+	if err := stampEarningsWrite(&earnings, op.ClientTimestamp); err != nil {
+		result.Status = models.SyncRejected
+		result.Error = "Failed to save earnings"
+		return result
+	}
+	defer releaseEarningsWrite(&earnings)
+
+	_, err := config.GetDB().Collection("earnings").InsertOne(context.Background(), earnings)
+	if mongo.IsDuplicateKeyError(err) {
+		if existing, found, _ := findEarningsByClientID(userID, op.ClientID); found {
+			result.Status = models.SyncDuplicate
+			result.ID = existing.ID.Hex()
+			result.Earnings = &existing
+			return result
+		}
+	}
+	if err != nil {
+		result.Status = models.SyncRejected
+		result.Error = "Failed to save earnings"
+		return result
+	}
+	recordEarningsChange(models.EarningsChangeCreate, userID, earnings.ID, nil, &earnings, syncReason(op))
+
+	result.Status = models.SyncApplied
+	result.ID = earnings.ID.Hex()
+	result.Earnings = &earnings
+	return result
+}
+
+func syncUpdate(existing models.Earnings, op models.SyncOperation, loc *time.Location, now time.Time, result models.SyncResult) models.SyncResult {
+	result.Earnings = &existing
+	if existing.DeletedAt != nil {
+		result.Status = models.SyncConflict
+		result.Error = "This entry was deleted"
+		return result
+	}
+
+	after := existing
+	if errMessage := applyEarningsRequest(&after, *op.Data, loc); errMessage != "" {
+		result.Status = models.SyncRejected
+		result.Error = errMessage
+		return result
+	}
+
+	lastModified := existing.ModifiedAt
+	if lastModified.IsZero() {
+		lastModified = existing.UpdatedAt
+	}
+	switch {
+	case op.ClientTimestamp.Before(lastModified):
+		result.Status = models.SyncConflict
+		result.Error = "A newer change to this entry is already saved"
+		return result
+	case op.ClientTimestamp.Equal(lastModified):
+		// Same moment: a retry of this change if the figures match, otherwise the saved copy wins
+		result.Status = models.SyncDuplicate
+		if !sameEarningsFigures(existing, after) {
+			result.Status = models.SyncConflict
+			result.Error = "A different change to this entry was saved at the same time"
+		}
+		return result
+	}
+
+	after.UpdatedAt = now
+	if !sameEarningsFigures(existing, after) {
+		reviewEarnings(&after)
+	}
+	if err := stampEarningsWrite(&after, op.ClientTimestamp); err != nil {
+		result.Status = models.SyncRejected
+		result.Error = "Failed to update earnings"
+		return result
+	}
+	defer releaseEarningsWrite(&after)
+
+	// Only write over the version that was compared against
+	update, err := config.GetDB().Collection("earnings").UpdateOne(
+		context.Background(),
+		bson.M{"_id": existing.ID, "user_id": existing.UserID, "sync_seq": existing.SyncSeq},
+		withReviewFields(bson.M{
+			"date":          after.Date,
+			"revenue":       after.Revenue,
+			"expenses":      after.Expenses,
+			"expense_items": after.ExpenseItems,
+			"trips":         after.Trips,
+			"net_earnings":  after.NetEarnings,
+			"updated_at":    after.UpdatedAt,
+			"modified_at":   after.ModifiedAt,
+			"sync_seq":      after.SyncSeq,
+		}, after),
+	)
+	if err != nil {
+		result.Status = models.SyncRejected
+		result.Error = "Failed to update earnings"
+		return result
+	}
+	if update.MatchedCount == 0 {
+		result.Status = models.SyncConflict
+		result.Error = "This entry changed during the sync; sync again"
+		return result
+	}
+	recordEarningsChange(models.EarningsChangeUpdate, existing.UserID, existing.ID, &existing, &after, syncReason(op))
+
+	result.Status = models.SyncApplied
+	result.Earnings = &after
+	return result
+}
+
+func syncDelete(existing models.Earnings, op models.SyncOperation, now time.Time, result models.SyncResult) models.SyncResult {
+	result.Earnings = &existing
+	if existing.DeletedAt != nil {
+		result.Status = models.SyncDuplicate
+		return result
+	}
+
+	after := existing
+	after.DeletedAt = &now
+	after.UpdatedAt = now
+	// Deletes win, but never move the modification time backwards
+	modifiedAt := op.ClientTimestamp
+	if modifiedAt.Before(existing.ModifiedAt) {
+		modifiedAt = existing.ModifiedAt
+	}
+	if err := stampEarningsWrite(&after, modifiedAt); err != nil {
+		result.Status = models.SyncRejected
+		result.Error = "Failed to delete earnings"
+		return result
+	}
+	defer releaseEarningsWrite(&after)
+
+	// Only delete the version that was read, so a concurrent edit or restore is not lost
+	update, err := config.GetDB().Collection("earnings").UpdateOne(
+		context.Background(),
+		bson.M{"_id": existing.ID, "user_id": existing.UserID, "sync_seq": existing.SyncSeq},
+		bson.M{"$set": bson.M{
+			"deleted_at":  now,
+			"updated_at":  now,
+			"modified_at": after.ModifiedAt,
+			"sync_seq":    after.SyncSeq,
+		}},
+	)
+	if err != nil {
+		result.Status = models.SyncRejected
+		result.Error = "Failed to delete earnings"
+		return result
+	}
+	if update.MatchedCount == 0 {
+		result.Status = models.SyncConflict
+		result.Error = "This entry changed during the sync; sync again"
+		return result
+	}
+	recordEarningsChange(models.EarningsChangeDelete, existing.UserID, existing.ID, &existing, &after, syncReason(op))
+
+	result.Status = models.SyncApplied
+	result.Earnings = &after
+	return result
+}
+
+// sameEarningsFigures compares what a driver can edit on an entry
+func sameEarningsFigures(a, b models.Earnings) bool {
+	if len(a.ExpenseItems) == 0 && len(b.ExpenseItems) == 0 {
+		a.ExpenseItems, b.ExpenseItems = nil, nil
+	}
+	return a.Date.Equal(b.Date) &&
+		a.Revenue == b.Revenue &&
+		a.Expenses == b.Expenses &&
+		a.Trips == b.Trips &&
+		reflect.DeepEqual(a.ExpenseItems, b.ExpenseItems)
+}
+
+func syncReason(op models.SyncOperation) string {
+	if op.Reason != "" {
+		return op.Reason
+	}
+	return "Synced from device"
+}
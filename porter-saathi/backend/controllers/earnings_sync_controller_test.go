@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"porter-saathi-backend/models"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestSyncRequestValidation(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		op      models.SyncOperation
+		wantErr bool
+	}{
+		{"delete", models.SyncOperation{OpID: "op-1", Type: models.SyncDelete, ID: "abc", ClientTimestamp: now}, false},
+		{"unknown type", models.SyncOperation{OpID: "op-1", Type: "bogus", ID: "abc", ClientTimestamp: now}, true},
+		{"no type", models.SyncOperation{OpID: "op-1", ID: "abc", ClientTimestamp: now}, true},
+		{"no opId", models.SyncOperation{Type: models.SyncDelete, ID: "abc", ClientTimestamp: now}, true},
+		{"no clientTimestamp", models.SyncOperation{OpID: "op-1", Type: models.SyncDelete, ID: "abc"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := models.SyncRequest{Operations: []models.SyncOperation{tt.op}}
+			if err := binding.Validator.ValidateStruct(&request); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStruct() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,1065 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fleetDocumentWarningDays is how soon before expiry a vehicle's papers show as expiring
+const fleetDocumentWarningDays = 30
+
+// fleetSharedWithOwner tells drivers exactly what their fleet owner can see
+var fleetSharedWithOwner = []string{
+	"Your name and mobile number",
+	"Total revenue, expenses, trips and days worked while you are in the fleet",
+	"Which of your documents are uploaded and whether your account is verified",
+}
+
+// fleetDay is one day of a driver's earnings
+type fleetDay struct {
+	Date     time.Time `bson:"_id"`
+	Revenue  float64   `bson:"revenue"`
+	Expenses float64   `bson:"expenses"`
+	Trips    int       `bson:"trips"`
+}
+
+// CreateFleet registers the signed-in user as the owner of a new fleet
+func CreateFleet(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.FleetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fleet := models.Fleet{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   objectID,
+		Name:      strings.TrimSpace(request.Name),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	_, err = config.GetDB().Collection("fleets").InsertOne(context.Background(), fleet)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a fleet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create fleet"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Fleet created successfully",
+		"fleet":   fleet,
+	})
+}
+
+// GetFleet returns the owner's fleet with its vehicles and drivers
+func GetFleet(c *gin.Context) {
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return
+	}
+
+	vehicles, err := loadFleetVehicles(fleet.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	members, err := loadFleetMembers(bson.M{
+		"fleet_id": fleet.ID,
+		"status":   bson.M{"$in": []string{models.FleetMemberInvited, models.FleetMemberActive}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.FleetDetail{Fleet: fleet, Vehicles: vehicles, Members: members})
+}
+
+// UpdateFleet renames the owner's fleet
+func UpdateFleet(c *gin.Context) {
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return
+	}
+
+	var request models.FleetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fleet.Name = strings.TrimSpace(request.Name)
+	fleet.UpdatedAt = time.Now()
+	_, err := config.GetDB().Collection("fleets").UpdateOne(
+		context.Background(),
+		bson.M{"_id": fleet.ID},
+		bson.M{"$set": bson.M{"name": fleet.Name, "updated_at": fleet.UpdatedAt}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update fleet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fleet updated successfully",
+		"fleet":   fleet,
+	})
+}
+
+// AddFleetVehicle adds a vehicle to the owner's fleet
+func AddFleetVehicle(c *gin.Context) {
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return
+	}
+
+	var request models.FleetVehicleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vehicle := models.FleetVehicle{
+		ID:        primitive.NewObjectID(),
+		FleetID:   fleet.ID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	loc, _ := userCalendar(fleet.OwnerID)
+	if errMessage := applyFleetVehicleRequest(&vehicle, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+
+	_, err := config.GetDB().Collection("fleet_vehicles").InsertOne(context.Background(), vehicle)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This vehicle is already in your fleet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Vehicle added successfully",
+		"vehicle": vehicle,
+	})
+}
+
+// UpdateFleetVehicle corrects a fleet vehicle's details and document expiry dates
+func UpdateFleetVehicle(c *gin.Context) {
+	fleet, vehicle, ok := findFleetVehicle(c)
+	if !ok {
+		return
+	}
+
+	var request models.FleetVehicleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(fleet.OwnerID)
+	if errMessage := applyFleetVehicleRequest(&vehicle, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+	vehicle.UpdatedAt = time.Now()
+
+	_, err := config.GetDB().Collection("fleet_vehicles").ReplaceOne(context.Background(), bson.M{"_id": vehicle.ID}, vehicle)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This vehicle is already in your fleet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vehicle updated successfully",
+		"vehicle": vehicle,
+	})
+}
+
+// DeleteFleetVehicle removes a vehicle and its assignment history from the fleet
+func DeleteFleetVehicle(c *gin.Context) {
+	_, vehicle, ok := findFleetVehicle(c)
+	if !ok {
+		return
+	}
+
+	if _, err := config.GetDB().Collection("fleet_vehicles").DeleteOne(context.Background(), bson.M{"_id": vehicle.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vehicle"})
+		return
+	}
+	if _, err := config.GetDB().Collection("fleet_assignments").DeleteMany(context.Background(), bson.M{"vehicle_id": vehicle.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle removed from fleet"})
+}
+
+// AssignFleetVehicle gives a vehicle to an active driver, or takes it back when driverId is
+// empty. A driver has one vehicle at a time, so any vehicle they had is unassigned.
+func AssignFleetVehicle(c *gin.Context) {
+	fleet, vehicle, ok := findFleetVehicle(c)
+	if !ok {
+		return
+	}
+
+	var request models.FleetAssignRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var driverID primitive.ObjectID
+	if request.DriverID != "" {
+		var err error
+		if driverID, err = primitive.ObjectIDFromHex(request.DriverID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID"})
+			return
+		}
+		count, err := config.GetDB().Collection("fleet_members").CountDocuments(
+			context.Background(),
+			bson.M{"fleet_id": fleet.ID, "user_id": driverID, "status": models.FleetMemberActive},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Vehicles can only be assigned to drivers who have joined your fleet"})
+			return
+		}
+	}
+
+	now := time.Now()
+	if err := endFleetAssignments(bson.M{"vehicle_id": vehicle.ID}, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign vehicle"})
+		return
+	}
+	vehicle.DriverID, vehicle.AssignedAt, vehicle.UpdatedAt = nil, nil, now
+
+	if request.DriverID != "" {
+		if err := endFleetAssignments(bson.M{"fleet_id": fleet.ID, "driver_id": driverID}, now); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign vehicle"})
+			return
+		}
+		assignment := models.FleetAssignment{
+			ID:        primitive.NewObjectID(),
+			FleetID:   fleet.ID,
+			VehicleID: vehicle.ID,
+			DriverID:  driverID,
+			From:      now,
+		}
+		if _, err := config.GetDB().Collection("fleet_assignments").InsertOne(context.Background(), assignment); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign vehicle"})
+			return
+		}
+		vehicle.DriverID, vehicle.AssignedAt = &driverID, &now
+	}
+
+	// Ending the vehicle's assignment already cleared its driver
+	if vehicle.DriverID != nil {
+		_, err := config.GetDB().Collection("fleet_vehicles").UpdateOne(
+			context.Background(),
+			bson.M{"_id": vehicle.ID},
+			bson.M{"$set": bson.M{"driver_id": vehicle.DriverID, "assigned_at": vehicle.AssignedAt, "updated_at": now}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign vehicle"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vehicle assignment updated",
+		"vehicle": vehicle,
+	})
+}
+
+// InviteFleetDriver invites a driver by mobile number. They join by accepting from
+// their own account, which they can create after the invite is sent.
+func InviteFleetDriver(c *gin.Context) {
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return
+	}
+
+	var request models.FleetInviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	mobile := strings.Join(strings.Fields(request.Mobile), "")
+
+	var owner models.User
+	err := config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": fleet.OwnerID},
+		options.FindOne().SetProjection(bson.M{"mobile": 1}),
+	).Decode(&owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if owner.Mobile == mobile {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot invite yourself"})
+		return
+	}
+
+	collection := config.GetDB().Collection("fleet_members")
+	count, err := collection.CountDocuments(context.Background(), bson.M{
+		"fleet_id": fleet.ID,
+		"mobile":   mobile,
+		"status":   bson.M{"$in": []string{models.FleetMemberInvited, models.FleetMemberActive}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This driver has already been invited"})
+		return
+	}
+
+	member := models.FleetMember{
+		ID:        primitive.NewObjectID(),
+		FleetID:   fleet.ID,
+		Mobile:    mobile,
+		Status:    models.FleetMemberInvited,
+		InvitedAt: time.Now(),
+	}
+	if _, err := collection.InsertOne(context.Background(), member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite driver"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Driver invited successfully",
+		"member":  member,
+	})
+}
+
+// RemoveFleetDriver cancels an invite or removes a driver from the fleet. The owner
+// keeps the totals from while they drove for the fleet, but sees nothing after.
+func RemoveFleetDriver(c *gin.Context) {
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return
+	}
+	memberID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID"})
+		return
+	}
+
+	var member models.FleetMember
+	err = config.GetDB().Collection("fleet_members").FindOne(
+		context.Background(),
+		bson.M{
+			"_id":      memberID,
+			"fleet_id": fleet.ID,
+			"status":   bson.M{"$in": []string{models.FleetMemberInvited, models.FleetMemberActive}},
+		},
+	).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if member.Status == models.FleetMemberInvited {
+		if _, err := config.GetDB().Collection("fleet_members").DeleteOne(context.Background(), bson.M{"_id": member.ID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel invite"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Invite cancelled"})
+		return
+	}
+
+	if err := endFleetMembership(member, models.FleetMemberRemoved, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove driver"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Driver removed from fleet"})
+}
+
+// GetFleetDashboard reports each vehicle's and driver's earnings between ?from= and ?to=
+// (default the last 30 days) with the state of their documents. A day's earnings count
+// towards the vehicle the driver had that day.
+func GetFleetDashboard(c *gin.Context) {
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	loc, _ := userCalendar(fleet.OwnerID)
+	today := utils.StartOfDay(time.Now(), loc)
+	to := today
+	if value := c.Query("to"); value != "" {
+		if to, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := c.Query("from"); value != "" {
+		if from, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	dashboard, err := buildFleetDashboard(fleet, from, end, today, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load fleet dashboard"})
+		return
+	}
+	dashboard.To = to
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// GetFleetInvites lists pending invites to the signed-in driver's mobile number
+func GetFleetInvites(c *gin.Context) {
+	user, ok := fleetDriver(c)
+	if !ok {
+		return
+	}
+
+	members, err := loadFleetMembers(bson.M{"mobile": user.Mobile, "status": models.FleetMemberInvited})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	invites := []models.FleetInvite{}
+	for _, member := range members {
+		fleet, ownerName, err := fleetWithOwner(member.FleetID)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		invites = append(invites, models.FleetInvite{
+			ID:        member.ID,
+			FleetName: fleet.Name,
+			OwnerName: ownerName,
+			InvitedAt: member.InvitedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+// AcceptFleetInvite joins the fleet. A driver drives for one fleet at a time.
+func AcceptFleetInvite(c *gin.Context) {
+	user, member, ok := findFleetInvite(c)
+	if !ok {
+		return
+	}
+
+	count, err := config.GetDB().Collection("fleet_members").CountDocuments(
+		context.Background(),
+		bson.M{"user_id": user.ID, "status": models.FleetMemberActive},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Leave your current fleet before joining another"})
+		return
+	}
+
+	now := time.Now()
+	_, err = config.GetDB().Collection("fleet_members").UpdateOne(
+		context.Background(),
+		bson.M{"_id": member.ID, "status": models.FleetMemberInvited},
+		bson.M{"$set": bson.M{"user_id": user.ID, "status": models.FleetMemberActive, "joined_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join fleet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "You have joined the fleet",
+		"sharedWithOwner": fleetSharedWithOwner,
+	})
+}
+
+// DeclineFleetInvite turns down an invite
+func DeclineFleetInvite(c *gin.Context) {
+	_, member, ok := findFleetInvite(c)
+	if !ok {
+		return
+	}
+
+	_, err := config.GetDB().Collection("fleet_members").UpdateOne(
+		context.Background(),
+		bson.M{"_id": member.ID, "status": models.FleetMemberInvited},
+		bson.M{"$set": bson.M{"status": models.FleetMemberDeclined}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invite"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invite declined"})
+}
+
+// GetFleetMembership shows the driver their fleet, their vehicle and what the owner can see
+func GetFleetMembership(c *gin.Context) {
+	user, ok := fleetDriver(c)
+	if !ok {
+		return
+	}
+	member, ok := activeFleetMember(c, user.ID)
+	if !ok {
+		return
+	}
+
+	fleet, ownerName, err := fleetWithOwner(member.FleetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	membership := models.FleetMembership{
+		FleetID:         fleet.ID,
+		FleetName:       fleet.Name,
+		OwnerName:       ownerName,
+		JoinedAt:        *member.JoinedAt,
+		SharedWithOwner: fleetSharedWithOwner,
+	}
+
+	var vehicle models.FleetVehicle
+	err = config.GetDB().Collection("fleet_vehicles").FindOne(
+		context.Background(),
+		bson.M{"fleet_id": fleet.ID, "driver_id": user.ID},
+	).Decode(&vehicle)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	membership.VehicleNumber = vehicle.VehicleNumber
+
+	c.JSON(http.StatusOK, membership)
+}
+
+// LeaveFleet ends the driver's membership; the owner sees nothing from them afterwards
+func LeaveFleet(c *gin.Context) {
+	user, ok := fleetDriver(c)
+	if !ok {
+		return
+	}
+	member, ok := activeFleetMember(c, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := endFleetMembership(member, models.FleetMemberLeft, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave fleet"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You have left the fleet"})
+}
+
+// buildFleetDashboard adds up each member's earnings in [from, end) while they were in
+// the fleet, and each vehicle's from the days it was assigned to them
+func buildFleetDashboard(fleet models.Fleet, from, end, today time.Time, loc *time.Location) (models.FleetDashboard, error) {
+	dashboard := models.FleetDashboard{
+		FleetID:  fleet.ID,
+		Name:     fleet.Name,
+		From:     from,
+		Vehicles: []models.FleetVehicleReport{},
+		Drivers:  []models.FleetDriverReport{},
+	}
+
+	vehicles, err := loadFleetVehicles(fleet.ID)
+	if err != nil {
+		return dashboard, err
+	}
+	// Members who left before the period have nothing to show
+	members, err := loadFleetMembers(bson.M{
+		"fleet_id": fleet.ID,
+		"$or": []bson.M{
+			{"status": bson.M{"$in": []string{models.FleetMemberInvited, models.FleetMemberActive}}},
+			{"left_at": bson.M{"$gt": from}},
+		},
+	})
+	if err != nil {
+		return dashboard, err
+	}
+	cursor, err := config.GetDB().Collection("fleet_assignments").Find(
+		context.Background(),
+		bson.M{
+			"fleet_id": fleet.ID,
+			"from":     bson.M{"$lt": end},
+			"$or":      []bson.M{{"to": bson.M{"$exists": false}}, {"to": bson.M{"$gt": from}}},
+		},
+	)
+	if err != nil {
+		return dashboard, err
+	}
+	var assignments []models.FleetAssignment
+	if err := cursor.All(context.Background(), &assignments); err != nil {
+		return dashboard, err
+	}
+
+	var userIDs []primitive.ObjectID
+	for _, member := range members {
+		if member.UserID != nil {
+			userIDs = append(userIDs, *member.UserID)
+		}
+	}
+	users := make(map[primitive.ObjectID]models.User, len(userIDs))
+	if len(userIDs) > 0 {
+		cursor, err := config.GetDB().Collection("users").Find(
+			context.Background(),
+			bson.M{"_id": bson.M{"$in": userIDs}},
+			options.Find().SetProjection(bson.M{"name": 1, "documents": 1, "is_verified": 1}),
+		)
+		if err != nil {
+			return dashboard, err
+		}
+		var found []models.User
+		if err := cursor.All(context.Background(), &found); err != nil {
+			return dashboard, err
+		}
+		for _, user := range found {
+			users[user.ID] = user
+		}
+	}
+
+	vehicleTotals := make(map[primitive.ObjectID]*models.FleetTotals, len(vehicles))
+	vehicleNumbers := make(map[primitive.ObjectID]string, len(vehicles))
+	for _, vehicle := range vehicles {
+		vehicleTotals[vehicle.ID] = &models.FleetTotals{}
+		vehicleNumbers[vehicle.ID] = vehicle.VehicleNumber
+	}
+
+	for _, member := range members {
+		report := models.FleetDriverReport{
+			MemberID:  member.ID,
+			UserID:    member.UserID,
+			Mobile:    member.Mobile,
+			Status:    member.Status,
+			Documents: map[string]bool{},
+		}
+		if member.UserID == nil || member.JoinedAt == nil {
+			dashboard.Drivers = append(dashboard.Drivers, report)
+			continue
+		}
+		driverID := *member.UserID
+		user := users[driverID]
+		report.Name = user.Name
+		report.Verified = user.IsVerified
+		report.Documents = map[string]bool{
+			"aadharCard":     user.Documents.AadharCard != nil,
+			"drivingLicense": user.Documents.DrivingLicense != nil,
+			"vehicleRC":      user.Documents.VehicleRC != nil,
+			"profilePhoto":   user.Documents.ProfilePhoto != nil,
+		}
+		for _, vehicle := range vehicles {
+			if vehicle.DriverID != nil && *vehicle.DriverID == driverID {
+				report.VehicleNumber = vehicle.VehicleNumber
+			}
+		}
+
+		// Only days from the one they joined up to the one they left are shared with the owner
+		windowFrom, windowEnd := from, end
+		if joined := utils.StartOfDay(*member.JoinedAt, loc); joined.After(windowFrom) {
+			windowFrom = joined
+		}
+		if member.LeftAt != nil {
+			if left := utils.StartOfDay(*member.LeftAt, loc); left.Before(windowEnd) {
+				windowEnd = left
+			}
+		}
+		if windowFrom.Before(windowEnd) {
+			days, err := fleetDriverDays(driverID, windowFrom, windowEnd)
+			if err != nil {
+				return dashboard, err
+			}
+			for _, day := range days {
+				addFleetDay(&report.Totals, day)
+				lastWorked := day.Date.In(loc)
+				report.LastWorked = &lastWorked
+				if vehicleID, ok := fleetVehicleOn(assignments, driverID, day.Date, loc); ok && vehicleTotals[vehicleID] != nil {
+					addFleetDay(vehicleTotals[vehicleID], day)
+				}
+			}
+		}
+		roundFleetTotals(&report.Totals)
+		addFleetTotals(&dashboard.Totals, report.Totals)
+		dashboard.Drivers = append(dashboard.Drivers, report)
+	}
+	roundFleetTotals(&dashboard.Totals)
+
+	for _, vehicle := range vehicles {
+		report := models.FleetVehicleReport{
+			VehicleID:     vehicle.ID,
+			VehicleNumber: vehicle.VehicleNumber,
+			VehicleClass:  vehicle.VehicleClass,
+			Totals:        *vehicleTotals[vehicle.ID],
+			Documents: []models.FleetDocumentStatus{
+				fleetDocumentStatus("insurance", vehicle.InsuranceExpiry, today),
+				fleetDocumentStatus("permit", vehicle.PermitExpiry, today),
+				fleetDocumentStatus("fitness", vehicle.FitnessExpiry, today),
+				fleetDocumentStatus("puc", vehicle.PUCExpiry, today),
+			},
+		}
+		if vehicle.DriverID != nil {
+			report.DriverName = users[*vehicle.DriverID].Name
+		}
+		roundFleetTotals(&report.Totals)
+		dashboard.Vehicles = append(dashboard.Vehicles, report)
+	}
+	return dashboard, nil
+}
+
+// fleetDriverDays returns a driver's daily totals in [from, to), leaving out entries waiting for confirmation
+func fleetDriverDays(userID primitive.ObjectID, from, to time.Time) ([]fleetDay, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: excludeFlagged(bson.M{
+			"user_id":    userID,
+			"deleted_at": bson.M{"$exists": false},
+			"date":       bson.M{"$gte": from, "$lt": to},
+		})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$date",
+			"revenue":  bson.M{"$sum": "$revenue"},
+			"expenses": bson.M{"$sum": "$expenses"},
+			"trips":    bson.M{"$sum": "$trips"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	cursor, err := config.GetDB().Collection("earnings").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var days []fleetDay
+	if err := cursor.All(context.Background(), &days); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// fleetVehicleOn finds the vehicle a driver had on a day. A vehicle handed over
+// during a day counts as the new driver's from that day.
+func fleetVehicleOn(assignments []models.FleetAssignment, driverID primitive.ObjectID, day time.Time, loc *time.Location) (primitive.ObjectID, bool) {
+	for _, assignment := range assignments {
+		if assignment.DriverID != driverID || day.Before(utils.StartOfDay(assignment.From, loc)) {
+			continue
+		}
+		if assignment.To == nil || day.Before(utils.StartOfDay(*assignment.To, loc)) {
+			return assignment.VehicleID, true
+		}
+	}
+	return primitive.NilObjectID, false
+}
+
+func addFleetDay(totals *models.FleetTotals, day fleetDay) {
+	totals.Revenue += day.Revenue
+	totals.Expenses += day.Expenses
+	totals.Trips += day.Trips
+	totals.DaysWorked++
+}
+
+func addFleetTotals(totals *models.FleetTotals, add models.FleetTotals) {
+	totals.Revenue += add.Revenue
+	totals.Expenses += add.Expenses
+	totals.Trips += add.Trips
+	totals.DaysWorked += add.DaysWorked
+}
+
+func roundFleetTotals(totals *models.FleetTotals) {
+	totals.Revenue = utils.RoundMoney(totals.Revenue)
+	totals.Expenses = utils.RoundMoney(totals.Expenses)
+	totals.NetEarnings = utils.RoundMoney(totals.Revenue - totals.Expenses)
+}
+
+// fleetDocumentStatus says whether a paper is valid, expires within fleetDocumentWarningDays, has expired or has no date
+func fleetDocumentStatus(document string, expiry *time.Time, today time.Time) models.FleetDocumentStatus {
+	status := models.FleetDocumentStatus{Document: document, Status: models.DocumentMissing, Expiry: expiry}
+	switch {
+	case expiry == nil:
+	case expiry.Before(today):
+		status.Status = models.DocumentExpired
+	case expiry.Before(today.AddDate(0, 0, fleetDocumentWarningDays)):
+		status.Status = models.DocumentExpiring
+	default:
+		status.Status = models.DocumentValid
+	}
+	return status
+}
+
+func applyFleetVehicleRequest(vehicle *models.FleetVehicle, request models.FleetVehicleRequest, loc *time.Location) string {
+	vehicle.VehicleNumber = strings.ToUpper(strings.Join(strings.Fields(request.VehicleNumber), ""))
+	if vehicle.VehicleNumber == "" {
+		return "vehicleNumber is required"
+	}
+	vehicle.VehicleClass = request.VehicleClass
+
+	dates := []struct {
+		name  string
+		value string
+		field **time.Time
+	}{
+		{"insuranceExpiry", request.InsuranceExpiry, &vehicle.InsuranceExpiry},
+		{"permitExpiry", request.PermitExpiry, &vehicle.PermitExpiry},
+		{"fitnessExpiry", request.FitnessExpiry, &vehicle.FitnessExpiry},
+		{"pucExpiry", request.PUCExpiry, &vehicle.PUCExpiry},
+	}
+	for _, date := range dates {
+		*date.field = nil
+		if date.value == "" {
+			continue
+		}
+		parsed, err := utils.ParseLocalDate(date.value, loc)
+		if err != nil {
+			return "Invalid " + date.name + " date. Use YYYY-MM-DD"
+		}
+		*date.field = &parsed
+	}
+	return ""
+}
+
+// endFleetMembership marks a member as removed or left and takes back their vehicle
+func endFleetMembership(member models.FleetMember, status string, now time.Time) error {
+	_, err := config.GetDB().Collection("fleet_members").UpdateOne(
+		context.Background(),
+		bson.M{"_id": member.ID},
+		bson.M{"$set": bson.M{"status": status, "left_at": now}},
+	)
+	if err != nil || member.UserID == nil {
+		return err
+	}
+	return endFleetAssignments(bson.M{"fleet_id": member.FleetID, "driver_id": *member.UserID}, now)
+}
+
+// endFleetAssignments closes the current assignments matching filter and unassigns their vehicles
+func endFleetAssignments(filter bson.M, now time.Time) error {
+	filter["to"] = bson.M{"$exists": false}
+	collection := config.GetDB().Collection("fleet_assignments")
+	cursor, err := collection.Find(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	var open []models.FleetAssignment
+	if err := cursor.All(context.Background(), &open); err != nil {
+		return err
+	}
+	for _, assignment := range open {
+		if _, err := collection.UpdateOne(context.Background(), bson.M{"_id": assignment.ID}, bson.M{"$set": bson.M{"to": now}}); err != nil {
+			return err
+		}
+		_, err := config.GetDB().Collection("fleet_vehicles").UpdateOne(
+			context.Background(),
+			bson.M{"_id": assignment.VehicleID, "driver_id": assignment.DriverID},
+			bson.M{"$unset": bson.M{"driver_id": "", "assigned_at": ""}, "$set": bson.M{"updated_at": now}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownerFleet loads the signed-in user's own fleet, writing the error response if they have none
+func ownerFleet(c *gin.Context) (models.Fleet, bool) {
+	var fleet models.Fleet
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return fleet, false
+	}
+
+	err = config.GetDB().Collection("fleets").FindOne(context.Background(), bson.M{"owner_id": objectID}).Decode(&fleet)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You do not have a fleet"})
+		return fleet, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return fleet, false
+	}
+	return fleet, true
+}
+
+func findFleetVehicle(c *gin.Context) (models.Fleet, models.FleetVehicle, bool) {
+	var vehicle models.FleetVehicle
+	fleet, ok := ownerFleet(c)
+	if !ok {
+		return fleet, vehicle, false
+	}
+	vehicleID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return fleet, vehicle, false
+	}
+
+	err = config.GetDB().Collection("fleet_vehicles").FindOne(context.Background(), bson.M{"_id": vehicleID, "fleet_id": fleet.ID}).Decode(&vehicle)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+		return fleet, vehicle, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return fleet, vehicle, false
+	}
+	return fleet, vehicle, true
+}
+
+// fleetDriver loads the signed-in driver, whose mobile number invites are matched on
+func fleetDriver(c *gin.Context) (models.User, bool) {
+	var user models.User
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return user, false
+	}
+
+	err = config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": objectID},
+		options.FindOne().SetProjection(bson.M{"mobile": 1, "name": 1}),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return user, false
+	}
+	user.Mobile = strings.Join(strings.Fields(user.Mobile), "")
+	return user, true
+}
+
+// findFleetInvite loads a pending invite addressed to the signed-in driver's mobile number
+func findFleetInvite(c *gin.Context) (models.User, models.FleetMember, bool) {
+	var member models.FleetMember
+	user, ok := fleetDriver(c)
+	if !ok {
+		return user, member, false
+	}
+	memberID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return user, member, false
+	}
+
+	err = config.GetDB().Collection("fleet_members").FindOne(
+		context.Background(),
+		bson.M{"_id": memberID, "mobile": user.Mobile, "status": models.FleetMemberInvited},
+	).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return user, member, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return user, member, false
+	}
+	return user, member, true
+}
+
+func activeFleetMember(c *gin.Context, userID primitive.ObjectID) (models.FleetMember, bool) {
+	var member models.FleetMember
+	err := config.GetDB().Collection("fleet_members").FindOne(
+		context.Background(),
+		bson.M{"user_id": userID, "status": models.FleetMemberActive},
+	).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not part of a fleet"})
+		return member, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return member, false
+	}
+	return member, true
+}
+
+// fleetWithOwner loads a fleet and its owner's name
+func fleetWithOwner(fleetID primitive.ObjectID) (models.Fleet, string, error) {
+	var fleet models.Fleet
+	if err := config.GetDB().Collection("fleets").FindOne(context.Background(), bson.M{"_id": fleetID}).Decode(&fleet); err != nil {
+		return fleet, "", err
+	}
+	var owner models.User
+	err := config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": fleet.OwnerID},
+		options.FindOne().SetProjection(bson.M{"name": 1}),
+	).Decode(&owner)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fleet, "", err
+	}
+	return fleet, owner.Name, nil
+}
+
+func loadFleetVehicles(fleetID primitive.ObjectID) ([]models.FleetVehicle, error) {
+	cursor, err := config.GetDB().Collection("fleet_vehicles").Find(context.Background(), bson.M{"fleet_id": fleetID})
+	if err != nil {
+		return nil, err
+	}
+	vehicles := []models.FleetVehicle{}
+	if err := cursor.All(context.Background(), &vehicles); err != nil {
+		return nil, err
+	}
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].VehicleNumber < vehicles[j].VehicleNumber })
+	return vehicles, nil
+}
+
+func loadFleetMembers(filter bson.M) ([]models.FleetMember, error) {
+	cursor, err := config.GetDB().Collection("fleet_members").Find(
+		context.Background(),
+		filter,
+		options.Find().SetSort(bson.D{{Key: "invited_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	members := []models.FleetMember{}
+	if err := cursor.All(context.Background(), &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
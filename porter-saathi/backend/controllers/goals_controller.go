@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// goalStreakDays is how far back streaks are counted
+const goalStreakDays = 365
+
+// GetEarningsGoals returns the driver's targets with progress, streak and projections
+func GetEarningsGoals(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	response, err := buildGoalsResponse(objectID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate goal progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateEarningsGoals sets daily, weekly or monthly targets; periods left out keep their targets
+func UpdateEarningsGoals(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.GoalsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	goals, found, err := loadEarningsGoals(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !found {
+		goals.ID = primitive.NewObjectID()
+		goals.CreatedAt = time.Now()
+	}
+	if request.Daily != nil {
+		goals.Daily = *request.Daily
+	}
+	if request.Weekly != nil {
+		goals.Weekly = *request.Weekly
+	}
+	if request.Monthly != nil {
+		goals.Monthly = *request.Monthly
+	}
+	goals.UpdatedAt = time.Now()
+
+	collection := config.GetDB().Collection("earnings_goals")
+	_, err = collection.ReplaceOne(
+		context.Background(),
+		bson.M{"user_id": objectID},
+		goals,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save goals"})
+		return
+	}
+	// The chat assistant's cached goal progress is for the old targets
+	services.Earnings().Invalidate(objectID)
+
+	response, err := buildGoalsResponse(objectID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate goal progress"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Goals updated successfully",
+		"goals":   response,
+	})
+}
+
+// loadEarningsGoals returns the driver's goals, or empty targets when none are set
+func loadEarningsGoals(userID primitive.ObjectID) (models.EarningsGoals, bool, error) {
+	goals := models.EarningsGoals{UserID: userID}
+	err := config.GetDB().Collection("earnings_goals").FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&goals)
+	if err == mongo.ErrNoDocuments {
+		return goals, false, nil
+	}
+	return goals, err == nil, err
+}
+
+// buildGoalsResponse computes progress for every period from one day-by-day aggregation
+func buildGoalsResponse(userID primitive.ObjectID, now time.Time) (models.GoalsResponse, error) {
+	var response models.GoalsResponse
+
+	goals, _, err := loadEarningsGoals(userID)
+	if err != nil {
+		return response, err
+	}
+	response.Goals = goals
+
+	loc, weekStart := userCalendar(userID)
+	today := utils.StartOfDay(now, loc)
+	periods := []struct {
+		name   string
+		start  time.Time
+		end    time.Time
+		target models.GoalTarget
+	}{
+		{models.GoalDaily, today, today.AddDate(0, 0, 1), goals.Daily},
+		{models.GoalWeekly, utils.StartOfWeek(now, loc, weekStart), utils.StartOfWeek(now, loc, weekStart).AddDate(0, 0, 7), goals.Weekly},
+		{models.GoalMonthly, time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc), time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, loc), goals.Monthly},
+	}
+
+	from := today.AddDate(0, 0, -goalStreakDays)
+	for _, period := range periods {
+		if period.start.Before(from) {
+			from = period.start
+		}
+	}
+	facets, err := aggregateEarningsSummary(userID, from, today.AddDate(0, 0, 1), "day", loc, weekStart, false)
+	if err != nil {
+		return response, err
+	}
+	daily := make(map[int64]models.EarningsBucket, len(facets.Buckets))
+	for _, bucket := range facets.Buckets {
+		daily[bucket.Start.Unix()] = models.EarningsBucket{
+			NetEarnings: bucket.Revenue - bucket.Expenses,
+			Trips:       bucket.Trips,
+		}
+	}
+
+	for _, period := range periods {
+		var net float64
+		var trips int
+		for day := period.start; !day.After(today); day = day.AddDate(0, 0, 1) {
+			net += daily[day.Unix()].NetEarnings
+			trips += daily[day.Unix()].Trips
+		}
+		progress := goalProgress(period.target, net, trips, period.start, period.end, now)
+		progress.Period = period.name
+		response.Progress = append(response.Progress, progress)
+	}
+
+	response.Streak = goalStreak(daily, goals.Daily, from, today)
+	return response, nil
+}
+
+// goalProgress measures actuals against a target and projects the period end at the current pace
+func goalProgress(target models.GoalTarget, net float64, trips int, start, end, now time.Time) models.GoalProgress {
+	progress := models.GoalProgress{
+		Start:       start,
+		End:         end,
+		Target:      target,
+		NetEarnings: utils.RoundMoney(net),
+		Trips:       trips,
+	}
+
+	elapsed := float64(now.Sub(start)) / float64(end.Sub(start))
+	elapsed = math.Max(math.Min(elapsed, 1), 0.01)
+	progress.ElapsedPercentage = math.Round(elapsed*1000) / 10
+	progress.ProjectedNetEarnings = utils.RoundMoney(net / elapsed)
+	progress.ProjectedTrips = int(math.Round(float64(trips) / elapsed))
+
+	if target.NetEarnings <= 0 && target.Trips <= 0 {
+		return progress
+	}
+
+	progress.Achieved = true
+	var completion time.Time
+	canProject := true
+	check := func(actual, goal float64) *float64 {
+		if goal <= 0 {
+			return nil
+		}
+		percentage := math.Round(actual/goal*1000) / 10
+		if actual >= goal {
+			return &percentage
+		}
+		progress.Achieved = false
+		if actual <= 0 {
+			canProject = false
+			return &percentage
+		}
+		// Time to reach the goal at the pace so far
+		reached := start.Add(time.Duration(float64(now.Sub(start)) * goal / actual))
+		if reached.After(completion) {
+			completion = reached
+		}
+		return &percentage
+	}
+	progress.NetEarningsPercentage = check(net, target.NetEarnings)
+	progress.TripsPercentage = check(float64(trips), float64(target.Trips))
+
+	switch {
+	case progress.Achieved:
+		progress.OnTrack = true
+	case canProject && !completion.IsZero():
+		progress.ProjectedCompletion = &completion
+		progress.OnTrack = !completion.After(end)
+	}
+	return progress
+}
+
+// goalStreak counts days on which every daily target was met. Today only extends
+// the current streak once it is hit, so an unfinished day does not break it.
+func goalStreak(daily map[int64]models.EarningsBucket, target models.GoalTarget, from, today time.Time) models.GoalStreak {
+	var streak models.GoalStreak
+	if target.NetEarnings <= 0 && target.Trips <= 0 {
+		return streak
+	}
+
+	hit := func(day time.Time) bool {
+		totals := daily[day.Unix()]
+		return (target.NetEarnings <= 0 || totals.NetEarnings >= target.NetEarnings) &&
+			(target.Trips <= 0 || totals.Trips >= target.Trips)
+	}
+
+	run := 0
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if !hit(day) {
+			run = 0
+			continue
+		}
+		run++
+		streak.Longest = max(streak.Longest, run)
+		hitDay := day
+		streak.LastHitDate = &hitDay
+	}
+
+	day := today
+	if !hit(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	for ; !day.Before(from) && hit(day); day = day.AddDate(0, 0, -1) {
+		streak.Current++
+	}
+	return streak
+}
+
+// formatGoalContext summarises goal progress for the AI prompt
+func formatGoalContext(goals *models.GoalsResponse) string {
+	if goals == nil {
+		return "no goals set"
+	}
+
+	var parts []string
+	for _, progress := range goals.Progress {
+		if progress.Target.NetEarnings <= 0 && progress.Target.Trips <= 0 {
+			continue
+		}
+		part := fmt.Sprintf("%s: ₹%.0f of ₹%.0f net, %d of %d trips", progress.Period, progress.NetEarnings, progress.Target.NetEarnings, progress.Trips, progress.Target.Trips)
+		switch {
+		case progress.Achieved:
+			part += " (achieved)"
+		case progress.OnTrack:
+			part += " (on track)"
+		default:
+			part += fmt.Sprintf(" (behind pace, projected ₹%.0f)", progress.ProjectedNetEarnings)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "no goals set"
+	}
+	if goals.Streak.Current > 0 {
+		parts = append(parts, fmt.Sprintf("daily target streak: %d days (best %d)", goals.Streak.Current, goals.Streak.Longest))
+	}
+	return strings.Join(parts, "; ")
+}
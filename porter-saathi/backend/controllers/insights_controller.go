@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// insightsHistoryDays is how far back the forecast learns from
+	insightsHistoryDays = 84
+	// forecastAlpha is the weight of the latest day in the exponentially weighted level
+	forecastAlpha = 0.3
+	// minHourTrips is how many trips an hour needs before it can be called a best hour
+	minHourTrips = 3
+)
+
+// GetEarningsInsights forecasts this week's net earnings and lists the driver's best days and hours
+func GetEarningsInsights(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	insights, err := buildEarningsInsights(objectID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate insights"})
+		return
+	}
+
+	c.JSON(http.StatusOK, insights)
+}
+
+// buildEarningsInsights fits day-of-week seasonal models for net earnings and trips
+// over the last 12 weeks and projects the current week. Everything runs in-process
+// on one aggregation of daily totals plus the trip start times.
+func buildEarningsInsights(userID primitive.ObjectID, now time.Time) (models.EarningsInsights, error) {
+	loc, weekStart := userCalendar(userID)
+	today := utils.StartOfDay(now, loc)
+	from := today.AddDate(0, 0, -insightsHistoryDays)
+	insights := models.EarningsInsights{
+		GeneratedAt: now,
+		Confidence:  models.ConfidenceLow,
+		Weekdays:    []models.WeekdayInsight{},
+		BestDays:    []string{},
+		BestHours:   []models.HourInsight{},
+	}
+
+	facets, err := aggregateEarningsSummary(userID, from, today.AddDate(0, 0, 1), "day", loc, weekStart, false)
+	if err != nil {
+		return insights, err
+	}
+	daily := make(map[int64]models.EarningsBucket, len(facets.Buckets))
+	for _, bucket := range facets.Buckets {
+		daily[bucket.Start.Unix()] = models.EarningsBucket{
+			NetEarnings: bucket.Revenue - bucket.Expenses,
+			Trips:       bucket.Trips,
+		}
+	}
+
+	// The history runs from the first recorded day to yesterday; days in between
+	// without entries are days the driver earned nothing
+	var days []time.Time
+	var net, trips []float64
+	for day := from; day.Before(today); day = day.AddDate(0, 0, 1) {
+		totals, recorded := daily[day.Unix()]
+		if !recorded && len(days) == 0 {
+			continue
+		}
+		days = append(days, day)
+		net = append(net, totals.NetEarnings)
+		trips = append(trips, float64(totals.Trips))
+		if recorded {
+			insights.DaysWorked++
+		}
+	}
+	if len(days) > 0 {
+		insights.HistoryFrom = days[0]
+	}
+	switch {
+	case insights.DaysWorked >= 42:
+		insights.Confidence = models.ConfidenceHigh
+	case insights.DaysWorked >= 14:
+		insights.Confidence = models.ConfidenceMedium
+	}
+
+	netModel := utils.FitSeasonalModel(net, days, forecastAlpha)
+	tripsModel := utils.FitSeasonalModel(trips, days, forecastAlpha)
+	insights.DailyBaseline = utils.RoundMoney(netModel.Level)
+
+	insights.Week = forecastWeek(netModel, tripsModel, daily, utils.StartOfWeek(now, loc, weekStart), today)
+	insights.Weekdays, insights.BestDays = weekdayInsights(days, net, trips, daily, weekStart)
+
+	insights.BestHours, err = bestTripHours(userID, from, today.AddDate(0, 0, 1), loc)
+	if err != nil {
+		return insights, err
+	}
+	return insights, nil
+}
+
+// forecastWeek uses actuals for days gone by and the models for today onwards.
+// Today counts as whichever is higher, what is already recorded or the forecast.
+func forecastWeek(netModel, tripsModel utils.SeasonalModel, daily map[int64]models.EarningsBucket, start, today time.Time) models.WeekForecast {
+	week := models.WeekForecast{Start: start, End: start.AddDate(0, 0, 7)}
+	var variance, projectedTrips float64
+	for i := 0; i < 7; i++ {
+		day := start.AddDate(0, 0, i)
+		totals, recorded := daily[day.Unix()]
+		forecast := models.ForecastDay{Date: day, DayName: day.Format("Mon")}
+		if recorded {
+			actual := utils.RoundMoney(totals.NetEarnings)
+			forecast.Actual = &actual
+		}
+
+		if day.Before(today) {
+			week.ActualSoFar += totals.NetEarnings
+			week.Projected += totals.NetEarnings
+			projectedTrips += float64(totals.Trips)
+			forecast.Expected = utils.RoundMoney(totals.NetEarnings)
+			forecast.Low, forecast.High = forecast.Expected, forecast.Expected
+			forecast.ExpectedTrips = float64(totals.Trips)
+			week.Days = append(week.Days, forecast)
+			continue
+		}
+
+		forecast.IsForecast = true
+		expected := netModel.Predict(day)
+		expectedTrips := math.Max(tripsModel.Predict(day), 0)
+		if day.Equal(today) {
+			week.ActualSoFar += totals.NetEarnings
+			expected = math.Max(expected, totals.NetEarnings)
+			expectedTrips = math.Max(expectedTrips, float64(totals.Trips))
+		}
+		band := utils.ForecastZ80 * netModel.Sigma
+		forecast.Expected = utils.RoundMoney(expected)
+		forecast.Low = utils.RoundMoney(expected - band)
+		forecast.High = utils.RoundMoney(expected + band)
+		forecast.ExpectedTrips = math.Round(expectedTrips*10) / 10
+		week.Projected += expected
+		projectedTrips += expectedTrips
+		variance += netModel.Sigma * netModel.Sigma
+		week.Days = append(week.Days, forecast)
+	}
+
+	// Day errors are treated as independent, so the week's band grows with the square root of the days left
+	band := utils.ForecastZ80 * math.Sqrt(variance)
+	week.ActualSoFar = utils.RoundMoney(week.ActualSoFar)
+	week.Low = utils.RoundMoney(week.Projected - band)
+	week.High = utils.RoundMoney(week.Projected + band)
+	week.Projected = utils.RoundMoney(week.Projected)
+	week.ProjectedTrips = int(math.Round(projectedTrips))
+	return week
+}
+
+// weekdayInsights averages each day of the week over the days it was worked and
+// picks the best ones, which need at least two worked days to count
+func weekdayInsights(days []time.Time, net, trips []float64, daily map[int64]models.EarningsBucket, weekStart time.Weekday) ([]models.WeekdayInsight, []string) {
+	var occurrences, worked [7]int
+	var netSums, tripSums [7]float64
+	for i, day := range days {
+		weekday := day.Weekday()
+		occurrences[weekday]++
+		if _, recorded := daily[day.Unix()]; recorded {
+			worked[weekday]++
+			netSums[weekday] += net[i]
+			tripSums[weekday] += trips[i]
+		}
+	}
+
+	insights := []models.WeekdayInsight{}
+	for i := 0; i < 7; i++ {
+		weekday := time.Weekday((int(weekStart) + i) % 7)
+		insight := models.WeekdayInsight{Weekday: weekday.String(), DaysWorked: worked[weekday]}
+		if occurrences[weekday] > 0 {
+			insight.WorkRate = math.Round(float64(worked[weekday])/float64(occurrences[weekday])*1000) / 10
+		}
+		if worked[weekday] > 0 {
+			insight.AverageNetEarnings = utils.RoundMoney(netSums[weekday] / float64(worked[weekday]))
+			insight.AverageTrips = math.Round(tripSums[weekday]/float64(worked[weekday])*10) / 10
+		}
+		insights = append(insights, insight)
+	}
+
+	ranked := []models.WeekdayInsight{}
+	for _, insight := range insights {
+		if insight.DaysWorked >= 2 && insight.AverageNetEarnings > 0 {
+			ranked = append(ranked, insight)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].AverageNetEarnings > ranked[j].AverageNetEarnings })
+	bestDays := []string{}
+	for i := 0; i < len(ranked) && i < 3; i++ {
+		bestDays = append(bestDays, ranked[i].Weekday)
+	}
+	return insights, bestDays
+}
+
+// bestTripHours ranks hours of the day by trip income on the days the driver worked
+// them. Only trips with a start time can be placed, so this is empty without them.
+func bestTripHours(userID primitive.ObjectID, from, end time.Time, loc *time.Location) ([]models.HourInsight, error) {
+	cursor, err := config.GetDB().Collection("trips").Find(context.Background(), bson.M{
+		"user_id":    userID,
+		"date":       bson.M{"$gte": from, "$lt": end},
+		"start_time": bson.M{"$exists": true, "$ne": nil},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var trips []models.Trip
+	if err := cursor.All(context.Background(), &trips); err != nil {
+		return nil, err
+	}
+
+	type hourTotals struct {
+		trips int
+		net   float64
+		days  map[string]bool
+	}
+	var hours [24]hourTotals
+	for _, trip := range trips {
+		if trip.StartTime == nil {
+			continue
+		}
+		start := trip.StartTime.In(loc)
+		totals := &hours[start.Hour()]
+		if totals.days == nil {
+			totals.days = make(map[string]bool)
+		}
+		totals.trips++
+		totals.net += trip.NetAmount
+		totals.days[start.Format("2006-01-02")] = true
+	}
+
+	ranked := []models.HourInsight{}
+	for hour, totals := range hours {
+		if totals.trips < minHourTrips {
+			continue
+		}
+		ranked = append(ranked, models.HourInsight{
+			Hour:              hour,
+			Label:             fmt.Sprintf("%02d:00-%02d:00", hour, (hour+1)%24),
+			Trips:             totals.trips,
+			AverageNetPerTrip: utils.RoundMoney(totals.net / float64(totals.trips)),
+			NetPerDay:         utils.RoundMoney(totals.net / float64(len(totals.days))),
+		})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].NetPerDay > ranked[j].NetPerDay })
+	if len(ranked) > 3 {
+		ranked = ranked[:3]
+	}
+	return ranked, nil
+}
+
+// formatInsightsContext summarises the forecast and best days for the AI prompt
+func formatInsightsContext(insights *models.EarningsInsights) string {
+	if insights == nil || insights.DaysWorked == 0 {
+		return "not enough history to forecast"
+	}
+
+	week := insights.Week
+	parts := []string{fmt.Sprintf("this week projected ₹%.0f net (likely ₹%.0f to ₹%.0f), ₹%.0f earned so far, about %d trips; %s confidence from %d days worked",
+		week.Projected, week.Low, week.High, week.ActualSoFar, week.ProjectedTrips, insights.Confidence, insights.DaysWorked)}
+	if len(insights.BestDays) > 0 {
+		parts = append(parts, "best days: "+strings.Join(insights.BestDays, ", "))
+	}
+	if len(insights.BestHours) > 0 {
+		var hours []string
+		for _, hour := range insights.BestHours {
+			hours = append(hours, fmt.Sprintf("%s (₹%.0f per day)", hour.Label, hour.NetPerDay))
+		}
+		parts = append(parts, "best hours: "+strings.Join(hours, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,683 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultDuesWindowDays is how far ahead the dues endpoint looks for upcoming EMIs
+	defaultDuesWindowDays = 30
+	// earningsDuesWindowDays is how far ahead earnings responses show upcoming EMIs
+	earningsDuesWindowDays = 7
+	// emiTolerance absorbs rounding between the EMI and what the driver paid
+	emiTolerance = 1.0
+)
+
+// GetLoans lists the driver's loans with where each stands today
+func GetLoans(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	loans, payments, err := loadLoans(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	today := utils.StartOfDay(time.Now(), loc)
+	summaries := []models.LoanSummary{}
+	for _, loan := range loans {
+		summary, _ := summarizeLoan(loan, payments[loan.ID], today, loc)
+		summaries = append(summaries, summary)
+	}
+	c.JSON(http.StatusOK, gin.H{"loans": summaries})
+}
+
+// AddLoan records a vehicle loan
+func AddLoan(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.LoanRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	loan := models.Loan{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if errMessage := applyLoanRequest(&loan, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+
+	if _, err := config.GetDB().Collection("loans").InsertOne(context.Background(), loan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save loan"})
+		return
+	}
+
+	summary, _ := summarizeLoan(loan, nil, utils.StartOfDay(time.Now(), loc), loc)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Loan added successfully",
+		"loan":    summary,
+	})
+}
+
+// GetLoan returns a loan with its amortization schedule and payments
+func GetLoan(c *gin.Context) {
+	loan, ok := findUserLoan(c)
+	if !ok {
+		return
+	}
+
+	payments, err := loadLoanPayments(loan.UserID, []primitive.ObjectID{loan.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	loc, _ := userCalendar(loan.UserID)
+	today := utils.StartOfDay(time.Now(), loc)
+	summary, dues := summarizeLoan(loan, payments[loan.ID], today, loc)
+
+	// The schedule is the one agreed with the lender; installments already due show how they were paid
+	schedule := utils.AmortizationSchedule(loan.Principal, loan.AnnualRate, loan.EMI, 1, loanDueDate(loan))
+	for i := range schedule {
+		if i < len(dues) {
+			schedule[i].Status = dues[i].Status
+		}
+	}
+
+	loanPayments := payments[loan.ID]
+	if loanPayments == nil {
+		loanPayments = []models.LoanPayment{}
+	}
+	for i := range loanPayments {
+		loanPayments[i].Date = loanPayments[i].Date.In(loc)
+	}
+	c.JSON(http.StatusOK, models.LoanDetailResponse{
+		LoanSummary: summary,
+		Schedule:    schedule,
+		Payments:    loanPayments,
+	})
+}
+
+// UpdateLoan corrects a loan's terms; logged payments are kept
+func UpdateLoan(c *gin.Context) {
+	loan, ok := findUserLoan(c)
+	if !ok {
+		return
+	}
+
+	var request models.LoanRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(loan.UserID)
+	if errMessage := applyLoanRequest(&loan, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+	loan.UpdatedAt = time.Now()
+
+	_, err := config.GetDB().Collection("loans").ReplaceOne(context.Background(), bson.M{"_id": loan.ID, "user_id": loan.UserID}, loan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update loan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Loan updated successfully",
+		"loan":    loan,
+	})
+}
+
+// DeleteLoan removes a loan and every payment logged against it
+func DeleteLoan(c *gin.Context) {
+	loan, ok := findUserLoan(c)
+	if !ok {
+		return
+	}
+
+	if _, err := config.GetDB().Collection("loans").DeleteOne(context.Background(), bson.M{"_id": loan.ID, "user_id": loan.UserID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete loan"})
+		return
+	}
+	if _, err := config.GetDB().Collection("loan_payments").DeleteMany(context.Background(), bson.M{"loan_id": loan.ID, "user_id": loan.UserID}); err != nil {
+		log.Printf("Error deleting payments of loan %s: %v", loan.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Loan deleted successfully"})
+}
+
+// AddLoanPayment logs an EMI or a prepayment
+func AddLoanPayment(c *gin.Context) {
+	loan, ok := findUserLoan(c)
+	if !ok {
+		return
+	}
+
+	var request models.LoanPaymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(loan.UserID)
+	date, err := utils.ParseLocalDate(request.Date, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+	today := utils.StartOfDay(time.Now(), loc)
+	if date.After(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment date cannot be in the future"})
+		return
+	}
+	if date.Before(loan.DisbursedOn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment date cannot be before the loan was disbursed"})
+		return
+	}
+
+	payment := models.LoanPayment{
+		ID:        primitive.NewObjectID(),
+		LoanID:    loan.ID,
+		UserID:    loan.UserID,
+		Date:      date,
+		Amount:    utils.RoundMoney(request.Amount),
+		Type:      request.Type,
+		Note:      request.Note,
+		CreatedAt: time.Now(),
+	}
+	if payment.Type == "" {
+		payment.Type = models.LoanPaymentEMI
+	}
+	if _, err := config.GetDB().Collection("loan_payments").InsertOne(context.Background(), payment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save payment"})
+		return
+	}
+
+	payments, err := loadLoanPayments(loan.UserID, []primitive.ObjectID{loan.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	summary, _ := summarizeLoan(loan, payments[loan.ID], today, loc)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment added successfully",
+		"payment": payment,
+		"loan":    summary,
+	})
+}
+
+// DeleteLoanPayment removes a payment logged by mistake
+func DeleteLoanPayment(c *gin.Context) {
+	loan, ok := findUserLoan(c)
+	if !ok {
+		return
+	}
+	paymentID, err := primitive.ObjectIDFromHex(c.Param("paymentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	result, err := config.GetDB().Collection("loan_payments").DeleteOne(context.Background(), bson.M{"_id": paymentID, "loan_id": loan.ID, "user_id": loan.UserID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payment deleted successfully"})
+}
+
+// SimulatePrepayment shows what paying a lump sum today would save, either by keeping
+// the EMI and closing sooner or by keeping the remaining installments and lowering the EMI
+func SimulatePrepayment(c *gin.Context) {
+	loan, ok := findUserLoan(c)
+	if !ok {
+		return
+	}
+
+	var request models.PrepaymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Mode == "" {
+		request.Mode = models.PrepayReduceTenure
+	}
+
+	payments, err := loadLoanPayments(loan.UserID, []primitive.ObjectID{loan.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	loc, _ := userCalendar(loan.UserID)
+	today := utils.StartOfDay(time.Now(), loc)
+	dueDate := loanDueDate(loan)
+	state := utils.ReplayLoan(loan.Principal, loan.AnnualRate, dueDate, payments[loan.ID], today)
+	outstanding := utils.RoundMoney(state.Balance + state.UnpaidInterest)
+	if outstanding <= emiTolerance {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This loan is already repaid"})
+		return
+	}
+
+	current := utils.AmortizationSchedule(outstanding, loan.AnnualRate, loan.EMI, state.InstallmentsDue+1, dueDate)
+	simulation := models.PrepaymentSimulation{
+		Mode:                 request.Mode,
+		Amount:               math.Min(request.Amount, outstanding),
+		OutstandingPrincipal: outstanding,
+		CurrentEMI:           loan.EMI,
+		CurrentInstallments:  len(current),
+		CurrentInterest:      utils.ScheduleInterest(current),
+		NewEMI:               loan.EMI,
+		Schedule:             []models.AmortizationRow{},
+	}
+
+	remaining := utils.RoundMoney(outstanding - simulation.Amount)
+	if remaining > 0 {
+		if request.Mode == models.PrepayReduceEMI {
+			simulation.NewEMI = utils.EMIAmount(remaining, loan.AnnualRate, len(current))
+		}
+		simulation.Schedule = utils.AmortizationSchedule(remaining, loan.AnnualRate, simulation.NewEMI, state.InstallmentsDue+1, dueDate)
+	} else {
+		simulation.NewEMI = 0
+	}
+	simulation.NewInstallments = len(simulation.Schedule)
+	simulation.NewInterest = utils.ScheduleInterest(simulation.Schedule)
+	simulation.InterestSaved = utils.RoundMoney(simulation.CurrentInterest - simulation.NewInterest)
+	simulation.InstallmentsSaved = simulation.CurrentInstallments - simulation.NewInstallments
+	if n := len(simulation.Schedule); n > 0 {
+		closeDate := simulation.Schedule[n-1].DueDate
+		simulation.NewCloseDate = &closeDate
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
+
+// GetLoanDues lists missed EMIs across the driver's loans and those due in the next ?days= (default 30)
+func GetLoanDues(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	days := defaultDuesWindowDays
+	if value := c.Query("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 0 || days > 366 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and 366"})
+			return
+		}
+	}
+
+	dues, _, err := loanDues(objectID, time.Now(), days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, dues)
+}
+
+// earningsLoans takes EMIs paid in each period off its net earnings and lists dues for
+// an earnings response. It is nil for drivers without loans.
+func earningsLoans(userID primitive.ObjectID, current, previous models.EarningsSummary, start, end, previousStart, previousEnd time.Time) *models.EarningsLoans {
+	dues, hasLoans, err := loanDues(userID, time.Now(), earningsDuesWindowDays)
+	if err != nil {
+		log.Printf("Error loading loan dues: %v", err)
+		return nil
+	}
+	if !hasLoans {
+		return nil
+	}
+
+	loans := &models.EarningsLoans{
+		Upcoming:     dues.Upcoming,
+		Missed:       dues.Missed,
+		MissedAmount: dues.MissedAmount,
+	}
+	if loans.LoanPayments, err = emiPaidBetween(userID, start, end); err != nil {
+		log.Printf("Error loading loan payments: %v", err)
+		return nil
+	}
+	if loans.PreviousLoanPayments, err = emiPaidBetween(userID, previousStart, previousEnd); err != nil {
+		log.Printf("Error loading loan payments: %v", err)
+		return nil
+	}
+	loans.NetAfterLoans = netAfterLoans(current, loans.LoanPayments)
+	loans.PreviousNetAfterLoans = netAfterLoans(previous, loans.PreviousLoanPayments)
+	return loans
+}
+
+// netAfterLoans takes loan payments off net earnings, less whatever was already logged as an emi expense
+func netAfterLoans(summary models.EarningsSummary, loanPayments float64) float64 {
+	var emiExpenses float64
+	for _, category := range summary.Categories {
+		if category.Category == models.ExpenseEMI {
+			emiExpenses = category.Amount
+		}
+	}
+	return utils.RoundMoney(summary.NetEarnings - math.Max(loanPayments-emiExpenses, 0))
+}
+
+// emiPaidBetween totals EMI payments dated in [start, end). Prepayments are left out,
+// they pay down the loan rather than being a running cost.
+func emiPaidBetween(userID primitive.ObjectID, start, end time.Time) (float64, error) {
+	cursor, err := config.GetDB().Collection("loan_payments").Aggregate(context.Background(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id": userID,
+			"type":    bson.M{"$ne": models.LoanPaymentPrepayment},
+			"date":    bson.M{"$gte": start, "$lt": end},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$amount"}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var totals []struct {
+		Total float64 `bson:"total"`
+	}
+	if err := cursor.All(context.Background(), &totals); err != nil {
+		return 0, err
+	}
+	if len(totals) == 0 {
+		return 0, nil
+	}
+	return utils.RoundMoney(totals[0].Total), nil
+}
+
+// loanDues collects missed EMIs and those due within days of now across all loans.
+// The bool reports whether the driver has any loans.
+func loanDues(userID primitive.ObjectID, now time.Time, days int) (models.LoanDuesResponse, bool, error) {
+	dues := models.LoanDuesResponse{Upcoming: []models.LoanDue{}, Missed: []models.LoanDue{}}
+	loans, payments, err := loadLoans(userID)
+	if err != nil || len(loans) == 0 {
+		return dues, false, err
+	}
+
+	loc, _ := userCalendar(userID)
+	today := utils.StartOfDay(now, loc)
+	horizon := today.AddDate(0, 0, days)
+	for _, loan := range loans {
+		summary, past := summarizeLoan(loan, payments[loan.ID], today, loc)
+		for _, due := range past {
+			if due.Status == models.DueStatusDue {
+				dues.Upcoming = append(dues.Upcoming, due)
+			}
+		}
+		dues.Missed = append(dues.Missed, summary.Missed...)
+		dues.MissedAmount += summary.MissedAmount
+		if summary.Closed {
+			continue
+		}
+
+		dueDate := loanDueDate(loan)
+		remaining := utils.AmortizationSchedule(summary.OutstandingPrincipal+summary.UnpaidInterest, loan.AnnualRate, loan.EMI, summary.InstallmentsDue+1, dueDate)
+		emiPaid := paidTowardsEMIs(payments[loan.ID])
+		for _, row := range remaining {
+			if row.DueDate.After(horizon) {
+				break
+			}
+			dues.Upcoming = append(dues.Upcoming, loanDue(loan, row.Number, row.DueDate, row.EMI, emiPaid, today))
+		}
+	}
+
+	sort.SliceStable(dues.Upcoming, func(i, j int) bool { return dues.Upcoming[i].DueDate.Before(dues.Upcoming[j].DueDate) })
+	sort.SliceStable(dues.Missed, func(i, j int) bool { return dues.Missed[i].DueDate.Before(dues.Missed[j].DueDate) })
+	dues.MissedAmount = utils.RoundMoney(dues.MissedAmount)
+	return dues, true, nil
+}
+
+// summarizeLoan replays a loan's payments up to today and returns its summary and
+// every installment due so far. EMI payments are matched to installments in order,
+// so paying a missed EMI late clears the oldest one first.
+func summarizeLoan(loan models.Loan, payments []models.LoanPayment, today time.Time, loc *time.Location) (models.LoanSummary, []models.LoanDue) {
+	loan.DisbursedOn = loan.DisbursedOn.In(loc)
+	loan.FirstEMIDate = loan.FirstEMIDate.In(loc)
+	dueDate := loanDueDate(loan)
+	state := utils.ReplayLoan(loan.Principal, loan.AnnualRate, dueDate, payments, today)
+
+	summary := models.LoanSummary{
+		Loan:                 loan,
+		OutstandingPrincipal: utils.RoundMoney(state.Balance),
+		UnpaidInterest:       utils.RoundMoney(state.UnpaidInterest),
+		PrincipalPaid:        utils.RoundMoney(state.PrincipalPaid),
+		InterestPaid:         utils.RoundMoney(state.InterestPaid),
+		TotalPaid:            utils.RoundMoney(state.TotalPaid),
+		InstallmentsDue:      state.InstallmentsDue,
+		Missed:               []models.LoanDue{},
+	}
+	summary.Closed = state.Balance+state.UnpaidInterest <= emiTolerance
+
+	dues := []models.LoanDue{}
+	for number := 1; number <= state.InstallmentsDue; number++ {
+		due := loanDue(loan, number, dueDate(number), loan.EMI, state.EMIPaid, today)
+		if summary.Closed {
+			due.Status, due.Paid, due.Shortfall, due.DaysOverdue = models.DueStatusPaid, due.Amount, 0, 0
+		}
+		switch due.Status {
+		case models.DueStatusPaid:
+			summary.InstallmentsPaid++
+		case models.DueStatusMissed:
+			summary.Missed = append(summary.Missed, due)
+			summary.MissedAmount += due.Shortfall
+		case models.DueStatusDue:
+			summary.NextDue = &due
+		}
+		dues = append(dues, due)
+	}
+	summary.MissedAmount = utils.RoundMoney(summary.MissedAmount)
+	if summary.Closed {
+		return summary, dues
+	}
+
+	// What is left, assuming the EMI is paid on time from the next due date
+	remaining := utils.AmortizationSchedule(state.Balance+state.UnpaidInterest, loan.AnnualRate, loan.EMI, state.InstallmentsDue+1, dueDate)
+	summary.RemainingInstallments = len(remaining)
+	if len(remaining) > 0 {
+		closeDate := remaining[len(remaining)-1].DueDate
+		summary.ExpectedCloseDate = &closeDate
+		if summary.NextDue == nil {
+			next := loanDue(loan, remaining[0].Number, remaining[0].DueDate, remaining[0].EMI, state.EMIPaid, today)
+			summary.NextDue = &next
+		}
+	}
+	return summary, dues
+}
+
+// loanDue works out how much of installment number is covered by the EMIs paid so far
+func loanDue(loan models.Loan, number int, dueDate time.Time, amount, emiPaid float64, today time.Time) models.LoanDue {
+	due := models.LoanDue{
+		LoanID:  loan.ID,
+		Lender:  loan.Lender,
+		Number:  number,
+		DueDate: dueDate,
+		Amount:  utils.RoundMoney(amount),
+	}
+	due.Paid = utils.RoundMoney(math.Max(math.Min(emiPaid-float64(number-1)*loan.EMI, amount), 0))
+	due.Shortfall = utils.RoundMoney(amount - due.Paid)
+	switch {
+	case due.Shortfall <= emiTolerance:
+		due.Status = models.DueStatusPaid
+	case dueDate.After(today):
+		due.Status = models.DueStatusUpcoming
+	case dueDate.Equal(today):
+		due.Status = models.DueStatusDue
+	default:
+		due.Status = models.DueStatusMissed
+		due.DaysOverdue = int(math.Round(today.Sub(dueDate).Hours() / 24))
+	}
+	return due
+}
+
+func paidTowardsEMIs(payments []models.LoanPayment) float64 {
+	total := 0.0
+	for _, payment := range payments {
+		if payment.Type != models.LoanPaymentPrepayment {
+			total += payment.Amount
+		}
+	}
+	return total
+}
+
+// applyLoanRequest validates a request and copies it onto loan, calculating the EMI if it was not given
+func applyLoanRequest(loan *models.Loan, request models.LoanRequest, loc *time.Location) string {
+	disbursedOn, err := utils.ParseLocalDate(request.DisbursedOn, loc)
+	if err != nil {
+		return "Invalid disbursedOn date. Use YYYY-MM-DD"
+	}
+	firstEMIDate := utils.EMIDueDate(time.Date(disbursedOn.Year(), disbursedOn.Month()+1, 1, 0, 0, 0, 0, loc), request.EMIDay, 1)
+	if request.FirstEMIDate != "" {
+		if firstEMIDate, err = utils.ParseLocalDate(request.FirstEMIDate, loc); err != nil {
+			return "Invalid firstEmiDate. Use YYYY-MM-DD"
+		}
+		if !firstEMIDate.After(disbursedOn) {
+			return "firstEmiDate must be after disbursedOn"
+		}
+	}
+
+	emi := request.EMI
+	if emi == 0 {
+		emi = utils.EMIAmount(request.Principal, request.AnnualRate, request.TenureMonths)
+	}
+	if emi <= utils.RoundMoney(request.Principal*request.AnnualRate/1200) {
+		return "emi does not cover the monthly interest"
+	}
+
+	vehicleNumber := strings.ToUpper(strings.Join(strings.Fields(request.VehicleNumber), ""))
+	if vehicleNumber == "" {
+		vehicleNumber, _ = currentVehicle(loan.UserID)
+	}
+
+	loan.Lender = strings.TrimSpace(request.Lender)
+	loan.VehicleNumber = vehicleNumber
+	loan.Principal = utils.RoundMoney(request.Principal)
+	loan.AnnualRate = request.AnnualRate
+	loan.TenureMonths = request.TenureMonths
+	loan.EMI = utils.RoundMoney(emi)
+	loan.EMIDay = request.EMIDay
+	loan.DisbursedOn = disbursedOn
+	loan.FirstEMIDate = firstEMIDate
+	return ""
+}
+
+// loanDueDate returns the due date of each installment of loan
+func loanDueDate(loan models.Loan) func(int) time.Time {
+	return func(number int) time.Time {
+		return utils.EMIDueDate(loan.FirstEMIDate, loan.EMIDay, number)
+	}
+}
+
+// findUserLoan loads the loan in the :id path parameter, writing the error response if it can't
+func findUserLoan(c *gin.Context) (models.Loan, bool) {
+	var loan models.Loan
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return loan, false
+	}
+	loanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid loan ID"})
+		return loan, false
+	}
+
+	err = config.GetDB().Collection("loans").FindOne(context.Background(), bson.M{"_id": loanID, "user_id": objectID}).Decode(&loan)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
+		return loan, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return loan, false
+	}
+	// Due dates fall on the EMI day in the driver's timezone
+	loc, _ := userCalendar(objectID)
+	loan.DisbursedOn = loan.DisbursedOn.In(loc)
+	loan.FirstEMIDate = loan.FirstEMIDate.In(loc)
+	return loan, true
+}
+
+// loadLoans returns the driver's loans, oldest first, with their payments by loan
+func loadLoans(userID primitive.ObjectID) ([]models.Loan, map[primitive.ObjectID][]models.LoanPayment, error) {
+	cursor, err := config.GetDB().Collection("loans").Find(
+		context.Background(),
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "disbursed_on", Value: 1}}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	var loans []models.Loan
+	if err := cursor.All(context.Background(), &loans); err != nil {
+		return nil, nil, err
+	}
+	if len(loans) == 0 {
+		return loans, map[primitive.ObjectID][]models.LoanPayment{}, nil
+	}
+
+	loanIDs := make([]primitive.ObjectID, len(loans))
+	for i, loan := range loans {
+		loanIDs[i] = loan.ID
+	}
+	payments, err := loadLoanPayments(userID, loanIDs)
+	return loans, payments, err
+}
+
+func loadLoanPayments(userID primitive.ObjectID, loanIDs []primitive.ObjectID) (map[primitive.ObjectID][]models.LoanPayment, error) {
+	cursor, err := config.GetDB().Collection("loan_payments").Find(
+		context.Background(),
+		bson.M{"user_id": userID, "loan_id": bson.M{"$in": loanIDs}},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var payments []models.LoanPayment
+	if err := cursor.All(context.Background(), &payments); err != nil {
+		return nil, err
+	}
+
+	byLoan := make(map[primitive.ObjectID][]models.LoanPayment)
+	for _, payment := range payments {
+		byLoan[payment.LoanID] = append(byLoan[payment.LoanID], payment)
+	}
+	return byLoan, nil
+}
@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+)
+
+// ocrFieldWeights is how much each field counts towards the overall score
+var ocrFieldWeights = map[string]float64{
+	"aadhaarNumber":   40,
+	"aadhaarLastFour": 40,
+	"name":            20,
+	"dateOfBirth":     15,
+	"gender":          10,
+	"address":         10,
+	"mobileNumber":    5,
+}
+
+// ocrThresholds decide between auto-accept, asking the driver to confirm, and rejecting
+type ocrThresholds struct {
+	AutoAccept float64
+	Reject     float64
+}
+
+var (
+	ocrThresholdsOnce  sync.Once
+	ocrThresholdsValue ocrThresholds
+)
+
+// getOCRThresholds reads OCR_AUTO_ACCEPT_THRESHOLD and OCR_REJECT_THRESHOLD (0-1) once
+func getOCRThresholds() ocrThresholds {
+	ocrThresholdsOnce.Do(func() {
+		ocrThresholdsValue = ocrThresholds{
+			AutoAccept: envFloat("OCR_AUTO_ACCEPT_THRESHOLD", 0.85),
+			Reject:     envFloat("OCR_REJECT_THRESHOLD", 0.5),
+		}
+	})
+	return ocrThresholdsValue
+}
+
+func envFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+var (
+	pinCodePattern = regexp.MustCompile(`\b[1-9]\d{5}\b`)
+	latinNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z .']{1,59}$`)
+	mobilePattern  = regexp.MustCompile(`^[6-9]\d{9}$`)
+)
+
+// scoreAadhaarFields scores every extracted field and makes an overall decision.
+// text and words come from the OCR engine and are empty for QR results, in which
+// case engineConfidence is used for every field.
+func scoreAadhaarFields(data map[string]interface{}, text string, words []models.OCRWord, engineConfidence float64, source string) (map[string]models.FieldConfidence, int, string) {
+	scores := make(map[string]models.FieldConfidence)
+	for field := range ocrFieldWeights {
+		value, ok := data[field].(string)
+		if !ok || value == "" {
+			continue
+		}
+		scores[field] = scoreField(field, value, data, text, words, engineConfidence)
+	}
+	if pinCode, ok := data["pinCode"].(string); ok && pinCode != "" {
+		scores["pinCode"] = scoreField("pinCode", pinCode, data, text, words, engineConfidence)
+	}
+
+	// Fields we expect to find for this source; missing ones count as zero
+	expected := []string{"aadhaarNumber", "name", "dateOfBirth", "gender", "address", "mobileNumber"}
+	critical := []string{"aadhaarNumber", "name"}
+	if source == "qr" {
+		expected = []string{"aadhaarLastFour", "name", "dateOfBirth", "gender", "address"}
+		critical = []string{"aadhaarLastFour", "name"}
+	}
+
+	var weighted, totalWeight float64
+	for _, field := range expected {
+		totalWeight += ocrFieldWeights[field]
+		weighted += ocrFieldWeights[field] * scores[field].Score
+	}
+	overall := 0.0
+	if totalWeight > 0 {
+		overall = weighted / totalWeight
+	}
+
+	thresholds := getOCRThresholds()
+	decision := models.OCRDecisionAutoAccept
+	if overall < thresholds.AutoAccept {
+		decision = models.OCRDecisionConfirm
+	}
+	if overall < thresholds.Reject {
+		decision = models.OCRDecisionReject
+	}
+	for _, field := range critical {
+		score, ok := scores[field]
+		if !ok || score.Score < thresholds.Reject {
+			decision = models.OCRDecisionReject
+			break
+		}
+		if score.Score < thresholds.AutoAccept && decision == models.OCRDecisionAutoAccept {
+			decision = models.OCRDecisionConfirm
+		}
+	}
+
+	return scores, int(math.Round(overall * 100)), decision
+}
+
+// scoreField combines engine confidence, validity, script agreement and plausibility
+func scoreField(field, value string, data map[string]interface{}, text string, words []models.OCRWord, engineConfidence float64) models.FieldConfidence {
+	result := models.FieldConfidence{Value: value, Engine: engineConfidence, Valid: true, Plausible: true}
+
+	if len(words) > 0 {
+		if confidence, box, found := locateValue(value, words); found {
+			result.Engine = confidence
+			result.BoundingBox = &box
+		} else {
+			result.Engine = engineConfidence * 0.8
+			result.Issues = append(result.Issues, "Could not locate the field on the image")
+		}
+	}
+
+	switch field {
+	case "aadhaarNumber":
+		if !utils.ValidAadhaarNumber(value) {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Aadhaar number fails the checksum")
+		}
+		if strings.Count(value, value[:1]) == len(value) {
+			result.Plausible = false
+			result.Issues = append(result.Issues, "Aadhaar number is a repeated digit")
+		}
+	case "aadhaarLastFour":
+		if len(value) != 4 || strings.Trim(value, "0123456789") != "" {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Reference ID does not start with four digits")
+		}
+	case "name":
+		if !latinNameRegex.MatchString(value) {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Name contains unexpected characters")
+		}
+		if len(strings.Fields(value)) > 6 {
+			result.Plausible = false
+			result.Issues = append(result.Issues, "Name has too many words")
+		}
+		if local, ok := data["nameLocal"].(string); ok && local != "" {
+			agreement := utils.NameSimilarity(value, local)
+			result.Agreement = &agreement
+		}
+	case "dateOfBirth":
+		date, yearOnly, err := parseDateOfBirth(value)
+		if err != nil {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Date of birth is not a valid date")
+			break
+		}
+		age := ageOn(date, yearOnly, time.Now())
+		if age < 18 || age > 100 {
+			result.Plausible = false
+			result.Issues = append(result.Issues, fmt.Sprintf("Age %d is outside the expected 18-100 range", age))
+		}
+		result.Agreement = agreementAcrossScripts(AadhaarPatterns["dateOfBirth"], text, value, nil)
+	case "gender":
+		if value != "Male" && value != "Female" && value != "Transgender" {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Unknown gender value")
+		}
+		result.Agreement = agreementAcrossScripts(AadhaarPatterns["gender"], text, value, normalizeGender)
+	case "address":
+		pin := pinCodePattern.FindString(value)
+		if pin == "" {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Address has no PIN code")
+			break
+		}
+		if _, known := utils.LookupPinCode(pin); !known {
+			result.Plausible = false
+			result.Issues = append(result.Issues, fmt.Sprintf("PIN code %s is not in a known postal region", pin))
+		}
+		if pins := pinCodePattern.FindAllString(text, -1); len(pins) > 1 {
+			result.Agreement = fractionEqual(pins, pin)
+		}
+	case "pinCode":
+		if !pinCodePattern.MatchString(value) || len(value) != 6 {
+			result.Valid = false
+			result.Issues = append(result.Issues, "PIN code must be six digits")
+		}
+	case "mobileNumber":
+		if !mobilePattern.MatchString(value) {
+			result.Valid = false
+			result.Issues = append(result.Issues, "Mobile number must be 10 digits starting with 6-9")
+		} else if strings.Count(value, value[:1]) == len(value) {
+			result.Plausible = false
+			result.Issues = append(result.Issues, "Mobile number is a repeated digit")
+		}
+	}
+
+	result.Score = combineFieldScore(result)
+	return result
+}
+
+// combineFieldScore weights the components; an invalid or implausible field is capped
+// so that a well-read but wrong value can never be auto-accepted.
+func combineFieldScore(field models.FieldConfidence) float64 {
+	validity, plausibility := 0.0, 0.0
+	if field.Valid {
+		validity = 1
+	}
+	if field.Plausible {
+		plausibility = 1
+	}
+
+	score := 0.4*field.Engine + 0.25*validity + 0.15*plausibility
+	weights := 0.8
+	if field.Agreement != nil {
+		score += 0.2 * *field.Agreement
+		weights += 0.2
+	}
+	score /= weights
+
+	if !field.Valid {
+		score = math.Min(score, 0.4)
+	}
+	if !field.Plausible {
+		score = math.Min(score, 0.6)
+	}
+
+	return math.Round(score*100) / 100
+}
+
+// agreementAcrossScripts finds every labelled occurrence of a field (English and
+// regional labels) and returns the share that agrees with value
+func agreementAcrossScripts(pattern *regexp.Regexp, text, value string, normalize func(string) string) *float64 {
+	if text == "" {
+		return nil
+	}
+
+	var values []string
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		if len(match) < 2 {
+			continue
+		}
+		found := match[1]
+		if normalize != nil {
+			found = normalize(found)
+		}
+		values = append(values, found)
+	}
+	if len(values) < 2 {
+		return nil
+	}
+	return fractionEqual(values, value)
+}
+
+func fractionEqual(values []string, target string) *float64 {
+	equal := 0
+	for _, value := range values {
+		if value == target {
+			equal++
+		}
+	}
+	fraction := float64(equal) / float64(len(values))
+	return &fraction
+}
+
+// normalizeGender maps regional gender words to the English value used in extractedData
+func normalizeGender(value string) string {
+	switch strings.ToLower(value) {
+	case "male", "पुरुष", "పురుషుడు", "ஆண்":
+		return "Male"
+	case "female", "महिला", "స్త్రీ", "பெண்":
+		return "Female"
+	}
+	return value
+}
+
+func ageOn(birth time.Time, yearOnly bool, now time.Time) int {
+	age := now.Year() - birth.Year()
+	if !yearOnly && (now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day())) {
+		age--
+	}
+	return age
+}
+
+// locateValue finds the run of OCR words that spell value and returns their
+// mean confidence and combined bounding box
+func locateValue(value string, words []models.OCRWord) (float64, models.BoundingBox, bool) {
+	target := alphanumericKey(value)
+	if target == "" {
+		return 0, models.BoundingBox{}, false
+	}
+
+	for start := range words {
+		var joined strings.Builder
+		for end := start; end < len(words); end++ {
+			joined.WriteString(alphanumericKey(words[end].Text))
+			current := joined.String()
+			if !strings.HasPrefix(target, current) {
+				break
+			}
+			if current == target {
+				return summariseWords(words[start : end+1])
+			}
+		}
+	}
+	return 0, models.BoundingBox{}, false
+}
+
+func summariseWords(words []models.OCRWord) (float64, models.BoundingBox, bool) {
+	confidence := 0.0
+	minX, minY := math.MaxInt, math.MaxInt
+	maxX, maxY := 0, 0
+	for _, word := range words {
+		confidence += word.Confidence
+		minX = min(minX, word.Box.X)
+		minY = min(minY, word.Box.Y)
+		maxX = max(maxX, word.Box.X+word.Box.Width)
+		maxY = max(maxY, word.Box.Y+word.Box.Height)
+	}
+
+	box := models.BoundingBox{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+	return confidence / float64(len(words)), box, true
+}
+
+func alphanumericKey(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, text)
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRunAadhaarOCRSimulated(t *testing.T) {
+	var blank bytes.Buffer
+	if err := png.Encode(&blank, image.NewGray(image.Rect(0, 0, 40, 40))); err != nil {
+		t.Fatal(err)
+	}
+	// Without a database, any attempt to load or save the profile would fail the call
+	defer func(db *mongo.Database) { config.DB = db }(config.DB)
+	config.DB = nil
+
+	expected := &models.OCRExpectedFields{Name: "Rajesh Kumar Sharma", AadharNumber: "234567890124", DateOfBirth: "15/08/1985"}
+	for _, userID := range []string{"", primitive.NewObjectID().Hex()} {
+		response, err := runAadhaarOCR(blank.Bytes(), userID, expected)
+		if err != nil {
+			t.Fatalf("runAadhaarOCR() error = %v", err)
+		}
+		if response.Source != "simulated" {
+			t.Errorf("Source = %q, want simulated", response.Source)
+		}
+		if report := response.MatchReport; report == nil || report.Status != models.VerificationNeedsReview || len(report.Fields) != 0 {
+			t.Errorf("MatchReport = %+v, want an unchecked needs_review report", report)
+		}
+		if response.Decision == models.OCRDecisionAutoAccept || response.AddressStatus != "" {
+			t.Errorf("decision %q and address status %q, want nothing accepted or saved", response.Decision, response.AddressStatus)
+		}
+	}
+}
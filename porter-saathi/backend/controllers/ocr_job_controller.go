@@ -0,0 +1,474 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ocrJobLease is how long a worker owns a job before another worker may reclaim it
+const ocrJobLease = 2 * time.Minute
+
+// ocrJobWakeup lets a new submission start processing without waiting for the next poll
+var ocrJobWakeup = make(chan struct{}, 1)
+
+// SubmitOCRJob queues an Aadhaar image for background processing and returns a job ID
+func SubmitOCRJob(c *gin.Context) {
+	input, status, errMessage := readOCRInput(c)
+	if errMessage != "" {
+		c.JSON(status, gin.H{"error": errMessage})
+		return
+	}
+
+	// Reject undecodable images now rather than burning retries on them later
+	if _, _, err := image.DecodeConfig(bytes.NewReader(input.ImageBytes)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported image format. Use JPEG or PNG."})
+		return
+	}
+
+	// Callbacks go to hosts the operator has approved, and only for signed-in drivers
+	if input.CallbackURL != "" {
+		if c.GetString("userID") == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to use callbackUrl"})
+			return
+		}
+		if err := validateOCRCallbackURL(input.CallbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	job := models.OCRJob{
+		ID:          primitive.NewObjectID(),
+		Status:      models.OCRJobQueued,
+		Expected:    input.Expected,
+		CallbackURL: input.CallbackURL,
+		MaxAttempts: envInt("OCR_JOB_MAX_ATTEMPTS", 3),
+		AvailableAt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		ExpiresAt:   now.Add(time.Duration(envInt("OCR_JOB_TTL_HOURS", 24)) * time.Hour),
+	}
+	// Anonymous jobs can only be read back with a random token handed out here
+	jobToken := ""
+	if userID, err := primitive.ObjectIDFromHex(c.GetString("userID")); err == nil {
+		job.UserID = &userID
+	} else {
+		jobToken, err = newAccessToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue OCR job"})
+			return
+		}
+		job.TokenHash = hashAccessToken(jobToken)
+	}
+
+	// Stored documents are read again by the worker; only ad-hoc images go into the queue
+	if input.DocumentType != "" {
+		job.DocumentType = input.DocumentType
+		job.DocumentFile = input.DocumentFile
+	} else {
+		job.Image = input.ImageBytes
+	}
+
+	collection := config.GetDB().Collection("ocr_jobs")
+	if _, err := collection.InsertOne(context.Background(), job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue OCR job"})
+		return
+	}
+
+	select {
+	case ocrJobWakeup <- struct{}{}:
+	default:
+	}
+
+	response := gin.H{
+		"message":   "OCR job queued",
+		"jobId":     job.ID.Hex(),
+		"status":    job.Status,
+		"pollUrl":   "/api/v1/ocr/jobs/" + job.ID.Hex(),
+		"expiresAt": job.ExpiresAt,
+	}
+	if jobToken != "" {
+		response["jobToken"] = jobToken
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// GetOCRJob returns the status of an OCR job, with the result once it has completed
+func GetOCRJob(c *gin.Context) {
+	jobID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	collection := config.GetDB().Collection("ocr_jobs")
+	var job models.OCRJob
+	err = collection.FindOne(
+		context.Background(),
+		bson.M{"_id": jobID},
+		options.FindOne().SetProjection(bson.M{"image": 0}),
+	).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Jobs submitted by a signed-in driver are only visible to that driver; anonymous
+	// jobs need the token returned at submission, sent as X-Job-Token or ?token=
+	if job.UserID != nil {
+		if job.UserID.Hex() != c.GetString("userID") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+	} else {
+		token := c.GetHeader("X-Job-Token")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if !accessTokenMatches(token, job.TokenHash) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// StartOCRWorkers launches the background pool that processes queued OCR jobs.
+// Concurrency is set with OCR_WORKER_CONCURRENCY.
+func StartOCRWorkers(ctx context.Context) {
+	ensureOCRJobIndexes()
+
+	concurrency := envInt("OCR_WORKER_CONCURRENCY", 4)
+	for i := 0; i < concurrency; i++ {
+		go runOCRWorker(ctx)
+	}
+	log.Printf("Started %d OCR workers", concurrency)
+}
+
+func ensureOCRJobIndexes() {
+	collection := config.GetDB().Collection("ocr_jobs")
+	_, err := collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "available_at", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Error creating OCR job indexes: %v", err)
+	}
+}
+
+func runOCRWorker(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		sweepExpiredOCRJobs(ctx)
+		job, err := claimOCRJob(ctx)
+		if err == nil {
+			processOCRJob(job)
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Error claiming OCR job: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ocrJobWakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimOCRJob atomically takes the oldest runnable job. Jobs whose worker died
+// (for example during a restart) become runnable again once their lease expires,
+// unless they have used up their attempts. Each claim gets its own lease owner so a
+// worker can tell if its lease was taken over.
+func claimOCRJob(ctx context.Context) (models.OCRJob, error) {
+	now := time.Now()
+	var job models.OCRJob
+	err := config.GetDB().Collection("ocr_jobs").FindOneAndUpdate(
+		ctx,
+		bson.M{"$or": []bson.M{
+			{"status": models.OCRJobQueued, "available_at": bson.M{"$lte": now}},
+			{
+				"status":      models.OCRJobProcessing,
+				"lease_until": bson.M{"$lt": now},
+				"$expr":       bson.M{"$lt": bson.A{"$attempts", "$max_attempts"}},
+			},
+		}},
+		bson.M{
+			"$set": bson.M{
+				"status":      models.OCRJobProcessing,
+				"lease_owner": primitive.NewObjectID().Hex(),
+				"lease_until": now.Add(ocrJobLease),
+				"updated_at":  now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "available_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	return job, err
+}
+
+// sweepExpiredOCRJobs marks jobs dead whose lease expired on their last attempt. Such
+// a job took its worker down with it every time, so it is never claimed again.
+func sweepExpiredOCRJobs(ctx context.Context) {
+	now := time.Now()
+	expired := bson.M{
+		"status":      models.OCRJobProcessing,
+		"lease_until": bson.M{"$lt": now},
+		"$expr":       bson.M{"$gte": bson.A{"$attempts", "$max_attempts"}},
+	}
+	collection := config.GetDB().Collection("ocr_jobs")
+	cursor, err := collection.Find(ctx, expired, options.Find().SetProjection(bson.M{"image": 0}))
+	if err != nil {
+		log.Printf("Error finding expired OCR jobs: %v", err)
+		return
+	}
+	var jobs []models.OCRJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		log.Printf("Error finding expired OCR jobs: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		job.Status = models.OCRJobDead
+		job.LastError = "OCR worker stopped while processing the job"
+		// Another worker may sweep the same job; only the one that marks it dead notifies
+		filter := bson.M{"_id": job.ID}
+		for key, value := range expired {
+			filter[key] = value
+		}
+		result, err := collection.UpdateOne(ctx, filter, bson.M{
+			"$set":   bson.M{"status": job.Status, "last_error": job.LastError, "updated_at": now},
+			"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+		})
+		if err != nil {
+			log.Printf("Error marking OCR job %s dead: %v", job.ID.Hex(), err)
+			continue
+		}
+		if result.MatchedCount == 1 {
+			log.Printf("OCR job %s is dead after %d attempts that did not finish", job.ID.Hex(), job.Attempts)
+			notifyOCRJobCallback(job)
+		}
+	}
+}
+
+func processOCRJob(job models.OCRJob) {
+	result, err := runOCRJobSafely(job)
+	now := time.Now()
+
+	var update bson.M
+	switch {
+	case err == nil:
+		job.Status = models.OCRJobCompleted
+		job.Result = &result
+		job.CompletedAt = &now
+		update = bson.M{
+			"$set": bson.M{
+				"status":       job.Status,
+				"result":       job.Result,
+				"completed_at": now,
+				"updated_at":   now,
+			},
+			// The image is no longer needed once we have a result
+			"$unset": bson.M{"image": "", "lease_owner": "", "lease_until": "", "last_error": ""},
+		}
+	case errors.Is(err, errUnreadableImage) || job.Attempts >= job.MaxAttempts:
+		// Keep the image on dead jobs so they can be inspected or replayed until they expire
+		job.Status = models.OCRJobDead
+		job.LastError = err.Error()
+		update = bson.M{
+			"$set":   bson.M{"status": job.Status, "last_error": job.LastError, "updated_at": now},
+			"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+		}
+	default:
+		backoff := time.Duration(1<<job.Attempts) * 5 * time.Second
+		update = bson.M{
+			"$set": bson.M{
+				"status":       models.OCRJobQueued,
+				"available_at": now.Add(backoff),
+				"last_error":   err.Error(),
+				"updated_at":   now,
+			},
+			"$unset": bson.M{"lease_owner": "", "lease_until": ""},
+		}
+	}
+
+	// Only write if this worker still holds the lease; otherwise another worker has reclaimed the job
+	updateResult, updateErr := config.GetDB().Collection("ocr_jobs").UpdateOne(
+		context.Background(),
+		bson.M{
+			"_id":         job.ID,
+			"status":      models.OCRJobProcessing,
+			"lease_owner": job.LeaseOwner,
+			"lease_until": bson.M{"$gte": now},
+		},
+		update,
+	)
+	if updateErr != nil {
+		log.Printf("Error updating OCR job %s: %v", job.ID.Hex(), updateErr)
+		return
+	}
+	if updateResult.MatchedCount == 0 {
+		log.Printf("OCR job %s lease expired before processing finished; result discarded", job.ID.Hex())
+		return
+	}
+
+	if job.Status == models.OCRJobCompleted || job.Status == models.OCRJobDead {
+		notifyOCRJobCallback(job)
+	}
+}
+
+// runOCRJobSafely turns a panic in the OCR pipeline into a retryable error
+func runOCRJobSafely(job models.OCRJob) (result models.OCRResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("OCR processing panicked: %v", r)
+		}
+	}()
+
+	userID := ""
+	if job.UserID != nil {
+		userID = job.UserID.Hex()
+	}
+
+	imageBytes := job.Image
+	if job.DocumentType != "" {
+		imageBytes, err = os.ReadFile(filepath.Join(uploadDirectory(), userID, filepath.Base(job.DocumentFile)))
+		if err != nil {
+			return result, fmt.Errorf("failed to read stored document: %w", err)
+		}
+	}
+
+	result, err = runAadhaarOCR(imageBytes, userID, job.Expected)
+	if err != nil {
+		return result, err
+	}
+	if job.DocumentType != "" {
+		attachExtractionToDocument(userID, job.DocumentType, job.DocumentFile, result)
+	}
+	return result, nil
+}
+
+// notifyOCRJobCallback tells the client's callback URL that the job has finished, best effort.
+// Only the job ID and status are sent; the extracted data is fetched through GetOCRJob.
+func notifyOCRJobCallback(job models.OCRJob) {
+	if job.CallbackURL == "" {
+		return
+	}
+	if err := validateOCRCallbackURL(job.CallbackURL); err != nil {
+		log.Printf("OCR job %s callback skipped: %v", job.ID.Hex(), err)
+		return
+	}
+
+	payload, err := json.Marshal(gin.H{
+		"jobId":   job.ID.Hex(),
+		"status":  job.Status,
+		"pollUrl": "/api/v1/ocr/jobs/" + job.ID.Hex(),
+	})
+	if err != nil {
+		return
+	}
+
+	resp, err := ocrCallbackClient.Post(job.CallbackURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Printf("OCR job %s callback failed: %v", job.ID.Hex(), err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// ocrCallbackClient refuses to connect to internal addresses, checking the IP actually
+// dialled so a public hostname cannot resolve to one, and does not follow redirects
+var ocrCallbackClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip, err := netip.ParseAddr(host)
+				if err != nil || !publicAddress(ip) {
+					return fmt.Errorf("callback address %s is not public", host)
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// validateOCRCallbackURL accepts https URLs on a host listed in OCR_CALLBACK_ALLOWED_HOSTS
+func validateOCRCallbackURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" || parsed.User != nil {
+		return fmt.Errorf("callbackUrl must be an https URL")
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddress(ip) {
+		return fmt.Errorf("callbackUrl must not point to an internal address")
+	}
+	for _, allowed := range strings.Split(os.Getenv("OCR_CALLBACK_ALLOWED_HOSTS"), ",") {
+		if allowed = strings.ToLower(strings.TrimSpace(allowed)); allowed != "" && allowed == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("callbackUrl host is not allowed")
+}
+
+// carrierGradeNAT is the shared address space (RFC 6598) used inside ISP and cloud networks
+var carrierGradeNAT = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress reports whether ip is routable on the internet
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !carrierGradeNAT.Contains(ip)
+}
+
+func envInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
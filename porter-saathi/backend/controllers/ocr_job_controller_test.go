@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestOCRJobAttemptLimit(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	defer func(db *mongo.Database) { config.DB = db }(config.DB)
+
+	mt.Run("claim skips expired jobs out of attempts", func(mt *mtest.T) {
+		config.DB = mt.DB
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		if _, err := claimOCRJob(context.Background()); err != mongo.ErrNoDocuments {
+			mt.Fatalf("claimOCRJob() error = %v, want no documents", err)
+		}
+		started := mt.GetStartedEvent()
+		reclaim := started.Command.Lookup("query", "$or").Array().Index(1).Value().Document()
+		if _, err := reclaim.LookupErr("$expr"); err != nil {
+			mt.Errorf("expired leases are reclaimed without checking attempts: %s", reclaim)
+		}
+	})
+
+	mt.Run("sweep marks expired last attempts dead", func(mt *mtest.T) {
+		config.DB = mt.DB
+		jobID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "porter_saathi.ocr_jobs", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: jobID},
+				{Key: "status", Value: models.OCRJobProcessing},
+				{Key: "attempts", Value: 3},
+				{Key: "max_attempts", Value: 3},
+				{Key: "lease_until", Value: time.Now().Add(-time.Minute)},
+			}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		sweepExpiredOCRJobs(context.Background())
+
+		var update bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				update = started.Command.Lookup("updates").Array().Index(0).Value().Document()
+			}
+		}
+		if update == nil {
+			mt.Fatal("sweep did not update the job")
+		}
+		if id := update.Lookup("q", "_id").ObjectID(); id != jobID {
+			mt.Errorf("updated job %s, want %s", id.Hex(), jobID.Hex())
+		}
+		if _, err := update.LookupErr("q", "$expr"); err != nil {
+			mt.Errorf("update is not guarded on the attempt count: %s", update)
+		}
+		if status := update.Lookup("u", "$set", "status").StringValue(); status != models.OCRJobDead {
+			mt.Errorf("status = %q, want %q", status, models.OCRJobDead)
+		}
+	})
+}
@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetEarningsStatement exports earnings between ?from= and ?to= (inclusive, YYYY-MM-DD).
+// format=csv gives one row per entry for the driver's own records; format=pdf gives a
+// signed income statement with a QR code that banks can use to verify it.
+func GetEarningsStatement(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or csv"})
+		return
+	}
+
+	loc, weekStart := userCalendar(objectID)
+	to := utils.StartOfDay(time.Now(), loc)
+	if value := c.Query("to"); value != "" {
+		if to, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := c.Query("from"); value != "" {
+		if from, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+	filename := fmt.Sprintf("earnings-%s-to-%s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	if format == "csv" {
+		content, err := earningsStatementCSV(objectID, from, end, loc)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export earnings"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", content)
+		return
+	}
+
+	if !utils.StatementSigningConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Signed PDF statements are not available. Use format=csv."})
+		return
+	}
+
+	var user models.User
+	err = config.GetDB().Collection("users").FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	facets, err := aggregateEarningsSummary(objectID, from, end, "month", loc, weekStart, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarise earnings"})
+		return
+	}
+
+	language := c.Query("language")
+	if language == "" {
+		language = user.PreferredLanguage
+	}
+	statement := models.Statement{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		From:      from.Format("2006-01-02"),
+		To:        to.Format("2006-01-02"),
+		Language:  utils.StatementLanguage(language),
+		CreatedAt: time.Now().In(loc).Truncate(time.Second),
+	}
+
+	monthly := make(map[int64]models.StatementMonth, len(facets.Buckets))
+	for _, bucket := range facets.Buckets {
+		monthly[bucket.Start.Unix()] = models.StatementMonth{
+			Revenue:  bucket.Revenue,
+			Expenses: bucket.Expenses,
+			Trips:    bucket.Trips,
+		}
+	}
+	for _, start := range summaryBucketStarts(from, end, "month", weekStart) {
+		month := monthly[start.Unix()]
+		month.Month = start.Format("2006-01")
+		month.Revenue = utils.RoundMoney(month.Revenue)
+		month.Expenses = utils.RoundMoney(month.Expenses)
+		month.NetEarnings = utils.RoundMoney(month.Revenue - month.Expenses)
+		statement.Months = append(statement.Months, month)
+
+		statement.Revenue += month.Revenue
+		statement.Expenses += month.Expenses
+		statement.Trips += month.Trips
+	}
+	statement.Revenue = utils.RoundMoney(statement.Revenue)
+	statement.Expenses = utils.RoundMoney(statement.Expenses)
+	statement.NetEarnings = utils.RoundMoney(statement.Revenue - statement.Expenses)
+	// The statement ID alone is guessable, so the QR code also carries a random token
+	verifyToken, err := newAccessToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate statement"})
+		return
+	}
+	statement.TokenHash = hashAccessToken(verifyToken)
+	if err := utils.SignStatement(&statement); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Signed PDF statements are not available. Use format=csv."})
+		return
+	}
+
+	categoryAmounts := make(map[string]float64)
+	for _, category := range facets.Categories {
+		categoryAmounts[category.Category] += category.Amount
+	}
+	for _, unitemized := range facets.Unitemized {
+		categoryAmounts[models.ExpenseOther] += unitemized.Amount
+	}
+
+	var pdf bytes.Buffer
+	err = utils.RenderStatementPDF(utils.StatementDocument{
+		Statement:     &statement,
+		DriverName:    user.Name,
+		Mobile:        user.Mobile,
+		VehicleNumber: user.VehicleNumber,
+		Categories:    services.CategoryTotals(categoryAmounts, statement.Expenses),
+		VerifyURL:     statementVerifyURL(statement.ID, verifyToken),
+	}, &pdf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate statement"})
+		return
+	}
+
+	// Only statements that were actually issued are kept for verification
+	if _, err := config.GetDB().Collection("statements").InsertOne(context.Background(), statement); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save statement"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.pdf"`)
+	c.Header("X-Statement-ID", statement.ID.Hex())
+	c.Data(http.StatusOK, "application/pdf", pdf.Bytes())
+}
+
+// VerifyStatement lets anyone holding a statement check it was issued by the server
+// and has not been altered. The ?token= from the QR code is required, so statement IDs
+// cannot be guessed; only the masked driver name is disclosed.
+func VerifyStatement(c *gin.Context) {
+	statementID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid statement ID"})
+		return
+	}
+
+	var statement models.Statement
+	err = config.GetDB().Collection("statements").FindOne(context.Background(), bson.M{"_id": statementID}).Decode(&statement)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	// A wrong token looks the same as a missing statement
+	if err == mongo.ErrNoDocuments || !accessTokenMatches(c.Query("token"), statement.TokenHash) {
+		c.JSON(http.StatusNotFound, gin.H{"valid": false, "error": "Statement not found"})
+		return
+	}
+
+	var user models.User
+	config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": statement.UserID},
+		options.FindOne().SetProjection(bson.M{"name": 1}),
+	).Decode(&user)
+
+	c.JSON(http.StatusOK, models.StatementVerification{
+		Valid:       utils.VerifyStatement(&statement),
+		StatementID: statement.ID.Hex(),
+		DriverName:  maskName(user.Name),
+		From:        statement.From,
+		To:          statement.To,
+		Revenue:     statement.Revenue,
+		Expenses:    statement.Expenses,
+		NetEarnings: statement.NetEarnings,
+		Trips:       statement.Trips,
+		Months:      statement.Months,
+		KeyID:       statement.KeyID,
+		IssuedAt:    statement.CreatedAt,
+	})
+}
+
+// GetStatementPublicKey publishes the Ed25519 key statements are signed with
+func GetStatementPublicKey(c *gin.Context) {
+	publicKey, err := utils.StatementPublicKey()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Statement signing is not configured"})
+		return
+	}
+	keyID, _ := utils.StatementKeyID()
+	c.JSON(http.StatusOK, gin.H{
+		"algorithm": "Ed25519",
+		"keyId":     keyID,
+		"publicKey": publicKey,
+	})
+}
+
+// earningsStatementCSV writes one row per entry with a column for every expense category
+func earningsStatementCSV(userID primitive.ObjectID, from, end time.Time, loc *time.Location) ([]byte, error) {
+	cursor, err := config.GetDB().Collection("earnings").Find(
+		context.Background(),
+		excludeFlagged(bson.M{
+			"user_id":    userID,
+			"deleted_at": bson.M{"$exists": false},
+			"date":       bson.M{"$gte": from, "$lt": end},
+		}),
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var entries []models.Earnings
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	header := []string{"date", "source", "revenue", "expenses", "net_earnings", "trips"}
+	header = append(header, models.ExpenseCategories...)
+	writer.Write(header)
+
+	money := func(amount float64) string {
+		return strconv.FormatFloat(utils.RoundMoney(amount), 'f', 2, 64)
+	}
+	for _, entry := range entries {
+		source := entry.Source
+		if source == "" {
+			source = "manual"
+		}
+		row := []string{
+			entry.Date.In(loc).Format("2006-01-02"),
+			source,
+			money(entry.Revenue),
+			money(entry.Expenses),
+			money(entry.Revenue - entry.Expenses),
+			strconv.Itoa(entry.Trips),
+		}
+		amounts := expensesByCategory([]models.Earnings{entry})
+		for _, category := range models.ExpenseCategories {
+			row = append(row, money(amounts[category]))
+		}
+		writer.Write(row)
+	}
+
+	summary := calculateSummary(entries)
+	totals := []string{"total", "", money(summary.Revenue), money(summary.Expenses), money(summary.NetEarnings), strconv.Itoa(summary.Trips)}
+	amounts := expensesByCategory(entries)
+	for _, category := range models.ExpenseCategories {
+		totals = append(totals, money(amounts[category]))
+	}
+	writer.Write(totals)
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+// statementVerifyURL is the public link encoded in a statement's QR code
+func statementVerifyURL(statementID primitive.ObjectID, token string) string {
+	base := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return base + "/api/v1/statements/" + statementID.Hex() + "/verify?token=" + token
+}
+
+// maskName keeps the first letter of each word, e.g. "Ramesh Kumar" becomes "R***** K****"
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		runes := []rune(word)
+		words[i] = string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	}
+	return strings.Join(words, " ")
+}
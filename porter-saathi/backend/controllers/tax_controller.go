@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTaxProfile returns the goods carriages used for Section 44AE
+func GetTaxProfile(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	profile, _, err := loadTaxProfile(objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateTaxProfile replaces the list of goods carriages with their weight and ownership dates
+func UpdateTaxProfile(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.TaxProfileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	profile := models.TaxProfile{UserID: objectID, Vehicles: []models.TaxVehicle{}, UpdatedAt: time.Now()}
+	for _, vehicle := range request.Vehicles {
+		ownedFrom, err := utils.ParseLocalDate(vehicle.OwnedFrom, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ownedFrom date. Use YYYY-MM-DD"})
+			return
+		}
+		taxVehicle := models.TaxVehicle{
+			VehicleNumber:        strings.ToUpper(strings.Join(strings.Fields(vehicle.VehicleNumber), "")),
+			GrossVehicleWeightKg: vehicle.GrossVehicleWeightKg,
+			OwnedFrom:            ownedFrom,
+		}
+		if vehicle.OwnedTo != "" {
+			ownedTo, err := utils.ParseLocalDate(vehicle.OwnedTo, loc)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ownedTo date. Use YYYY-MM-DD"})
+				return
+			}
+			if ownedTo.Before(ownedFrom) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ownedTo cannot be before ownedFrom"})
+				return
+			}
+			taxVehicle.OwnedTo = &ownedTo
+		}
+		profile.Vehicles = append(profile.Vehicles, taxVehicle)
+	}
+
+	collection := config.GetDB().Collection("tax_profiles")
+	_, err = collection.UpdateOne(
+		context.Background(),
+		bson.M{"user_id": objectID},
+		bson.M{"$set": bson.M{"vehicles": profile.Vehicles, "updated_at": profile.UpdatedAt}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tax profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax profile updated successfully",
+		"profile": profile,
+	})
+}
+
+// GetTaxEstimate works out Section 44AE presumptive income for ?fy=2024-25 (default the
+// current financial year), compares it with actual net earnings and estimates the tax
+// with its advance tax schedule and a month-by-month summary of the year
+func GetTaxEstimate(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	financialYear := c.DefaultQuery("fy", utils.FinancialYearOf(time.Now().In(loc)))
+	if _, _, err := utils.ParseFinancialYear(financialYear, loc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	estimate, err := buildTaxEstimate(objectID, financialYear, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate tax"})
+		return
+	}
+	c.JSON(http.StatusOK, estimate)
+}
+
+// GetTaxYears summarises every financial year since the driver's first earnings entry
+func GetTaxYears(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	now := time.Now().In(loc)
+	first := now
+	var earliest models.Earnings
+	err = config.GetDB().Collection("earnings").FindOne(
+		context.Background(),
+		bson.M{"user_id": objectID, "deleted_at": bson.M{"$exists": false}},
+		options.FindOne().SetSort(bson.D{{Key: "date", Value: 1}}).SetProjection(bson.M{"date": 1}),
+	).Decode(&earliest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if err == nil {
+		first = earliest.Date.In(loc)
+	}
+
+	years := []models.TaxYearSummary{}
+	for year := utils.FinancialYearOf(now); ; {
+		estimate, err := buildTaxEstimate(objectID, year, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate tax"})
+			return
+		}
+		years = append(years, models.TaxYearSummary{
+			FinancialYear:     year,
+			ActualNetEarnings: estimate.ActualNetEarnings,
+			PresumptiveIncome: estimate.PresumptiveIncome,
+			DeclaredIncome:    estimate.DeclaredIncome,
+			Basis:             estimate.Basis,
+			EstimatedTax:      estimate.Tax.TotalTax,
+		})
+		if !estimate.Start.After(first) {
+			break
+		}
+		year = utils.FinancialYearOf(estimate.Start.AddDate(0, 0, -1))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"years": years})
+}
+
+// buildTaxEstimate works out the tax for one financial year. An eligible owner may
+// declare the presumptive income even when they earned more, so that is what the
+// estimate uses; actual earnings are only shown for comparison.
+func buildTaxEstimate(userID primitive.ObjectID, financialYear string, now time.Time) (models.TaxEstimate, error) {
+	loc, weekStart := userCalendar(userID)
+	start, end, err := utils.ParseFinancialYear(financialYear, loc)
+	if err != nil {
+		return models.TaxEstimate{}, err
+	}
+	rules, rulesYear := utils.TaxRulesFor(financialYear)
+	estimate := models.TaxEstimate{
+		FinancialYear: financialYear,
+		Start:         start,
+		End:           end,
+		InProgress:    now.After(start) && now.Before(end),
+		RulesYear:     rulesYear,
+		Eligible:      true,
+		Notes:         []string{},
+		Vehicles:      []models.VehiclePresumptiveIncome{},
+		Months:        []models.EarningsBucket{},
+	}
+
+	profile, found, err := loadTaxProfile(userID)
+	if err != nil {
+		return estimate, err
+	}
+	if !found {
+		estimate.Notes = append(estimate.Notes, "Add your vehicles' gross vehicle weight and ownership dates for an accurate estimate; your registered vehicle is assumed to be a light goods vehicle owned all year.")
+	}
+
+	for _, vehicle := range profile.Vehicles {
+		income := utils.PresumptiveIncome(vehicle, rules, start, end)
+		if income.MonthsOwned == 0 {
+			continue
+		}
+		estimate.Vehicles = append(estimate.Vehicles, income)
+		estimate.PresumptiveIncome += income.Income
+	}
+	// The limit is on vehicles owned at the same time, not on every vehicle owned during the year
+	estimate.MaxVehiclesOwned = utils.MaxVehiclesOwnedAtOnce(profile.Vehicles, start, end)
+	if estimate.MaxVehiclesOwned > rules.Presumptive.MaxVehicles {
+		estimate.Eligible = false
+		estimate.Notes = append(estimate.Notes, fmt.Sprintf("Section 44AE only applies to owners of up to %d goods carriages at any time, so income is taken from actual earnings.", rules.Presumptive.MaxVehicles))
+	}
+
+	// Month by month totals for the year, up to today for a year in progress
+	until := end
+	if estimate.InProgress {
+		until = utils.StartOfDay(now, loc).AddDate(0, 0, 1)
+	}
+	if until.After(start) {
+		facets, err := aggregateEarningsSummary(userID, start, until, "month", loc, weekStart, false)
+		if err != nil {
+			return estimate, err
+		}
+		totals := make(map[int64]models.EarningsBucket, len(facets.Buckets))
+		for _, bucket := range facets.Buckets {
+			totals[bucket.Start.Unix()] = models.EarningsBucket{
+				Revenue:     bucket.Revenue,
+				Expenses:    bucket.Expenses,
+				Trips:       bucket.Trips,
+				NetEarnings: bucket.Revenue - bucket.Expenses,
+			}
+		}
+		for month := start; month.Before(until); month = month.AddDate(0, 1, 0) {
+			bucket := totals[month.Unix()]
+			bucket.Start = month
+			bucket.End = month.AddDate(0, 1, 0)
+			bucket.Label = summaryBucketLabel(month, "month")
+			estimate.Months = append(estimate.Months, bucket)
+			estimate.ActualNetEarnings += bucket.NetEarnings
+		}
+	}
+	estimate.ActualNetEarnings = utils.RoundMoney(estimate.ActualNetEarnings)
+
+	estimate.ProjectedNetEarnings = estimate.ActualNetEarnings
+	if estimate.InProgress {
+		elapsed := until.Sub(start).Hours() / 24
+		yearDays := end.Sub(start).Hours() / 24
+		estimate.ProjectedNetEarnings = utils.RoundMoney(estimate.ActualNetEarnings * yearDays / elapsed)
+		estimate.Notes = append(estimate.Notes, "The year is not over, so actual earnings are projected to the full year at the current pace.")
+	}
+
+	estimate.Basis = models.TaxBasisPresumptive
+	estimate.DeclaredIncome = estimate.PresumptiveIncome
+	switch {
+	case !estimate.Eligible:
+		estimate.Basis = models.TaxBasisActual
+		estimate.DeclaredIncome = estimate.ProjectedNetEarnings
+	case estimate.ProjectedNetEarnings > estimate.PresumptiveIncome:
+		estimate.Notes = append(estimate.Notes, "Your actual earnings are above the presumptive income. Section 44AE lets you declare the presumptive income; declaring more is optional.")
+	case estimate.ProjectedNetEarnings < estimate.PresumptiveIncome:
+		estimate.Notes = append(estimate.Notes, "Your actual earnings are below the presumptive income. Declaring the lower figure needs books of account and a tax audit.")
+	}
+
+	estimate.Tax = utils.ComputeIncomeTax(estimate.DeclaredIncome, rules)
+	estimate.AdvanceTaxApplies = estimate.Tax.TotalTax >= rules.AdvanceTax.Threshold
+	estimate.AdvanceTax = []models.AdvanceTaxInstallment{}
+	if estimate.AdvanceTaxApplies {
+		estimate.AdvanceTax = utils.AdvanceTaxSchedule(estimate.Tax.TotalTax, rules, start, now)
+	}
+	estimate.Notes = append(estimate.Notes, "Tax uses the new regime slab rates for business income only; other income, deductions and TDS are not included.")
+	return estimate, nil
+}
+
+// loadTaxProfile returns the driver's goods carriages. Without a saved profile their
+// registered vehicle is assumed to be a light goods vehicle owned all year.
+func loadTaxProfile(userID primitive.ObjectID) (models.TaxProfile, bool, error) {
+	profile := models.TaxProfile{UserID: userID, Vehicles: []models.TaxVehicle{}}
+	err := config.GetDB().Collection("tax_profiles").FindOne(context.Background(), bson.M{"user_id": userID}).Decode(&profile)
+	if err == nil {
+		return profile, true, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return profile, false, err
+	}
+
+	vehicle, err := currentVehicle(userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return profile, false, err
+	}
+	if vehicle != "" {
+		profile.Vehicles = append(profile.Vehicles, models.TaxVehicle{VehicleNumber: vehicle})
+	}
+	return profile, false, nil
+}
@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetTrips lists the driver's trips, optionally limited with ?from=YYYY-MM-DD&to=YYYY-MM-DD (inclusive)
+func GetTrips(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	filter := bson.M{"user_id": objectID}
+	dateFilter := bson.M{}
+	if from := c.Query("from"); from != "" {
+		date, err := utils.ParseLocalDate(from, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+			return
+		}
+		dateFilter["$gte"] = date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := utils.ParseLocalDate(to, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+			return
+		}
+		dateFilter["$lt"] = date.AddDate(0, 0, 1)
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	collection := config.GetDB().Collection("trips")
+	cursor, err := collection.Find(
+		context.Background(),
+		filter,
+		options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "start_time", Value: -1}}),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	trips := []models.Trip{}
+	if err := cursor.All(context.Background(), &trips); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	for i := range trips {
+		trips[i].Date = trips[i].Date.In(loc)
+	}
+
+	rollup := summariseTrips(trips)
+	c.JSON(http.StatusOK, models.TripListResponse{
+		Trips:   trips,
+		Summary: calculateSummary([]models.Earnings{rollup}),
+	})
+}
+
+// GetTripByID returns a single trip owned by the driver
+func GetTripByID(c *gin.Context) {
+	trip, ok := findUserTrip(c)
+	if !ok {
+		return
+	}
+
+	loc, _ := userCalendar(trip.UserID)
+	trip.Date = trip.Date.In(loc)
+	c.JSON(http.StatusOK, trip)
+}
+
+// AddTrip records a trip and refreshes that day's earnings rollup
+func AddTrip(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request models.TripRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trip := models.Trip{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		CreatedAt: time.Now(),
+	}
+	loc, _ := userCalendar(objectID)
+	if errMessage := applyTripRequest(&trip, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+
+	collection := config.GetDB().Collection("trips")
+	if _, err := collection.InsertOne(context.Background(), trip); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save trip"})
+		return
+	}
+	rollup := rollupTripDay(objectID, trip.Date)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "Trip added successfully",
+		"trip":              trip,
+		"needsConfirmation": rollup.ReviewStatus == models.ReviewFlagged,
+	})
+}
+
+// UpdateTrip replaces a trip's details and refreshes the affected days' rollups
+func UpdateTrip(c *gin.Context) {
+	before, ok := findUserTrip(c)
+	if !ok {
+		return
+	}
+
+	var request models.TripRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trip := before
+	loc, _ := userCalendar(before.UserID)
+	if errMessage := applyTripRequest(&trip, request, loc); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+
+	collection := config.GetDB().Collection("trips")
+	_, err := collection.ReplaceOne(context.Background(), bson.M{"_id": trip.ID, "user_id": trip.UserID}, trip)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update trip"})
+		return
+	}
+
+	rollup := rollupTripDay(trip.UserID, trip.Date)
+	if !utils.StartOfDay(before.Date, loc).Equal(trip.Date) {
+		rollupTripDay(trip.UserID, before.Date)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Trip updated successfully",
+		"trip":              trip,
+		"needsConfirmation": rollup.ReviewStatus == models.ReviewFlagged,
+	})
+}
+
+// DeleteTrip removes a trip and refreshes that day's rollup
+func DeleteTrip(c *gin.Context) {
+	trip, ok := findUserTrip(c)
+	if !ok {
+		return
+	}
+
+	collection := config.GetDB().Collection("trips")
+	if _, err := collection.DeleteOne(context.Background(), bson.M{"_id": trip.ID, "user_id": trip.UserID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete trip"})
+		return
+	}
+	rollupTripDay(trip.UserID, trip.Date)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trip deleted successfully"})
+}
+
+// applyTripRequest copies request fields onto the trip and derives its totals.
+// The date is a day in the driver's timezone.
+func applyTripRequest(trip *models.Trip, request models.TripRequest, loc *time.Location) string {
+	date, err := utils.ParseLocalDate(request.Date, loc)
+	if err != nil {
+		return "Invalid date format. Use YYYY-MM-DD"
+	}
+
+	trip.StartTime = nil
+	if request.StartTime != "" {
+		startTime, err := time.Parse(time.RFC3339, request.StartTime)
+		if err != nil {
+			return "Invalid startTime. Use RFC 3339, e.g. 2024-01-15T09:30:00+05:30"
+		}
+		trip.StartTime = &startTime
+	}
+
+	if errMessage := checkEarningsDate(date, loc); errMessage != "" {
+		return errMessage
+	}
+
+	if request.PlatformCommission > request.Fare {
+		return "Platform commission cannot be more than the fare"
+	}
+
+	trip.Date = date
+	trip.PickupArea = request.PickupArea
+	trip.DropArea = request.DropArea
+	trip.DistanceKm = request.DistanceKm
+	trip.DurationMinutes = request.DurationMinutes
+	trip.Fare = request.Fare
+	trip.PlatformCommission = request.PlatformCommission
+	trip.Tips = request.Tips
+	trip.WaitingCharges = request.WaitingCharges
+	trip.PaymentMode = request.PaymentMode
+
+	trip.GrossAmount = trip.Fare + trip.Tips + trip.WaitingCharges
+	trip.NetAmount = trip.GrossAmount - trip.PlatformCommission
+	trip.EarningPerKm = 0
+	if trip.DistanceKm > 0 {
+		trip.EarningPerKm = math.Round(trip.NetAmount/trip.DistanceKm*100) / 100
+	}
+	trip.UpdatedAt = time.Now()
+	return ""
+}
+
+// summariseTrips folds trips into the daily Earnings shape: everything the customer
+// paid is revenue and the platform's commission is an expense
+func summariseTrips(trips []models.Trip) models.Earnings {
+	var rollup models.Earnings
+	for _, trip := range trips {
+		rollup.Revenue += trip.GrossAmount
+		rollup.Expenses += trip.PlatformCommission
+		rollup.Trips++
+	}
+	if rollup.Expenses > 0 {
+		rollup.ExpenseItems = []models.ExpenseItem{{Category: models.ExpenseCommission, Amount: rollup.Expenses}}
+	}
+	rollup.NetEarnings = rollup.Revenue - rollup.Expenses
+	return rollup
+}
+
+// rollupRetries bounds how often a day's rollup is recomputed when another write to
+// it lands first
+const rollupRetries = 5
+
+// rollupTripDay recomputes the derived daily Earnings document for one day of trips
+// and returns it. There is one rollup document per driver and day, kept unique by an
+// index; a day whose trips are all removed has its rollup soft-deleted and brought
+// back by the next trip. The rollup is only written over the version it was computed
+// against, and recomputed from the trips when a concurrent trip write got there first,
+// so the last writer never saves a stale total. Rollups are reviewed like any other
+// entry, and changes are written to the earnings history like any other edit.
+func rollupTripDay(userID primitive.ObjectID, day time.Time) models.Earnings {
+	loc, _ := userCalendar(userID)
+	startOfDay := utils.StartOfDay(day, loc)
+	for attempt := 0; attempt < rollupRetries; attempt++ {
+		if rollup, done := rollupTripDayOnce(userID, startOfDay); done {
+			return rollup
+		}
+	}
+	log.Printf("Error saving earnings rollup for %s on %s: changed by other writes %d times", userID.Hex(), startOfDay.Format("2006-01-02"), rollupRetries)
+	return models.Earnings{}
+}
+
+// rollupTripDayOnce makes one attempt at a day's rollup. It reports false when the
+// rollup changed after it was read, so the attempt has to start over.
+func rollupTripDayOnce(userID primitive.ObjectID, startOfDay time.Time) (models.Earnings, bool) {
+	ctx := context.Background()
+	db := config.GetDB()
+
+	var trips []models.Trip
+	cursor, err := db.Collection("trips").Find(ctx, bson.M{
+		"user_id": userID,
+		"date":    bson.M{"$gte": startOfDay, "$lt": startOfDay.AddDate(0, 0, 1)},
+	})
+	if err != nil {
+		log.Printf("Error loading trips for rollup: %v", err)
+		return models.Earnings{}, true
+	}
+	if err := cursor.All(ctx, &trips); err != nil {
+		log.Printf("Error loading trips for rollup: %v", err)
+		return models.Earnings{}, true
+	}
+
+	earningsCollection := db.Collection("earnings")
+	var existing models.Earnings
+	err = earningsCollection.FindOne(ctx, bson.M{
+		"user_id": userID,
+		"date":    startOfDay,
+		"source":  models.EarningsSourceTrips,
+	}).Decode(&existing)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf("Error loading earnings rollup: %v", err)
+		return models.Earnings{}, true
+	}
+	found := err == nil
+	active := found && existing.DeletedAt == nil
+	// Only write over the version the trips were summed against
+	versionFilter := bson.M{"_id": existing.ID, "sync_seq": existing.SyncSeq}
+	now := time.Now()
+
+	if len(trips) == 0 {
+		if !active {
+			return models.Earnings{}, true
+		}
+		after := existing
+		after.DeletedAt = &now
+		if err := stampEarningsWrite(&after, now); err != nil {
+			return models.Earnings{}, true
+		}
+		defer releaseEarningsWrite(&after)
+		update, err := earningsCollection.UpdateOne(ctx, versionFilter, bson.M{"$set": bson.M{
+			"deleted_at":  now,
+			"updated_at":  now,
+			"modified_at": now,
+			"sync_seq":    after.SyncSeq,
+		}})
+		if err != nil {
+			log.Printf("Error removing earnings rollup: %v", err)
+			return models.Earnings{}, true
+		}
+		if update.MatchedCount == 0 {
+			return models.Earnings{}, false
+		}
+		recordEarningsChange(models.EarningsChangeDelete, userID, existing.ID, &existing, &after, "All trips for the day were removed")
+		return models.Earnings{}, true
+	}
+
+	rollup := summariseTrips(trips)
+	rollup.ID = primitive.NewObjectID()
+	rollup.UserID = userID
+	rollup.Date = startOfDay
+	rollup.Source = models.EarningsSourceTrips
+	rollup.CreatedAt = now
+	rollup.UpdatedAt = now
+	if found {
+		rollup.ID = existing.ID
+		rollup.CreatedAt = existing.CreatedAt
+		rollup.ReviewStatus = existing.ReviewStatus
+		rollup.AnomalyReasons = existing.AnomalyReasons
+		rollup.ConfirmedAt = existing.ConfirmedAt
+	}
+	// A confirmed day stays confirmed until its figures change
+	if !active || !sameEarningsFigures(existing, rollup) {
+		reviewEarnings(&rollup)
+		flagOverDailyLimits(&rollup)
+	}
+	if err := stampEarningsWrite(&rollup, now); err != nil {
+		return models.Earnings{}, true
+	}
+	defer releaseEarningsWrite(&rollup)
+
+	if !found {
+		_, err := earningsCollection.InsertOne(ctx, rollup)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another rollup for the same day was inserted first
+			return models.Earnings{}, false
+		}
+		if err != nil {
+			log.Printf("Error saving earnings rollup: %v", err)
+			return models.Earnings{}, true
+		}
+		recordEarningsChange(models.EarningsChangeCreate, userID, rollup.ID, nil, &rollup, "Rolled up from trips")
+		return rollup, true
+	}
+
+	update := withReviewFields(bson.M{
+		"revenue":       rollup.Revenue,
+		"expenses":      rollup.Expenses,
+		"expense_items": rollup.ExpenseItems,
+		"trips":         rollup.Trips,
+		"net_earnings":  rollup.NetEarnings,
+		"updated_at":    now,
+		"modified_at":   now,
+		"sync_seq":      rollup.SyncSeq,
+	}, rollup)
+	unset, _ := update["$unset"].(bson.M)
+	if unset == nil {
+		unset = bson.M{}
+		update["$unset"] = unset
+	}
+	unset["deleted_at"] = ""
+
+	var after models.Earnings
+	err = earningsCollection.FindOneAndUpdate(ctx, versionFilter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&after)
+	if err == mongo.ErrNoDocuments {
+		return models.Earnings{}, false
+	}
+	if err != nil {
+		log.Printf("Error saving earnings rollup: %v", err)
+		return models.Earnings{}, true
+	}
+
+	if !active {
+		recordEarningsChange(models.EarningsChangeCreate, userID, after.ID, nil, &after, "Rolled up from trips")
+		return after, true
+	}
+	recordEarningsChange(models.EarningsChangeUpdate, userID, after.ID, &existing, &after, "Rolled up from trips")
+	return after, true
+}
+
+// flagOverDailyLimits flags a rollup whose day adds up to more than a manual entry
+// may hold, as that is almost always a trip typed with an extra zero
+func flagOverDailyLimits(rollup *models.Earnings) {
+	var reasons []string
+	if rollup.Revenue > maxDailyAmount {
+		reasons = append(reasons, fmt.Sprintf("revenue of ₹%.0f is more than ₹1,00,000 for a day", rollup.Revenue))
+	}
+	if rollup.Trips > maxDailyTrips {
+		reasons = append(reasons, fmt.Sprintf("%d trips is more than %d for a day", rollup.Trips, maxDailyTrips))
+	}
+	if len(reasons) > 0 {
+		rollup.ReviewStatus = models.ReviewFlagged
+		rollup.AnomalyReasons = append(rollup.AnomalyReasons, reasons...)
+	}
+}
+
+// findUserTrip loads the trip named in the URL, scoped to the signed-in driver
+func findUserTrip(c *gin.Context) (models.Trip, bool) {
+	var trip models.Trip
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return trip, false
+	}
+	tripID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return trip, false
+	}
+
+	collection := config.GetDB().Collection("trips")
+	err = collection.FindOne(context.Background(), bson.M{"_id": tripID, "user_id": userID}).Decode(&trip)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
+			return trip, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return trip, false
+	}
+
+	return trip, true
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"porter-saathi-backend/models"
+)
+
+func TestApplyTripRequest(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Kolkata")
+	today := time.Now().In(loc).Format("2006-01-02")
+	tomorrow := time.Now().In(loc).AddDate(0, 0, 1).Format("2006-01-02")
+	trip := func(date string, fare, commission float64) models.TripRequest {
+		return models.TripRequest{
+			Date: date, PickupArea: "Karol Bagh", DropArea: "Okhla", DistanceKm: 20,
+			Fare: fare, PlatformCommission: commission, Tips: 20, WaitingCharges: 30, PaymentMode: "upi",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		request models.TripRequest
+		wantErr string
+		wantNet float64
+		wantKm  float64
+	}{
+		{"today", trip(today, 450, 90), "", 410, 20.5},
+		{"future date", trip(tomorrow, 450, 90), "date cannot be in the future", 0, 0},
+		{"commission above fare", trip(today, 100, 150), "Platform commission cannot be more than the fare", 0, 0},
+		{"bad date", trip("15-01-2024", 450, 90), "Invalid date format. Use YYYY-MM-DD", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.Trip
+			if errMessage := applyTripRequest(&got, tt.request, loc); errMessage != tt.wantErr {
+				t.Fatalf("applyTripRequest() = %q, want %q", errMessage, tt.wantErr)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+			if got.NetAmount != tt.wantNet || got.EarningPerKm != tt.wantKm {
+				t.Errorf("net %v and per km %v, want %v and %v", got.NetAmount, got.EarningPerKm, tt.wantNet, tt.wantKm)
+			}
+		})
+	}
+}
+
+func TestFlagOverDailyLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		rollup      models.Earnings
+		wantReasons int
+	}{
+		{"ordinary day", models.Earnings{Revenue: 3500, Trips: 12}, 0},
+		{"at the limits", models.Earnings{Revenue: maxDailyAmount, Trips: maxDailyTrips}, 0},
+		{"fare with an extra zero", models.Earnings{Revenue: 135000, Trips: 12}, 1},
+		{"too many trips", models.Earnings{Revenue: 3500, Trips: 150}, 1},
+		{"both", models.Earnings{Revenue: 135000, Trips: 150}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rollup := tt.rollup
+			flagOverDailyLimits(&rollup)
+			if len(rollup.AnomalyReasons) != tt.wantReasons {
+				t.Errorf("reasons = %q, want %d", rollup.AnomalyReasons, tt.wantReasons)
+			}
+			if flagged := rollup.ReviewStatus == models.ReviewFlagged; flagged != (tt.wantReasons > 0) {
+				t.Errorf("ReviewStatus = %q", rollup.ReviewStatus)
+			}
+		})
+	}
+}
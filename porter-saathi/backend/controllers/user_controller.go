@@ -4,13 +4,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"porter-saathi-backend/config"
 	"porter-saathi-backend/models"
+	"porter-saathi-backend/services"
+	"porter-saathi-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -36,6 +41,47 @@ func mapDocumentType(frontendType string) string {
 	return frontendType
 }
 
+// uploadDirectory returns the root directory for uploaded documents
+func uploadDirectory() string {
+	uploadDir := os.Getenv("UPLOAD_PATH")
+	if uploadDir == "" {
+		uploadDir = "./uploads"
+	}
+	return uploadDir
+}
+
+// loadUserDocument returns a user's uploaded document record and its file contents
+func loadUserDocument(userID primitive.ObjectID, frontendDocType string) (*models.DocumentFile, []byte, error) {
+	var user models.User
+	err := config.GetDB().Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var document *models.DocumentFile
+	switch mapDocumentType(frontendDocType) {
+	case "aadhar_card":
+		document = user.Documents.AadharCard
+	case "driving_license":
+		document = user.Documents.DrivingLicense
+	case "vehicle_rc":
+		document = user.Documents.VehicleRC
+	case "profile_photo":
+		document = user.Documents.ProfilePhoto
+	}
+	if document == nil {
+		return nil, nil, fmt.Errorf("no %s document uploaded", frontendDocType)
+	}
+
+	// filepath.Base keeps a tampered file name from escaping the user's directory
+	data, err := os.ReadFile(filepath.Join(uploadDirectory(), userID.Hex(), filepath.Base(document.FileName)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read stored %s: %w", frontendDocType, err)
+	}
+
+	return document, data, nil
+}
+
 // GetUserProfile returns user profile information
 func GetUserProfile(c *gin.Context) {
 	userID := c.GetString("userID")
@@ -61,6 +107,30 @@ func GetUserProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// editableProfileFields maps the profile fields a driver may edit, by JSON or
+// database name, to the field they are stored in
+var editableProfileFields = map[string]string{
+	"name":               "name",
+	"aadharNumber":       "aadhar_number",
+	"aadhar_number":      "aadhar_number",
+	"dateOfBirth":        "date_of_birth",
+	"date_of_birth":      "date_of_birth",
+	"licenseNumber":      "license_number",
+	"license_number":     "license_number",
+	"vehicleNumber":      "vehicle_number",
+	"vehicle_number":     "vehicle_number",
+	"emergencyContact":   "emergency_contact",
+	"emergency_contact":  "emergency_contact",
+	"preferredLanguage":  "preferred_language",
+	"preferred_language": "preferred_language",
+	"city":               "city",
+	"vehicleClass":       "vehicle_class",
+	"vehicle_class":      "vehicle_class",
+	"timezone":           "timezone",
+	"weekStart":          "week_start",
+	"week_start":         "week_start",
+}
+
 // UpdateUserProfile updates user profile information
 func UpdateUserProfile(c *gin.Context) {
 	userID := c.GetString("userID")
@@ -76,14 +146,105 @@ func UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
+	// Only the driver's own details can be edited here; verification results, addresses
+	// and documents are written by the OCR and upload flows
+	fields := bson.M{}
+	for key, value := range updateData {
+		field, editable := editableProfileFields[key]
+		if !editable {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field " + key + " cannot be updated"})
+			return
+		}
+		text, ok := value.(string)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Field " + key + " must be a string"})
+			return
+		}
+		fields[field] = text
+	}
+	updateData = fields
+
+	// Calendar preferences drive earnings bucketing, so only accept values we can use
+	for _, key := range []string{"timezone", "week_start"} {
+		value, present := updateData[key]
+		if !present {
+			continue
+		}
+		name, _ := value.(string)
+		delete(updateData, key)
+		if key == "timezone" {
+			if !utils.ValidTimezone(name) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone. Use an IANA name such as Asia/Kolkata"})
+				return
+			}
+			updateData["timezone"] = name
+			continue
+		}
+		weekStart, ok := utils.ParseWeekday(name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weekStart. Use a day name such as monday"})
+			return
+		}
+		updateData["week_start"] = strings.ToLower(weekStart.String())
+	}
+
+	// City and vehicle class pick the driver's peers for benchmarks
+	if value, present := updateData["city"]; present {
+		city, _ := value.(string)
+		city = strings.Join(strings.Fields(city), " ")
+		if len(city) > 60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "city is too long"})
+			return
+		}
+		updateData["city"] = city
+	}
+	if value, present := updateData["vehicle_class"]; present {
+		if class, _ := value.(string); !slices.Contains(models.VehicleClasses, class) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicleClass. Use one of " + strings.Join(models.VehicleClasses, ", ")})
+			return
+		}
+	}
+
+	// Days are stored as local midnight, so a new timezone moves existing records onto
+	// the same calendar days in it before the profile switches over
+	if name, changed := updateData["timezone"].(string); changed {
+		var current models.User
+		err := config.GetDB().Collection("users").FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&current)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		from, to := utils.LoadTimezone(current.Timezone), utils.LoadTimezone(name)
+		if from.String() != to.String() {
+			if err := utils.MoveRecordDays(context.Background(), objectID, from, to); err != nil {
+				log.Printf("Error moving records of %s to %s: %v", userID, name, err)
+				// Put back whatever was already moved so nothing is left between the two zones
+				if undoErr := utils.MoveRecordDays(context.Background(), objectID, to, from); undoErr != nil {
+					log.Printf("Error undoing timezone move for %s: %v", userID, undoErr)
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change timezone"})
+				return
+			}
+		}
+	}
+
 	// Add updated timestamp
 	updateData["updated_at"] = time.Now()
 
+	update := bson.M{"$set": updateData}
+	// A match report checked the old details, so the card has to be checked again
+	for _, field := range []string{"name", "aadhar_number", "date_of_birth"} {
+		if _, changed := updateData[field]; changed {
+			update["$unset"] = bson.M{"aadhaar_match": ""}
+			break
+		}
+	}
+
 	collection := config.GetDB().Collection("users")
 	result, err := collection.UpdateOne(
 		context.Background(),
 		bson.M{"_id": objectID},
-		bson.M{"$set": updateData},
+		update,
 	)
 
 	if err != nil {
@@ -95,6 +256,12 @@ func UpdateUserProfile(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	// Cached earnings are bucketed on the old calendar
+	_, timezoneChanged := updateData["timezone"]
+	_, weekStartChanged := updateData["week_start"]
+	if timezoneChanged || weekStartChanged {
+		services.Earnings().Invalidate(objectID)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
@@ -147,12 +314,7 @@ func UploadDocument(c *gin.Context) {
 	}
 
 	// Create upload directory if it doesn't exist
-	uploadDir := os.Getenv("UPLOAD_PATH")
-	if uploadDir == "" {
-		uploadDir = "./uploads"
-	}
-
-	userUploadDir := filepath.Join(uploadDir, userID)
+	userUploadDir := filepath.Join(uploadDirectory(), userID)
 	if err := os.MkdirAll(userUploadDir, 0755); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
 		return
@@ -0,0 +1,573 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// efficiencyDropPercentage is how far below its baseline an interval's km/l must fall to be flagged
+	efficiencyDropPercentage = 20.0
+	// efficiencyBaselineIntervals is how many preceding intervals the baseline is the median of
+	efficiencyBaselineIntervals = 5
+	// efficiencyMinBaseline is how many preceding intervals are needed before flagging anything
+	efficiencyMinBaseline = 2
+)
+
+// odometerPoint is a known odometer value from either a reading or a fill-up
+type odometerPoint struct {
+	Date time.Time
+	Km   float64
+}
+
+// GetOdometerReadings lists the readings for the driver's current vehicle
+func GetOdometerReadings(c *gin.Context) {
+	objectID, vehicle, ok := vehicleOwner(c)
+	if !ok {
+		return
+	}
+
+	readings, _, err := loadVehicleLog(objectID, vehicle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	loc, _ := userCalendar(objectID)
+	for i := range readings {
+		readings[i].Date = readings[i].Date.In(loc)
+	}
+	c.JSON(http.StatusOK, gin.H{"vehicleNumber": vehicle, "readings": readings})
+}
+
+// AddOdometerReading records an odometer reading for a day
+func AddOdometerReading(c *gin.Context) {
+	objectID, vehicle, ok := vehicleOwner(c)
+	if !ok {
+		return
+	}
+
+	var request models.OdometerRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	date, err := utils.ParseLocalDate(request.Date, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+	if errMessage := checkOdometerOrder(objectID, vehicle, date, request.ReadingKm); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+
+	reading := models.OdometerReading{
+		ID:            primitive.NewObjectID(),
+		UserID:        objectID,
+		VehicleNumber: vehicle,
+		Date:          date,
+		ReadingKm:     request.ReadingKm,
+		Note:          request.Note,
+		CreatedAt:     time.Now(),
+	}
+	if _, err := config.GetDB().Collection("odometer_readings").InsertOne(context.Background(), reading); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save odometer reading"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Odometer reading added successfully",
+		"reading": reading,
+	})
+}
+
+// DeleteOdometerReading removes one of the driver's readings
+func DeleteOdometerReading(c *gin.Context) {
+	deleteVehicleLogEntry(c, "odometer_readings", "Odometer reading")
+}
+
+// GetFuelFillUps lists the fill-ups for the driver's current vehicle
+func GetFuelFillUps(c *gin.Context) {
+	objectID, vehicle, ok := vehicleOwner(c)
+	if !ok {
+		return
+	}
+
+	_, fillUps, err := loadVehicleLog(objectID, vehicle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	loc, _ := userCalendar(objectID)
+	for i := range fillUps {
+		fillUps[i].Date = fillUps[i].Date.In(loc)
+	}
+	c.JSON(http.StatusOK, gin.H{"vehicleNumber": vehicle, "fillUps": fillUps})
+}
+
+// AddFuelFillUp records litres bought at a price and the odometer at the pump
+func AddFuelFillUp(c *gin.Context) {
+	objectID, vehicle, ok := vehicleOwner(c)
+	if !ok {
+		return
+	}
+
+	var request models.FuelFillUpRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc, _ := userCalendar(objectID)
+	date, err := utils.ParseLocalDate(request.Date, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+	if errMessage := checkOdometerOrder(objectID, vehicle, date, request.OdometerKm); errMessage != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+		return
+	}
+
+	fillUp := models.FuelFillUp{
+		ID:            primitive.NewObjectID(),
+		UserID:        objectID,
+		VehicleNumber: vehicle,
+		Date:          date,
+		OdometerKm:    request.OdometerKm,
+		Litres:        request.Litres,
+		PricePerLitre: request.PricePerLitre,
+		Amount:        utils.RoundMoney(request.Litres * request.PricePerLitre),
+		FullTank:      request.FullTank == nil || *request.FullTank,
+		Note:          request.Note,
+		CreatedAt:     time.Now(),
+	}
+	if _, err := config.GetDB().Collection("fuel_fillups").InsertOne(context.Background(), fillUp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save fill-up"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Fill-up added successfully",
+		"fillUp":  fillUp,
+	})
+}
+
+// DeleteFuelFillUp removes one of the driver's fill-ups
+func DeleteFuelFillUp(c *gin.Context) {
+	deleteVehicleLogEntry(c, "fuel_fillups", "Fill-up")
+}
+
+// GetVehicleEfficiency reports km/l, running cost per km and net earning per km
+// between ?from= and ?to= (inclusive, YYYY-MM-DD, default the last 12 weeks) in
+// week or month periods, with every full-tank interval and flagged efficiency drops
+func GetVehicleEfficiency(c *gin.Context) {
+	objectID, vehicle, ok := vehicleOwner(c)
+	if !ok {
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "week")
+	if granularity != "week" && granularity != "month" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be week or month"})
+		return
+	}
+
+	loc, weekStart := userCalendar(objectID)
+	var err error
+	to := utils.StartOfDay(time.Now(), loc)
+	if value := c.Query("to"); value != "" {
+		if to, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	from := utils.StartOfWeek(to, loc, weekStart).AddDate(0, 0, -77)
+	if value := c.Query("from"); value != "" {
+		if from, err = utils.ParseLocalDate(value, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date. Use YYYY-MM-DD"})
+			return
+		}
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+	bucketStarts := summaryBucketStarts(from, end, granularity, weekStart)
+	if len(bucketStarts) > maxSummaryBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Range is too long"})
+		return
+	}
+
+	readings, fillUps, err := loadVehicleLog(objectID, vehicle)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	points := odometerPoints(readings, fillUps)
+	intervals := fuelIntervals(fillUps, loc)
+
+	// Net earnings and maintenance spend per period come from the confirmed earnings records
+	var earnings []models.Earnings
+	cursor, err := config.GetDB().Collection("earnings").Find(context.Background(), excludeFlagged(bson.M{
+		"user_id":    objectID,
+		"deleted_at": bson.M{"$exists": false},
+		"date":       bson.M{"$gte": from, "$lt": end},
+	}))
+	if err == nil {
+		err = cursor.All(context.Background(), &earnings)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	byBucket := make(map[int64][]models.Earnings)
+	for _, entry := range earnings {
+		start := truncateToBucket(entry.Date.In(loc), granularity, weekStart)
+		byBucket[start.Unix()] = append(byBucket[start.Unix()], entry)
+	}
+
+	response := models.VehicleEfficiencyResponse{
+		VehicleNumber: vehicle,
+		Granularity:   granularity,
+		Periods:       make([]models.VehicleMetrics, 0, len(bucketStarts)),
+		Intervals:     []models.FuelEfficiencyInterval{},
+	}
+	for _, start := range bucketStarts {
+		periodStart, periodEnd := start, nextSummaryBucket(start, granularity)
+		if periodStart.Before(from) {
+			periodStart = from
+		}
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+		metrics := vehiclePeriodMetrics(periodStart, periodEnd, points, fillUps, intervals)
+		metrics.Label = summaryBucketLabel(start, granularity)
+		summary := calculateSummary(byBucket[start.Unix()])
+		finishVehicleMetrics(&metrics, summary.NetEarnings, expensesByCategory(byBucket[start.Unix()])[models.ExpenseMaintenance])
+		response.Periods = append(response.Periods, metrics)
+	}
+	for _, interval := range intervals {
+		if !interval.To.Before(from) && interval.To.Before(end) {
+			response.Intervals = append(response.Intervals, interval)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// weeklyVehicleMetrics is the vehicle block of the weekly earnings response, or nil
+// when the driver has not logged any odometer readings or fill-ups
+func weeklyVehicleMetrics(userID primitive.ObjectID, start, end time.Time, week models.EarningsSummary) *models.VehicleMetrics {
+	vehicle, err := currentVehicle(userID)
+	if err != nil || vehicle == "" {
+		return nil
+	}
+	readings, fillUps, err := loadVehicleLog(userID, vehicle)
+	if err != nil {
+		log.Printf("Error loading vehicle log: %v", err)
+		return nil
+	}
+	if len(readings) == 0 && len(fillUps) == 0 {
+		return nil
+	}
+
+	loc, _ := userCalendar(userID)
+	metrics := vehiclePeriodMetrics(start, end, odometerPoints(readings, fillUps), fillUps, fuelIntervals(fillUps, loc))
+	var maintenance float64
+	for _, category := range week.Categories {
+		if category.Category == models.ExpenseMaintenance {
+			maintenance = category.Amount
+		}
+	}
+	finishVehicleMetrics(&metrics, week.NetEarnings, maintenance)
+	return &metrics
+}
+
+// vehiclePeriodMetrics measures distance and fuel for [start, end). Fill-ups count
+// in the period they were bought; km/l comes from intervals that ended in it.
+func vehiclePeriodMetrics(start, end time.Time, points []odometerPoint, fillUps []models.FuelFillUp, intervals []models.FuelEfficiencyInterval) models.VehicleMetrics {
+	metrics := models.VehicleMetrics{
+		Start:      start,
+		End:        end,
+		DistanceKm: distanceBetween(points, start, end),
+	}
+	for _, fillUp := range fillUps {
+		if !fillUp.Date.Before(start) && fillUp.Date.Before(end) {
+			metrics.FuelLitres += fillUp.Litres
+			metrics.FuelCost += fillUp.Amount
+		}
+	}
+
+	var distance, litres float64
+	for _, interval := range intervals {
+		if interval.To.Before(start) || !interval.To.Before(end) {
+			continue
+		}
+		distance += interval.DistanceKm
+		litres += interval.Litres
+		if interval.Flagged {
+			metrics.Alerts = append(metrics.Alerts, interval)
+		}
+	}
+	if litres > 0 {
+		kmPerLitre := utils.RoundMoney(distance / litres)
+		metrics.KmPerLitre = &kmPerLitre
+	}
+	return metrics
+}
+
+// finishVehicleMetrics adds the earnings side and works out the per-km figures
+func finishVehicleMetrics(metrics *models.VehicleMetrics, netEarnings, maintenance float64) {
+	metrics.DistanceKm = utils.RoundMoney(metrics.DistanceKm)
+	metrics.FuelLitres = utils.RoundMoney(metrics.FuelLitres)
+	metrics.FuelCost = utils.RoundMoney(metrics.FuelCost)
+	metrics.MaintenanceCost = utils.RoundMoney(maintenance)
+	metrics.NetEarnings = utils.RoundMoney(netEarnings)
+	if metrics.DistanceKm <= 0 {
+		return
+	}
+	runningCost := utils.RoundMoney((metrics.FuelCost + metrics.MaintenanceCost) / metrics.DistanceKm)
+	netPerKm := utils.RoundMoney(metrics.NetEarnings / metrics.DistanceKm)
+	metrics.RunningCostPerKm = &runningCost
+	metrics.NetEarningPerKm = &netPerKm
+}
+
+// fuelIntervals turns full-tank to full-tank fill-ups into km/l. Litres from partial
+// fill-ups in between belong to the interval they fall in. Each interval is compared
+// with the median of the ones before it, and a sharp drop is flagged, as that usually
+// means the vehicle needs servicing or fuel is going missing.
+func fuelIntervals(fillUps []models.FuelFillUp, loc *time.Location) []models.FuelEfficiencyInterval {
+	sorted := append([]models.FuelFillUp(nil), fillUps...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].OdometerKm < sorted[j].OdometerKm })
+
+	intervals := []models.FuelEfficiencyInterval{}
+	var previous *models.FuelFillUp
+	var litres, cost float64
+	for i := range sorted {
+		fillUp := sorted[i]
+		if previous == nil {
+			if fillUp.FullTank {
+				previous = &sorted[i]
+			}
+			continue
+		}
+		litres += fillUp.Litres
+		cost += fillUp.Amount
+		if !fillUp.FullTank {
+			continue
+		}
+
+		distance := fillUp.OdometerKm - previous.OdometerKm
+		if distance > 0 && litres > 0 {
+			intervals = append(intervals, models.FuelEfficiencyInterval{
+				From:       previous.Date.In(loc),
+				To:         fillUp.Date.In(loc),
+				StartKm:    previous.OdometerKm,
+				EndKm:      fillUp.OdometerKm,
+				DistanceKm: utils.RoundMoney(distance),
+				Litres:     utils.RoundMoney(litres),
+				FuelCost:   utils.RoundMoney(cost),
+				KmPerLitre: utils.RoundMoney(distance / litres),
+				CostPerKm:  utils.RoundMoney(cost / distance),
+			})
+		}
+		previous = &sorted[i]
+		litres, cost = 0, 0
+	}
+
+	for i := range intervals {
+		if i < efficiencyMinBaseline {
+			continue
+		}
+		var window []float64
+		for _, interval := range intervals[max(0, i-efficiencyBaselineIntervals):i] {
+			window = append(window, interval.KmPerLitre)
+		}
+		baseline := utils.RoundMoney(median(window))
+		if baseline <= 0 {
+			continue
+		}
+		drop := utils.RoundMoney((baseline - intervals[i].KmPerLitre) / baseline * 100)
+		intervals[i].BaselineKmPerLitre = &baseline
+		intervals[i].DropPercentage = &drop
+		intervals[i].Flagged = drop >= efficiencyDropPercentage
+	}
+	return intervals
+}
+
+// distanceBetween is how far the odometer moved during [start, end): from the last
+// value before the period (or the first one in it) to the last one in it
+func distanceBetween(points []odometerPoint, start, end time.Time) float64 {
+	var from, to float64
+	var hasFrom, hasTo bool
+	for _, point := range points {
+		switch {
+		case point.Date.Before(start):
+			from, hasFrom = point.Km, true
+		case point.Date.Before(end):
+			if !hasFrom {
+				from, hasFrom = point.Km, true
+			}
+			to, hasTo = point.Km, true
+		}
+	}
+	if !hasTo || to < from {
+		return 0
+	}
+	return to - from
+}
+
+// odometerPoints merges readings and fill-ups into one timeline
+func odometerPoints(readings []models.OdometerReading, fillUps []models.FuelFillUp) []odometerPoint {
+	points := make([]odometerPoint, 0, len(readings)+len(fillUps))
+	for _, reading := range readings {
+		points = append(points, odometerPoint{Date: reading.Date, Km: reading.ReadingKm})
+	}
+	for _, fillUp := range fillUps {
+		points = append(points, odometerPoint{Date: fillUp.Date, Km: fillUp.OdometerKm})
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		if !points[i].Date.Equal(points[j].Date) {
+			return points[i].Date.Before(points[j].Date)
+		}
+		return points[i].Km < points[j].Km
+	})
+	return points
+}
+
+// checkOdometerOrder rejects a value that would make the odometer run backwards
+func checkOdometerOrder(userID primitive.ObjectID, vehicle string, date time.Time, km float64) string {
+	readings, fillUps, err := loadVehicleLog(userID, vehicle)
+	if err != nil {
+		return "Could not check earlier odometer readings"
+	}
+	for _, point := range odometerPoints(readings, fillUps) {
+		if point.Date.Before(date) && point.Km > km {
+			return "Odometer reading is lower than an earlier reading"
+		}
+		if point.Date.After(date) && point.Km < km {
+			return "Odometer reading is higher than a later reading"
+		}
+	}
+	return ""
+}
+
+// loadVehicleLog returns every odometer reading and fill-up for one of the driver's vehicles
+func loadVehicleLog(userID primitive.ObjectID, vehicle string) ([]models.OdometerReading, []models.FuelFillUp, error) {
+	ctx := context.Background()
+	db := config.GetDB()
+	filter := bson.M{"user_id": userID, "vehicle_number": vehicle}
+	sortOrder := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}})
+
+	readings := []models.OdometerReading{}
+	cursor, err := db.Collection("odometer_readings").Find(ctx, filter, sortOrder)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(ctx, &readings); err != nil {
+		return nil, nil, err
+	}
+
+	fillUps := []models.FuelFillUp{}
+	cursor, err = db.Collection("fuel_fillups").Find(ctx, filter, sortOrder)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(ctx, &fillUps); err != nil {
+		return nil, nil, err
+	}
+	return readings, fillUps, nil
+}
+
+// currentVehicle is the driver's registered vehicle number, normalised so spacing
+// and case differences don't split one vehicle's log
+func currentVehicle(userID primitive.ObjectID) (string, error) {
+	var user models.User
+	err := config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"vehicle_number": 1}),
+	).Decode(&user)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(strings.Join(strings.Fields(user.VehicleNumber), "")), nil
+}
+
+// vehicleOwner resolves the signed-in driver and their vehicle, writing the error response if either is missing
+func vehicleOwner(c *gin.Context) (primitive.ObjectID, string, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return objectID, "", false
+	}
+	vehicle, err := currentVehicle(objectID)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return objectID, "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return objectID, "", false
+	}
+	if vehicle == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Add your vehicle number to your profile first"})
+		return objectID, "", false
+	}
+	return objectID, vehicle, true
+}
+
+func deleteVehicleLogEntry(c *gin.Context, collectionName, label string) {
+	objectID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	entryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + strings.ToLower(label) + " ID"})
+		return
+	}
+
+	result, err := config.GetDB().Collection(collectionName).DeleteOne(context.Background(), bson.M{"_id": entryID, "user_id": objectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete " + strings.ToLower(label)})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": label + " not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": label + " deleted successfully"})
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -7,6 +7,22 @@ db.createCollection('earnings');
 db.createCollection('chat_sessions');
 db.createCollection('tutorials');
 db.createCollection('otps');
+db.createCollection('ocr_jobs');
+db.createCollection('earnings_history');
+db.createCollection('trips');
+db.createCollection('earnings_goals');
+db.createCollection('statements');
+db.createCollection('odometer_readings');
+db.createCollection('fuel_fillups');
+db.createCollection('sync_counters');
+db.createCollection('tax_profiles');
+db.createCollection('loans');
+db.createCollection('loan_payments');
+db.createCollection('earnings_benchmarks');
+db.createCollection('fleets');
+db.createCollection('fleet_vehicles');
+db.createCollection('fleet_members');
+db.createCollection('fleet_assignments');
 
 // Create indexes for better performance
 db.users.createIndex({ "mobile": 1 }, { unique: true });
@@ -14,6 +30,28 @@ db.users.createIndex({ "aadhar_number": 1 });
 db.users.createIndex({ "license_number": 1 });
 
 db.earnings.createIndex({ "user_id": 1, "date": -1 });
+db.earnings.createIndex({ "user_id": 1, "date": 1, "source": 1 }, { name: "trip_rollup_per_day", unique: true, partialFilterExpression: { "source": "trips" } });
+db.earnings.createIndex({ "user_id": 1, "client_id": 1 }, { unique: true, partialFilterExpression: { "client_id": { $type: "string" } } });
+db.earnings.createIndex({ "user_id": 1, "sync_seq": 1 });
+db.trips.createIndex({ "user_id": 1, "date": -1 });
+db.earnings_goals.createIndex({ "user_id": 1 }, { unique: true });
+db.earnings_history.createIndex({ "earnings_id": 1, "changed_at": 1 });
+db.statements.createIndex({ "user_id": 1, "created_at": -1 });
+db.odometer_readings.createIndex({ "user_id": 1, "vehicle_number": 1, "date": 1 });
+db.fuel_fillups.createIndex({ "user_id": 1, "vehicle_number": 1, "date": 1 });
+db.tax_profiles.createIndex({ "user_id": 1 }, { unique: true });
+db.loans.createIndex({ "user_id": 1, "disbursed_on": 1 });
+db.loan_payments.createIndex({ "user_id": 1, "loan_id": 1, "date": 1 });
+db.loan_payments.createIndex({ "user_id": 1, "date": 1 });
+db.earnings_benchmarks.createIndex({ "computed_at": -1 });
+db.earnings.createIndex({ "date": 1 });
+db.fleets.createIndex({ "owner_id": 1 }, { unique: true });
+db.fleet_vehicles.createIndex({ "fleet_id": 1, "vehicle_number": 1 }, { unique: true });
+db.fleet_members.createIndex({ "fleet_id": 1, "status": 1 });
+db.fleet_members.createIndex({ "mobile": 1, "status": 1 });
+db.fleet_members.createIndex({ "user_id": 1, "status": 1 });
+db.fleet_assignments.createIndex({ "fleet_id": 1, "driver_id": 1, "from": 1 });
+db.fleet_assignments.createIndex({ "vehicle_id": 1, "to": 1 });
 
 db.chat_sessions.createIndex({ "user_id": 1, "created_at": -1 });
 
@@ -22,4 +60,7 @@ db.tutorials.createIndex({ "category": 1 });
 db.otps.createIndex({ "mobile": 1 });
 db.otps.createIndex({ "expires_at": 1 }, { expireAfterSeconds: 0 });
 
+db.ocr_jobs.createIndex({ "status": 1, "available_at": 1 });
+db.ocr_jobs.createIndex({ "expires_at": 1 }, { expireAfterSeconds: 0 });
+
 print('Database initialized successfully!'); 
\ No newline at end of file
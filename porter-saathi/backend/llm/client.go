@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ErrEmptyResponse is returned when the model produced no text
+var ErrEmptyResponse = errors.New("llm: empty response")
+
+// LLMClient is a chat model. Implementations must be safe for concurrent use.
+type LLMClient interface {
+	// Chat returns the model's reply to the conversation
+	Chat(ctx context.Context, request Request) (Response, error)
+	// ChatJSON asks for a JSON object and decodes it into out. The raw reply is
+	// returned with the error when it is not valid JSON.
+	ChatJSON(ctx context.Context, request Request, out interface{}) (Response, error)
+	// Stream calls onDelta with each piece of text as it arrives and returns the
+	// whole reply at the end. An error from onDelta stops the stream.
+	Stream(ctx context.Context, request Request, onDelta func(delta string) error) (Response, error)
+}
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is a provider-neutral chat request. System is sent as the provider's
+// system instruction, ahead of Messages.
+type Request struct {
+	Model       string
+	System      string
+	Messages    []Message
+	Temperature *float64
+	MaxTokens   int
+	// JSON asks the provider for a single JSON object
+	JSON bool
+}
+
+// Usage counts the tokens a request used, when the provider reports them
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+type Response struct {
+	Text         string `json:"text"`
+	Model        string `json:"model"`
+	FinishReason string `json:"finishReason,omitempty"`
+	Usage        Usage  `json:"usage"`
+}
+
+// APIError is a non-2xx answer from a provider
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("llm: %s returned %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// chatJSON is the shared ChatJSON: it turns on JSON mode, then decodes the reply,
+// allowing for models that wrap JSON in a markdown code fence
+func chatJSON(ctx context.Context, client LLMClient, request Request, out interface{}) (Response, error) {
+	request.JSON = true
+	response, err := client.Chat(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(response.Text)), out); err != nil {
+		return response, fmt.Errorf("llm: reply is not valid JSON: %w", err)
+	}
+	return response, nil
+}
+
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimPrefix(text, "json")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// readServerSentEvents calls onData with the data of each event in an SSE body until it ends
+func readServerSentEvents(body io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if err := onData(strings.Join(data, "\n")); err != nil {
+					return err
+				}
+				data = data[:0]
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		return onData(strings.Join(data, "\n"))
+	}
+	return nil
+}
+
+// errorMessage reads a provider's error body, keeping it short enough to log
+func errorMessage(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 2048))
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &parsed) == nil && parsed.Error.Message != "" {
+		return parsed.Error.Message
+	}
+	return strings.TrimSpace(string(data))
+}
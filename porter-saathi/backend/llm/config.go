@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionConfig is the model and sampling settings for one kind of chat session
+type SessionConfig struct {
+	Model       string
+	Temperature float64
+	MaxTokens   int
+	Timeout     time.Duration
+	// HistoryTokens is how much of the conversation is sent with each question;
+	// older turns are summarized
+	HistoryTokens int
+}
+
+// Request starts a request with the session's model, temperature and token limit
+func (s SessionConfig) Request() Request {
+	temperature := s.Temperature
+	return Request{Model: s.Model, Temperature: &temperature, MaxTokens: s.MaxTokens}
+}
+
+var (
+	defaultOnce   sync.Once
+	defaultClient LLMClient
+	defaultErr    error
+)
+
+// Default returns the client chosen by LLM_PROVIDER ("gemini", "openai" or "fake"),
+// built once on first use
+func Default() (LLMClient, error) {
+	defaultOnce.Do(func() {
+		defaultClient, defaultErr = NewFromEnv()
+		if defaultErr != nil {
+			log.Printf("LLM client not configured: %v", defaultErr)
+		}
+	})
+	return defaultClient, defaultErr
+}
+
+// SetDefault makes Default return client, such as a FakeClient in tests
+func SetDefault(client LLMClient) {
+	defaultOnce.Do(func() {})
+	defaultClient, defaultErr = client, nil
+}
+
+// NewFromEnv builds a client from the LLM_* and provider environment variables
+func NewFromEnv() (LLMClient, error) {
+	// Each call sets its own deadline from the session timeout
+	httpClient := &http.Client{}
+	switch provider() {
+	case "gemini":
+		return NewGeminiClient(os.Getenv("GEMINI_API_KEY"), os.Getenv("GEMINI_BASE_URL"), httpClient)
+	case "openai":
+		return NewOpenAIClient(os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_BASE_URL"), httpClient), nil
+	case "fake":
+		return NewFakeClient(), nil
+	default:
+		return nil, fmt.Errorf("llm: unknown LLM_PROVIDER %q", os.Getenv("LLM_PROVIDER"))
+	}
+}
+
+// ForSession returns the settings for a session type. "guru" sessions are a
+// conversation and run warmer than "general" support answers; LLM_GURU_* and
+// LLM_GENERAL_* override the shared LLM_* values for each.
+func ForSession(sessionType string) SessionConfig {
+	prefix := "LLM_GENERAL_"
+	temperature := 0.3
+	if sessionType == "guru" {
+		prefix = "LLM_GURU_"
+		temperature = 0.7
+	}
+
+	model := defaultModel()
+	if value := envString(prefix+"MODEL", os.Getenv("LLM_MODEL")); value != "" {
+		model = value
+	}
+	return SessionConfig{
+		Model:         model,
+		Temperature:   envFloat(prefix+"TEMPERATURE", envFloat("LLM_TEMPERATURE", temperature)),
+		MaxTokens:     envPositiveInt(prefix+"MAX_TOKENS", envPositiveInt("LLM_MAX_TOKENS", 0)),
+		Timeout:       time.Duration(envPositiveInt(prefix+"TIMEOUT_SECONDS", envPositiveInt("LLM_TIMEOUT_SECONDS", 30))) * time.Second,
+		HistoryTokens: envPositiveInt(prefix+"HISTORY_TOKENS", envPositiveInt("LLM_HISTORY_TOKENS", 2000)),
+	}
+}
+
+func provider() string {
+	if value := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER"))); value != "" {
+		return value
+	}
+	return "gemini"
+}
+
+func defaultModel() string {
+	switch provider() {
+	case "openai":
+		return "llama3.1"
+	case "fake":
+		return "fake"
+	default:
+		return "gemini-2.5-flash"
+	}
+}
+
+func envString(key, defaultValue string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func envFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func envPositiveInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// FakeClient is a deterministic LLMClient for tests and for running without a model.
+// It replies with its script in order, then echoes the last user message. Every
+// request is recorded.
+type FakeClient struct {
+	mu       sync.Mutex
+	script   []string
+	next     int
+	requests []Request
+	// Err, when set, is returned by every call instead of a reply
+	Err error
+}
+
+func NewFakeClient(script ...string) *FakeClient {
+	return &FakeClient{script: script}
+}
+
+func (f *FakeClient) Chat(ctx context.Context, request Request) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	return f.reply(request)
+}
+
+func (f *FakeClient) ChatJSON(ctx context.Context, request Request, out interface{}) (Response, error) {
+	return chatJSON(ctx, f, request, out)
+}
+
+// Stream sends the reply a word at a time, keeping the spacing, so callers see
+// several deltas just as with a real model
+func (f *FakeClient) Stream(ctx context.Context, request Request, onDelta func(delta string) error) (Response, error) {
+	response, err := f.reply(request)
+	if err != nil {
+		return response, err
+	}
+	for _, delta := range strings.SplitAfter(response.Text, " ") {
+		if err := ctx.Err(); err != nil {
+			return response, err
+		}
+		if delta == "" {
+			continue
+		}
+		if err := onDelta(delta); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
+}
+
+// Requests returns every request received so far
+func (f *FakeClient) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+func (f *FakeClient) reply(request Request) (Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, request)
+	if f.Err != nil {
+		return Response{}, f.Err
+	}
+
+	response := Response{Model: "fake", FinishReason: "stop"}
+	if f.next < len(f.script) {
+		response.Text = f.script[f.next]
+		f.next++
+	} else {
+		response.Text = "You said: " + lastUserMessage(request.Messages)
+	}
+	response.Usage = Usage{
+		PromptTokens:     len(strings.Fields(request.System)) + messageWords(request.Messages),
+		CompletionTokens: len(strings.Fields(response.Text)),
+	}
+	return response, nil
+}
+
+func lastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func messageWords(messages []Message) int {
+	words := 0
+	for _, message := range messages {
+		words += len(strings.Fields(message.Content))
+	}
+	return words
+}
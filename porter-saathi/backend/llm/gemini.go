@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGeminiBaseURL is the Gemini API; GEMINI_BASE_URL can point elsewhere, e.g. a proxy
+const DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiClient talks to Google's Gemini generateContent API
+type GeminiClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewGeminiClient(apiKey, baseURL string, httpClient *http.Client) (*GeminiClient, error) {
+	if apiKey == "" {
+		return nil, errors.New("llm: GEMINI_API_KEY not set")
+	}
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &GeminiClient{apiKey: apiKey, baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}, nil
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+func (c *GeminiClient) Chat(ctx context.Context, request Request) (Response, error) {
+	var response Response
+	body, err := c.post(ctx, request, "generateContent", "")
+	if err != nil {
+		return response, err
+	}
+	defer body.Close()
+
+	var result geminiResponse
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return response, fmt.Errorf("llm: decoding Gemini response: %w", err)
+	}
+	response = Response{Model: request.Model}
+	addGeminiChunk(&response, result)
+	if response.Text == "" {
+		return response, ErrEmptyResponse
+	}
+	return response, nil
+}
+
+func (c *GeminiClient) ChatJSON(ctx context.Context, request Request, out interface{}) (Response, error) {
+	return chatJSON(ctx, c, request, out)
+}
+
+func (c *GeminiClient) Stream(ctx context.Context, request Request, onDelta func(delta string) error) (Response, error) {
+	response := Response{Model: request.Model}
+	body, err := c.post(ctx, request, "streamGenerateContent", "sse")
+	if err != nil {
+		return response, err
+	}
+	defer body.Close()
+
+	err = readServerSentEvents(body, func(data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("llm: decoding Gemini stream: %w", err)
+		}
+		before := len(response.Text)
+		addGeminiChunk(&response, chunk)
+		if delta := response.Text[before:]; delta != "" {
+			return onDelta(delta)
+		}
+		return nil
+	})
+	if err != nil {
+		return response, err
+	}
+	if response.Text == "" {
+		return response, ErrEmptyResponse
+	}
+	return response, nil
+}
+
+// addGeminiChunk appends a response, or one streamed chunk of it, to response
+func addGeminiChunk(response *Response, chunk geminiResponse) {
+	if len(chunk.Candidates) > 0 {
+		candidate := chunk.Candidates[0]
+		for _, part := range candidate.Content.Parts {
+			response.Text += part.Text
+		}
+		if candidate.FinishReason != "" {
+			response.FinishReason = candidate.FinishReason
+		}
+	}
+	if chunk.ModelVersion != "" {
+		response.Model = chunk.ModelVersion
+	}
+	if chunk.UsageMetadata.PromptTokenCount > 0 {
+		response.Usage = Usage{
+			PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
+			CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
+		}
+	}
+}
+
+// post sends the request to a model method and returns the body of a successful answer
+func (c *GeminiClient) post(ctx context.Context, request Request, method, alt string) (io.ReadCloser, error) {
+	payload := geminiRequest{
+		Contents: []geminiContent{},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     request.Temperature,
+			MaxOutputTokens: request.MaxTokens,
+		},
+	}
+	if request.JSON {
+		payload.GenerationConfig.ResponseMimeType = "application/json"
+	}
+	if request.System != "" {
+		payload.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: request.System}}}
+	}
+	for _, message := range request.Messages {
+		// Gemini has no system turns inside a conversation and calls the assistant "model"
+		role := "user"
+		if message.Role == RoleAssistant {
+			role = "model"
+		}
+		payload.Contents = append(payload.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: message.Content}}})
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/models/%s:%s", c.baseURL, url.PathEscape(request.Model), method)
+	if alt != "" {
+		endpoint += "?alt=" + alt
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("x-goog-api-key", c.apiKey)
+
+	httpResponse, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode/100 != 2 {
+		defer httpResponse.Body.Close()
+		return nil, &APIError{Provider: "gemini", StatusCode: httpResponse.StatusCode, Message: errorMessage(httpResponse.Body)}
+	}
+	return httpResponse.Body, nil
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultOpenAIBaseURL is a local Ollama server; llama.cpp's server listens on http://localhost:8080/v1
+const DefaultOpenAIBaseURL = "http://localhost:11434/v1"
+
+// OpenAIClient talks to any server with an OpenAI-compatible /chat/completions
+// endpoint: OpenAI itself, Ollama, llama.cpp, vLLM and the like
+type OpenAIClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpenAIClient creates a client; apiKey may be empty for local servers
+func NewOpenAIClient(apiKey, baseURL string, httpClient *http.Client) *OpenAIClient {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &OpenAIClient{apiKey: apiKey, baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+type openAIRequest struct {
+	Model          string    `json:"model"`
+	Messages       []Message `json:"messages"`
+	Temperature    *float64  `json:"temperature,omitempty"`
+	MaxTokens      int       `json:"max_tokens,omitempty"`
+	Stream         bool      `json:"stream,omitempty"`
+	ResponseFormat *struct {
+		Type string `json:"type"`
+	} `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      Message `json:"message"`
+		Delta        Message `json:"delta"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *OpenAIClient) Chat(ctx context.Context, request Request) (Response, error) {
+	response := Response{Model: request.Model}
+	body, err := c.post(ctx, request, false)
+	if err != nil {
+		return response, err
+	}
+	defer body.Close()
+
+	var result openAIResponse
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return response, fmt.Errorf("llm: decoding chat completion: %w", err)
+	}
+	if len(result.Choices) > 0 {
+		response.Text = result.Choices[0].Message.Content
+		response.FinishReason = result.Choices[0].FinishReason
+	}
+	addOpenAIMetadata(&response, result)
+	if response.Text == "" {
+		return response, ErrEmptyResponse
+	}
+	return response, nil
+}
+
+func (c *OpenAIClient) ChatJSON(ctx context.Context, request Request, out interface{}) (Response, error) {
+	return chatJSON(ctx, c, request, out)
+}
+
+func (c *OpenAIClient) Stream(ctx context.Context, request Request, onDelta func(delta string) error) (Response, error) {
+	response := Response{Model: request.Model}
+	body, err := c.post(ctx, request, true)
+	if err != nil {
+		return response, err
+	}
+	defer body.Close()
+
+	err = readServerSentEvents(body, func(data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("llm: decoding chat completion stream: %w", err)
+		}
+		addOpenAIMetadata(&response, chunk)
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if reason := chunk.Choices[0].FinishReason; reason != "" {
+			response.FinishReason = reason
+		}
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			return nil
+		}
+		response.Text += delta
+		return onDelta(delta)
+	})
+	if err != nil {
+		return response, err
+	}
+	if response.Text == "" {
+		return response, ErrEmptyResponse
+	}
+	return response, nil
+}
+
+func addOpenAIMetadata(response *Response, result openAIResponse) {
+	if result.Model != "" {
+		response.Model = result.Model
+	}
+	if result.Usage != nil {
+		response.Usage = Usage{PromptTokens: result.Usage.PromptTokens, CompletionTokens: result.Usage.CompletionTokens}
+	}
+}
+
+// post sends a chat completion request and returns the body of a successful answer
+func (c *OpenAIClient) post(ctx context.Context, request Request, stream bool) (io.ReadCloser, error) {
+	payload := openAIRequest{
+		Model:       request.Model,
+		Messages:    make([]Message, 0, len(request.Messages)+1),
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		Stream:      stream,
+	}
+	if request.System != "" {
+		payload.Messages = append(payload.Messages, Message{Role: RoleSystem, Content: request.System})
+	}
+	payload.Messages = append(payload.Messages, request.Messages...)
+	if request.JSON {
+		payload.ResponseFormat = &struct {
+			Type string `json:"type"`
+		}{Type: "json_object"}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	httpResponse, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	if httpResponse.StatusCode/100 != 2 {
+		defer httpResponse.Body.Close()
+		return nil, &APIError{Provider: "openai", StatusCode: httpResponse.StatusCode, Message: errorMessage(httpResponse.Body)}
+	}
+	return httpResponse.Body, nil
+}
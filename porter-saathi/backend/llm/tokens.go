@@ -0,0 +1,21 @@
+package llm
+
+import "unicode/utf8"
+
+// MessageOverheadTokens is roughly what each turn costs beyond its text
+const MessageOverheadTokens = 4
+
+// EstimateTokens guesses how many tokens text uses without a tokenizer: about four
+// characters per token for English and Hinglish, and two for Devanagari and other
+// scripts, which tokenizers split more finely. It errs on the high side.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + (other+1)/2
+}
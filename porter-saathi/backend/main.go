@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"porter-saathi-backend/config"
+	"porter-saathi-backend/controllers"
 	"porter-saathi-backend/routes"
 	"porter-saathi-backend/utils"
 
@@ -22,9 +24,20 @@ func main() {
 	// Initialize database connection
 	config.ConnectDB()
 
+	// Apply pending data migrations
+	utils.RunMigrations()
+
 	// Seed database with initial data
 	utils.SeedDatabase()
 
+	controllers.EnsureEarningsIndexes()
+
+	// Start background OCR job workers
+	controllers.StartOCRWorkers(context.Background())
+
+	// Precompute anonymized peer benchmarks on a schedule
+	controllers.StartBenchmarkScheduler(context.Background())
+
 	// Initialize Gin router
 	r := gin.Default()
 
@@ -32,7 +45,7 @@ func main() {
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowAllOrigins = true
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Job-Token"}
 	corsConfig.AllowCredentials = true
 
 	log.Printf("CORS Configuration: Allowing all origins for development")
@@ -39,3 +39,16 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware sets the user ID when a valid token is sent, but lets anonymous requests through
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(tokenParts) == 2 && tokenParts[0] == "Bearer" {
+			if claims, err := utils.ValidateJWT(tokenParts[1]); err == nil {
+				c.Set("userID", claims.UserID)
+			}
+		}
+		c.Next()
+	}
+}
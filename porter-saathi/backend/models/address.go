@@ -0,0 +1,22 @@
+package models
+
+// Address is a structured Indian postal address
+type Address struct {
+	CareOf      string `bson:"care_of,omitempty" json:"careOf,omitempty"`
+	House       string `bson:"house,omitempty" json:"house,omitempty"`
+	Street      string `bson:"street,omitempty" json:"street,omitempty"`
+	Landmark    string `bson:"landmark,omitempty" json:"landmark,omitempty"`
+	Locality    string `bson:"locality,omitempty" json:"locality,omitempty"`
+	VillageTown string `bson:"village_town,omitempty" json:"villageTown,omitempty"`
+	District    string `bson:"district,omitempty" json:"district,omitempty"`
+	State       string `bson:"state,omitempty" json:"state,omitempty"`
+	PinCode     string `bson:"pin_code,omitempty" json:"pinCode,omitempty"`
+	// StateHint and DistrictHint are where the PIN code's postal region suggests the
+	// address is; the PIN code itself is not validated
+	StateHint    string   `bson:"state_hint,omitempty" json:"stateHint,omitempty"`
+	DistrictHint string   `bson:"district_hint,omitempty" json:"districtHint,omitempty"`
+	Issues       []string `bson:"issues,omitempty" json:"issues,omitempty"`
+	Raw          string   `bson:"raw,omitempty" json:"raw,omitempty"`
+	// Local is the same address as printed in the regional script, when available
+	Local *Address `bson:"local,omitempty" json:"local,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Vehicle classes drivers are benchmarked within
+const (
+	VehicleTwoWheeler   = "two_wheeler"
+	VehicleThreeWheeler = "three_wheeler"
+	VehicleMiniTruck    = "mini_truck" // Tata Ace and similar
+	VehiclePickup       = "pickup"     // 8 ft pickups such as the Bolero Pickup
+	VehicleLCV          = "lcv"        // Tata 407 and 14 ft trucks
+	VehicleTruck        = "truck"
+)
+
+// VehicleClasses lists the classes a driver can pick on their profile
+var VehicleClasses = []string{
+	VehicleTwoWheeler, VehicleThreeWheeler, VehicleMiniTruck, VehiclePickup, VehicleLCV, VehicleTruck,
+}
+
+// Benchmark cohort levels, from most to least specific
+const (
+	CohortCity     = "city"
+	CohortNational = "national"
+)
+
+// BenchmarkPercentiles are the percentiles stored for each metric. Outer percentiles
+// of a small cohort are a single driver's figure (the 5th of ten drivers is the
+// lowest one), so only the quartiles are kept, and those with noise added.
+var BenchmarkPercentiles = []float64{25, 50, 75}
+
+// EarningsBenchmark is the precomputed distribution of one cohort's figures. Only
+// cohorts with enough drivers are stored, and no driver IDs are kept.
+type EarningsBenchmark struct {
+	CohortKey    string    `bson:"_id" json:"cohortKey"`
+	Level        string    `bson:"level" json:"level"`
+	City         string    `bson:"city,omitempty" json:"city,omitempty"`
+	VehicleClass string    `bson:"vehicle_class" json:"vehicleClass"`
+	Drivers      int       `bson:"drivers" json:"drivers"`
+	WindowFrom   time.Time `bson:"window_from" json:"windowFrom"`
+	WindowTo     time.Time `bson:"window_to" json:"windowTo"`
+	ComputedAt   time.Time `bson:"computed_at" json:"computedAt"`
+	// Values at each of BenchmarkPercentiles
+	NetPerDay    []float64 `bson:"net_per_day" json:"netPerDay"`
+	TripsPerDay  []float64 `bson:"trips_per_day" json:"tripsPerDay"`
+	ExpenseRatio []float64 `bson:"expense_ratio" json:"expenseRatio"`
+}
+
+// BenchmarkMetric places one of the driver's figures among their peers
+type BenchmarkMetric struct {
+	Value float64 `json:"value"`
+	// Percentile is the share of peers with a lower value, estimated outside the quartiles
+	Percentile     float64 `json:"percentile"`
+	Median         float64 `json:"median"`
+	P25            float64 `json:"p25"`
+	P75            float64 `json:"p75"`
+	HigherIsBetter bool    `json:"higherIsBetter"`
+}
+
+type BenchmarkResponse struct {
+	Available    bool             `json:"available"`
+	Message      string           `json:"message,omitempty"`
+	City         string           `json:"city,omitempty"`
+	VehicleClass string           `json:"vehicleClass,omitempty"`
+	Cohort       string           `json:"cohort,omitempty"`
+	CohortSize   int              `json:"cohortSize,omitempty"`
+	WindowFrom   time.Time        `json:"windowFrom"`
+	WindowTo     time.Time        `json:"windowTo"`
+	ComputedAt   *time.Time       `json:"computedAt,omitempty"`
+	DaysWorked   int              `json:"daysWorked"`
+	NetPerDay    *BenchmarkMetric `json:"netPerDay,omitempty"`
+	TripsPerDay  *BenchmarkMetric `json:"tripsPerDay,omitempty"`
+	ExpenseRatio *BenchmarkMetric `json:"expenseRatio,omitempty"`
+}
@@ -12,8 +12,12 @@ type ChatSession struct {
 	SessionType string             `bson:"session_type" json:"sessionType"` // "general" or "guru"
 	Messages    []ChatMessage      `bson:"messages" json:"messages"`
 	Language    string             `bson:"language" json:"language"`
-	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+	// Summary covers the first SummarizedCount messages, which are no longer sent to the model
+	Summary         string     `bson:"summary,omitempty" json:"summary,omitempty"`
+	SummarizedCount int        `bson:"summarized_count" json:"summarizedCount"`
+	SummarizedAt    *time.Time `bson:"summarized_at,omitempty" json:"summarizedAt,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time  `bson:"updated_at" json:"updatedAt"`
 }
 
 type ChatMessage struct {
@@ -33,3 +37,20 @@ type ChatResponse struct {
 	SessionID string        `json:"sessionId"`
 	Messages  []ChatMessage `json:"messages"`
 }
+
+// ChatStreamDelta is a "delta" event: the next piece of a streamed reply
+type ChatStreamDelta struct {
+	Text string `json:"text"`
+}
+
+// ChatStreamSentence is a "sentence" event, sent as each sentence of the reply completes
+type ChatStreamSentence struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+// ChatStreamDone is the last event of a streamed reply, sent once the turn is saved
+type ChatStreamDone struct {
+	SessionID string      `json:"sessionId"`
+	Message   ChatMessage `json:"message"`
+}
@@ -7,27 +7,133 @@ import (
 )
 
 type Earnings struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID      primitive.ObjectID `bson:"user_id" json:"userId"`
-	Date        time.Time          `bson:"date" json:"date"`
-	Revenue     float64            `bson:"revenue" json:"revenue"`
-	Expenses    float64            `bson:"expenses" json:"expenses"`
-	Trips       int                `bson:"trips" json:"trips"`
-	NetEarnings float64            `bson:"net_earnings" json:"netEarnings"`
-	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID   primitive.ObjectID `bson:"user_id" json:"userId"`
+	Date     time.Time          `bson:"date" json:"date"`
+	Revenue  float64            `bson:"revenue" json:"revenue"`
+	Expenses float64            `bson:"expenses" json:"expenses"`
+	// ExpenseItems itemizes Expenses; older entries may only have the total
+	ExpenseItems []ExpenseItem `bson:"expense_items,omitempty" json:"expenseItems,omitempty"`
+	Trips        int           `bson:"trips" json:"trips"`
+	NetEarnings  float64       `bson:"net_earnings" json:"netEarnings"`
+	CreatedAt    time.Time     `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time     `bson:"updated_at" json:"updatedAt"`
+	// Source is "trips" for daily rollups derived from the trip ledger, empty for manual entries
+	Source string `bson:"source,omitempty" json:"source,omitempty"`
+	// DeletedAt is set when the entry has been soft-deleted; such entries are left out of every total
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+	// PurgedAt is set on the tombstone left by a permanent delete: the figures are gone,
+	// but the entry stays deleted so devices that synced it drop it too
+	PurgedAt *time.Time `bson:"purged_at,omitempty" json:"purgedAt,omitempty"`
+	// ClientID is the app's own ID for an entry made offline; retries with the same ID never duplicate it
+	ClientID string `bson:"client_id,omitempty" json:"clientId,omitempty"`
+	// ModifiedAt is when the latest change was made: the device's clock for synced changes, the server's otherwise
+	ModifiedAt time.Time `bson:"modified_at,omitempty" json:"modifiedAt"`
+	// SyncSeq increases on every write to any of the driver's entries and backs the sync cursor
+	SyncSeq int64 `bson:"sync_seq" json:"syncSeq"`
+	// ReviewStatus is "flagged" while an unusual entry waits for the driver to confirm it;
+	// flagged entries are left out of statements, tax estimates and AI advice
+	ReviewStatus   string     `bson:"review_status,omitempty" json:"reviewStatus,omitempty"`
+	AnomalyReasons []string   `bson:"anomaly_reasons,omitempty" json:"anomalyReasons,omitempty"`
+	ConfirmedAt    *time.Time `bson:"confirmed_at,omitempty" json:"confirmedAt,omitempty"`
 }
 
+// Review statuses of an earnings entry
+const (
+	ReviewFlagged   = "flagged"
+	ReviewConfirmed = "confirmed"
+)
+
+// Expense categories. Commission is only used for trip rollups; unitemized expenses count as other.
+const (
+	ExpenseFuel             = "fuel"
+	ExpenseToll             = "toll"
+	ExpenseParking          = "parking"
+	ExpenseMaintenance      = "maintenance"
+	ExpenseFood             = "food"
+	ExpenseLoadingUnloading = "loading_unloading"
+	ExpenseEMI              = "emi"
+	ExpenseChallan          = "challan"
+	ExpenseCommission       = "commission"
+	ExpenseOther            = "other"
+)
+
+// ExpenseCategories lists the categories in the order they are reported
+var ExpenseCategories = []string{
+	ExpenseFuel, ExpenseToll, ExpenseParking, ExpenseMaintenance, ExpenseFood,
+	ExpenseLoadingUnloading, ExpenseEMI, ExpenseChallan, ExpenseCommission, ExpenseOther,
+}
+
+// ExpenseItem is one line of a day's expenses
+type ExpenseItem struct {
+	Category   string  `bson:"category" json:"category" binding:"required,oneof=fuel toll parking maintenance food loading_unloading emi challan other"`
+	Amount     float64 `bson:"amount" json:"amount" binding:"gte=0,lte=100000"`
+	Note       string  `bson:"note,omitempty" json:"note,omitempty"`
+	ReceiptRef string  `bson:"receipt_ref,omitempty" json:"receiptRef,omitempty"`
+}
+
+// CategoryTotal is the spend in one expense category over a period
+type CategoryTotal struct {
+	Category   string  `json:"category"`
+	Amount     float64 `json:"amount"`
+	Percentage float64 `json:"percentage"`
+	// Change compares with the previous period; nil when there is nothing to compare with
+	PreviousAmount   *float64 `json:"previousAmount,omitempty"`
+	Change           *float64 `json:"change,omitempty"`
+	ChangePercentage *float64 `json:"changePercentage,omitempty"`
+}
+
+// EarningsSourceTrips marks a daily Earnings document that is rolled up from trips
+const EarningsSourceTrips = "trips"
+
+// Earnings change actions recorded in the audit history
+const (
+	EarningsChangeCreate  = "create"
+	EarningsChangeUpdate  = "update"
+	EarningsChangeDelete  = "delete"
+	EarningsChangePurge   = "purge"
+	EarningsChangeRestore = "restore"
+)
+
+// EarningsChange is one entry in the audit history of an earnings record
+type EarningsChange struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EarningsID primitive.ObjectID `bson:"earnings_id" json:"earningsId"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	Action     string             `bson:"action" json:"action"`
+	Before     *Earnings          `bson:"before,omitempty" json:"before,omitempty"`
+	After      *Earnings          `bson:"after,omitempty" json:"after,omitempty"`
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedAt  time.Time          `bson:"changed_at" json:"changedAt"`
+}
+
+// EarningsUpdateRequest changes only the fields that are sent
+type EarningsUpdateRequest struct {
+	Date         *string        `json:"date"`
+	Revenue      *float64       `json:"revenue" binding:"omitempty,gte=0,lte=100000"`
+	Expenses     *float64       `json:"expenses" binding:"omitempty,gte=0,lte=100000"`
+	ExpenseItems *[]ExpenseItem `json:"expenseItems" binding:"omitempty,dive"`
+	Trips        *int           `json:"trips" binding:"omitempty,gte=0,lte=100"`
+	Reason       string         `json:"reason"`
+}
+
+// EarningsRequest takes either an expenses total or itemized expenseItems, which then set the total.
+// Zero is a valid figure for a day off or a day without spend; the upper limits catch extra zeros.
 type EarningsRequest struct {
-	Date     string  `json:"date" binding:"required"`
-	Revenue  float64 `json:"revenue" binding:"required"`
-	Expenses float64 `json:"expenses" binding:"required"`
-	Trips    int     `json:"trips" binding:"required"`
+	Date         string        `json:"date" binding:"required"`
+	Revenue      float64       `json:"revenue" binding:"gte=0,lte=100000"`
+	Expenses     float64       `json:"expenses" binding:"gte=0,lte=100000"`
+	ExpenseItems []ExpenseItem `json:"expenseItems" binding:"dive"`
+	Trips        int           `json:"trips" binding:"gte=0,lte=100"`
+	// ClientID makes the create idempotent; the Idempotency-Key header works the same way
+	ClientID string `json:"clientId" binding:"max=100"`
 }
 
 type EarningsResponse struct {
 	Today    EarningsSummary `json:"today"`
 	LastWeek EarningsSummary `json:"lastWeek"`
+	// EMIs paid today and last week, and dues; omitted for drivers without loans
+	Loans *EarningsLoans `json:"loans,omitempty"`
 }
 
 type EarningsSummary struct {
@@ -35,6 +141,8 @@ type EarningsSummary struct {
 	Expenses    float64 `json:"expenses"`
 	Trips       int     `json:"trips"`
 	NetEarnings float64 `json:"netEarnings"`
+	// Categories breaks Expenses down; only categories with spend in either period are listed
+	Categories []CategoryTotal `json:"categories"`
 }
 
 type DailyEarnings struct {
@@ -52,4 +160,27 @@ type WeeklyEarningsResponse struct {
 	PreviousWeek     EarningsSummary `json:"previousWeek"`
 	GrowthPercentage float64         `json:"growthPercentage"`
 	WeekStartDate    time.Time       `json:"weekStartDate"`
+	// Running costs for the week, omitted until the driver logs odometer readings or fill-ups
+	Vehicle *VehicleMetrics `json:"vehicle,omitempty"`
+	// EMIs paid this week and last, and dues; omitted for drivers without loans
+	Loans *EarningsLoans `json:"loans,omitempty"`
+}
+
+// EarningsBucket is one day, week or month of an earnings summary
+type EarningsBucket struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Label       string    `json:"label"`
+	Revenue     float64   `json:"revenue"`
+	Expenses    float64   `json:"expenses"`
+	Trips       int       `json:"trips"`
+	NetEarnings float64   `json:"netEarnings"`
+}
+
+type EarningsRangeResponse struct {
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Granularity string           `json:"granularity"`
+	Buckets     []EarningsBucket `json:"buckets"`
+	Total       EarningsSummary  `json:"total"`
 }
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fleet member statuses
+const (
+	FleetMemberInvited  = "invited"
+	FleetMemberActive   = "active"
+	FleetMemberDeclined = "declined"
+	FleetMemberRemoved  = "removed"
+	FleetMemberLeft     = "left"
+)
+
+// Vehicle document statuses on the fleet dashboard
+const (
+	DocumentValid    = "valid"
+	DocumentExpiring = "expiring"
+	DocumentExpired  = "expired"
+	DocumentMissing  = "missing"
+)
+
+// Fleet is a small fleet owner's business. Each owner has one fleet.
+type Fleet struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID   primitive.ObjectID `bson:"owner_id" json:"ownerId"`
+	Name      string             `bson:"name" json:"name"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+type FleetRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// FleetVehicle is a vehicle owned by a fleet, with the driver it is assigned to
+type FleetVehicle struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FleetID       primitive.ObjectID `bson:"fleet_id" json:"fleetId"`
+	VehicleNumber string             `bson:"vehicle_number" json:"vehicleNumber"`
+	VehicleClass  string             `bson:"vehicle_class,omitempty" json:"vehicleClass,omitempty"`
+	// Expiry dates of the vehicle's papers, when the owner has entered them
+	InsuranceExpiry *time.Time `bson:"insurance_expiry,omitempty" json:"insuranceExpiry,omitempty"`
+	PermitExpiry    *time.Time `bson:"permit_expiry,omitempty" json:"permitExpiry,omitempty"`
+	FitnessExpiry   *time.Time `bson:"fitness_expiry,omitempty" json:"fitnessExpiry,omitempty"`
+	PUCExpiry       *time.Time `bson:"puc_expiry,omitempty" json:"pucExpiry,omitempty"`
+	// DriverID is the fleet member driving the vehicle, nil while it is unassigned
+	DriverID   *primitive.ObjectID `bson:"driver_id,omitempty" json:"driverId,omitempty"`
+	AssignedAt *time.Time          `bson:"assigned_at,omitempty" json:"assignedAt,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time           `bson:"updated_at" json:"updatedAt"`
+}
+
+// FleetVehicleRequest adds or edits a fleet vehicle. Dates are YYYY-MM-DD; an empty date clears it.
+type FleetVehicleRequest struct {
+	VehicleNumber   string `json:"vehicleNumber" binding:"required,max=20"`
+	VehicleClass    string `json:"vehicleClass" binding:"omitempty,oneof=two_wheeler three_wheeler mini_truck pickup lcv truck"`
+	InsuranceExpiry string `json:"insuranceExpiry"`
+	PermitExpiry    string `json:"permitExpiry"`
+	FitnessExpiry   string `json:"fitnessExpiry"`
+	PUCExpiry       string `json:"pucExpiry"`
+}
+
+// FleetAssignRequest assigns a vehicle to a fleet member's user ID, or unassigns it when driverId is empty
+type FleetAssignRequest struct {
+	DriverID string `json:"driverId"`
+}
+
+// FleetAssignment is one period a driver had a fleet vehicle; earnings entered in it count towards the vehicle
+type FleetAssignment struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FleetID   primitive.ObjectID `bson:"fleet_id" json:"fleetId"`
+	VehicleID primitive.ObjectID `bson:"vehicle_id" json:"vehicleId"`
+	DriverID  primitive.ObjectID `bson:"driver_id" json:"driverId"`
+	From      time.Time          `bson:"from" json:"from"`
+	// To is nil while the assignment is current
+	To *time.Time `bson:"to,omitempty" json:"to,omitempty"`
+}
+
+// FleetMember is a driver invited to a fleet by mobile number. UserID is set once they accept,
+// as the invite can be sent before the driver has signed up.
+type FleetMember struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	FleetID   primitive.ObjectID  `bson:"fleet_id" json:"fleetId"`
+	Mobile    string              `bson:"mobile" json:"mobile"`
+	UserID    *primitive.ObjectID `bson:"user_id,omitempty" json:"userId,omitempty"`
+	Status    string              `bson:"status" json:"status"`
+	InvitedAt time.Time           `bson:"invited_at" json:"invitedAt"`
+	JoinedAt  *time.Time          `bson:"joined_at,omitempty" json:"joinedAt,omitempty"`
+	// LeftAt is when the driver left or was removed; their earnings after it are never shown to the owner
+	LeftAt *time.Time `bson:"left_at,omitempty" json:"leftAt,omitempty"`
+}
+
+type FleetInviteRequest struct {
+	Mobile string `json:"mobile" binding:"required,max=15"`
+}
+
+// FleetInvite is a pending invite as the invited driver sees it
+type FleetInvite struct {
+	ID        primitive.ObjectID `json:"id"`
+	FleetName string             `json:"fleetName"`
+	OwnerName string             `json:"ownerName"`
+	InvitedAt time.Time          `json:"invitedAt"`
+}
+
+// FleetDetail is the owner's view of their fleet
+type FleetDetail struct {
+	Fleet    Fleet          `json:"fleet"`
+	Vehicles []FleetVehicle `json:"vehicles"`
+	Members  []FleetMember  `json:"members"`
+}
+
+// FleetMembership is the driver's view of the fleet they drive for, and what its owner can see
+type FleetMembership struct {
+	FleetID         primitive.ObjectID `json:"fleetId"`
+	FleetName       string             `json:"fleetName"`
+	OwnerName       string             `json:"ownerName"`
+	JoinedAt        time.Time          `json:"joinedAt"`
+	VehicleNumber   string             `json:"vehicleNumber,omitempty"`
+	SharedWithOwner []string           `json:"sharedWithOwner"`
+}
+
+// FleetTotals are earnings over the dashboard period
+type FleetTotals struct {
+	Revenue     float64 `json:"revenue"`
+	Expenses    float64 `json:"expenses"`
+	NetEarnings float64 `json:"netEarnings"`
+	Trips       int     `json:"trips"`
+	DaysWorked  int     `json:"daysWorked"`
+}
+
+// FleetDocumentStatus is where one of a vehicle's papers stands
+type FleetDocumentStatus struct {
+	Document string     `json:"document"`
+	Status   string     `json:"status"`
+	Expiry   *time.Time `json:"expiry,omitempty"`
+}
+
+type FleetVehicleReport struct {
+	VehicleID     primitive.ObjectID    `json:"vehicleId"`
+	VehicleNumber string                `json:"vehicleNumber"`
+	VehicleClass  string                `json:"vehicleClass,omitempty"`
+	DriverName    string                `json:"driverName,omitempty"`
+	Totals        FleetTotals           `json:"totals"`
+	Documents     []FleetDocumentStatus `json:"documents"`
+}
+
+// FleetDriverReport only holds totals and whether documents are on file; the driver's
+// individual entries, loans, tax, chats and document files stay private
+type FleetDriverReport struct {
+	MemberID      primitive.ObjectID  `json:"memberId"`
+	UserID        *primitive.ObjectID `json:"userId,omitempty"`
+	Name          string              `json:"name,omitempty"`
+	Mobile        string              `json:"mobile"`
+	Status        string              `json:"status"`
+	VehicleNumber string              `json:"vehicleNumber,omitempty"`
+	Totals        FleetTotals         `json:"totals"`
+	LastWorked    *time.Time          `json:"lastWorked,omitempty"`
+	Verified      bool                `json:"verified"`
+	// Documents says which of the driver's documents are uploaded
+	Documents map[string]bool `json:"documents"`
+}
+
+type FleetDashboard struct {
+	FleetID  primitive.ObjectID   `json:"fleetId"`
+	Name     string               `json:"name"`
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Totals   FleetTotals          `json:"totals"`
+	Vehicles []FleetVehicleReport `json:"vehicles"`
+	Drivers  []FleetDriverReport  `json:"drivers"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Goal periods
+const (
+	GoalDaily   = "daily"
+	GoalWeekly  = "weekly"
+	GoalMonthly = "monthly"
+)
+
+// GoalTarget is what the driver aims for in one period; zero means no target
+type GoalTarget struct {
+	NetEarnings float64 `bson:"net_earnings" json:"netEarnings" binding:"gte=0"`
+	Trips       int     `bson:"trips" json:"trips" binding:"gte=0"`
+}
+
+// EarningsGoals are a driver's targets, one document per driver
+type EarningsGoals struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	Daily     GoalTarget         `bson:"daily" json:"daily"`
+	Weekly    GoalTarget         `bson:"weekly" json:"weekly"`
+	Monthly   GoalTarget         `bson:"monthly" json:"monthly"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// GoalsRequest updates only the periods that are sent
+type GoalsRequest struct {
+	Daily   *GoalTarget `json:"daily"`
+	Weekly  *GoalTarget `json:"weekly"`
+	Monthly *GoalTarget `json:"monthly"`
+}
+
+// GoalProgress is how far the driver is into one period's targets
+type GoalProgress struct {
+	Period string     `json:"period"`
+	Start  time.Time  `json:"start"`
+	End    time.Time  `json:"end"`
+	Target GoalTarget `json:"target"`
+	// Actual totals so far this period
+	NetEarnings float64 `json:"netEarnings"`
+	Trips       int     `json:"trips"`
+	// Percentages of each target reached, omitted when that target is not set
+	NetEarningsPercentage *float64 `json:"netEarningsPercentage,omitempty"`
+	TripsPercentage       *float64 `json:"tripsPercentage,omitempty"`
+	Achieved              bool     `json:"achieved"`
+	// Projections at the current pace
+	ElapsedPercentage    float64    `json:"elapsedPercentage"`
+	ProjectedNetEarnings float64    `json:"projectedNetEarnings"`
+	ProjectedTrips       int        `json:"projectedTrips"`
+	ProjectedCompletion  *time.Time `json:"projectedCompletion,omitempty"`
+	OnTrack              bool       `json:"onTrack"`
+}
+
+// GoalStreak counts consecutive days on which every daily target was met
+type GoalStreak struct {
+	Current     int        `json:"current"`
+	Longest     int        `json:"longest"`
+	LastHitDate *time.Time `json:"lastHitDate,omitempty"`
+}
+
+type GoalsResponse struct {
+	Goals    EarningsGoals  `json:"goals"`
+	Progress []GoalProgress `json:"progress"`
+	Streak   GoalStreak     `json:"streak"`
+}
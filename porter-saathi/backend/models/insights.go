@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// Forecast confidence, from how much history the driver has
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// ForecastDay is one day of the week: the actual figure for days gone by and a
+// forecast with an 80% band for today and the days ahead
+type ForecastDay struct {
+	Date          time.Time `json:"date"`
+	DayName       string    `json:"dayName"`
+	IsForecast    bool      `json:"isForecast"`
+	Actual        *float64  `json:"actual,omitempty"`
+	Expected      float64   `json:"expected"`
+	Low           float64   `json:"low"`
+	High          float64   `json:"high"`
+	ExpectedTrips float64   `json:"expectedTrips"`
+}
+
+// WeekForecast projects the current week's net earnings from what is already earned plus the forecast
+type WeekForecast struct {
+	Start          time.Time     `json:"start"`
+	End            time.Time     `json:"end"`
+	ActualSoFar    float64       `json:"actualSoFar"`
+	Projected      float64       `json:"projected"`
+	Low            float64       `json:"low"`
+	High           float64       `json:"high"`
+	ProjectedTrips int           `json:"projectedTrips"`
+	Days           []ForecastDay `json:"days"`
+}
+
+// WeekdayInsight is how a day of the week has gone over the history window
+type WeekdayInsight struct {
+	Weekday    string `json:"weekday"`
+	DaysWorked int    `json:"daysWorked"`
+	// WorkRate is the share of these weekdays with any earnings recorded
+	WorkRate           float64 `json:"workRate"`
+	AverageNetEarnings float64 `json:"averageNetEarnings"`
+	AverageTrips       float64 `json:"averageTrips"`
+}
+
+// HourInsight is trip income in one hour of the day, from trips with a start time
+type HourInsight struct {
+	Hour              int     `json:"hour"`
+	Label             string  `json:"label"`
+	Trips             int     `json:"trips"`
+	AverageNetPerTrip float64 `json:"averageNetPerTrip"`
+	// NetPerDay is the hour's trip income on an average day the driver worked it
+	NetPerDay float64 `json:"netPerDay"`
+}
+
+type EarningsInsights struct {
+	GeneratedAt   time.Time        `json:"generatedAt"`
+	HistoryFrom   time.Time        `json:"historyFrom"`
+	DaysWorked    int              `json:"daysWorked"`
+	Confidence    string           `json:"confidence"`
+	DailyBaseline float64          `json:"dailyBaseline"`
+	Week          WeekForecast     `json:"week"`
+	Weekdays      []WeekdayInsight `json:"weekdays"`
+	BestDays      []string         `json:"bestDays"`
+	BestHours     []HourInsight    `json:"bestHours"`
+}
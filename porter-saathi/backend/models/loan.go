@@ -0,0 +1,169 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Loan payment types. A prepayment goes straight to principal and never counts towards an EMI.
+const (
+	LoanPaymentEMI        = "emi"
+	LoanPaymentPrepayment = "prepayment"
+)
+
+// EMI due statuses
+const (
+	DueStatusPaid     = "paid"
+	DueStatusDue      = "due"
+	DueStatusMissed   = "missed"
+	DueStatusUpcoming = "upcoming"
+)
+
+// Prepayment simulation modes
+const (
+	PrepayReduceTenure = "reduce_tenure"
+	PrepayReduceEMI    = "reduce_emi"
+)
+
+// Loan is a vehicle loan repaid in equal monthly installments
+type Loan struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"userId"`
+	Lender        string             `bson:"lender" json:"lender"`
+	VehicleNumber string             `bson:"vehicle_number,omitempty" json:"vehicleNumber,omitempty"`
+	Principal     float64            `bson:"principal" json:"principal"`
+	// AnnualRate is the yearly interest rate in percent, reducing balance
+	AnnualRate   float64   `bson:"annual_rate" json:"annualRate"`
+	TenureMonths int       `bson:"tenure_months" json:"tenureMonths"`
+	EMI          float64   `bson:"emi" json:"emi"`
+	EMIDay       int       `bson:"emi_day" json:"emiDay"`
+	DisbursedOn  time.Time `bson:"disbursed_on" json:"disbursedOn"`
+	FirstEMIDate time.Time `bson:"first_emi_date" json:"firstEmiDate"`
+	CreatedAt    time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// LoanRequest creates or corrects a loan. The EMI is calculated unless the lender's figure is given.
+type LoanRequest struct {
+	Lender        string  `json:"lender" binding:"required,max=100"`
+	VehicleNumber string  `json:"vehicleNumber" binding:"max=20"`
+	Principal     float64 `json:"principal" binding:"gt=0,lte=100000000"`
+	AnnualRate    float64 `json:"annualRate" binding:"gte=0,lte=60"`
+	TenureMonths  int     `json:"tenureMonths" binding:"gte=1,lte=120"`
+	EMIDay        int     `json:"emiDay" binding:"gte=1,lte=31"`
+	DisbursedOn   string  `json:"disbursedOn" binding:"required"`
+	// FirstEMIDate defaults to the EMI day in the month after disbursal
+	FirstEMIDate string  `json:"firstEmiDate"`
+	EMI          float64 `json:"emi" binding:"gte=0"`
+}
+
+// LoanPayment is money paid towards a loan
+type LoanPayment struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LoanID    primitive.ObjectID `bson:"loan_id" json:"loanId"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	Date      time.Time          `bson:"date" json:"date"`
+	Amount    float64            `bson:"amount" json:"amount"`
+	Type      string             `bson:"type" json:"type"`
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+type LoanPaymentRequest struct {
+	Date   string  `json:"date" binding:"required"`
+	Amount float64 `json:"amount" binding:"gt=0,lte=100000000"`
+	// Type defaults to emi
+	Type string `json:"type" binding:"omitempty,oneof=emi prepayment"`
+	Note string `json:"note" binding:"max=200"`
+}
+
+// AmortizationRow is one installment of a repayment schedule
+type AmortizationRow struct {
+	Number    int       `json:"number"`
+	DueDate   time.Time `json:"dueDate"`
+	EMI       float64   `json:"emi"`
+	Interest  float64   `json:"interest"`
+	Principal float64   `json:"principal"`
+	Balance   float64   `json:"balance"`
+	// Status is only set on installments already due
+	Status string `json:"status,omitempty"`
+}
+
+// LoanDue is one EMI that has fallen due or soon will
+type LoanDue struct {
+	LoanID      primitive.ObjectID `json:"loanId"`
+	Lender      string             `json:"lender"`
+	Number      int                `json:"number"`
+	DueDate     time.Time          `json:"dueDate"`
+	Amount      float64            `json:"amount"`
+	Paid        float64            `json:"paid"`
+	Shortfall   float64            `json:"shortfall"`
+	Status      string             `json:"status"`
+	DaysOverdue int                `json:"daysOverdue,omitempty"`
+}
+
+// LoanSummary is where a loan stands today, from the payments logged against it
+type LoanSummary struct {
+	Loan                  Loan       `json:"loan"`
+	Closed                bool       `json:"closed"`
+	OutstandingPrincipal  float64    `json:"outstandingPrincipal"`
+	UnpaidInterest        float64    `json:"unpaidInterest"`
+	PrincipalPaid         float64    `json:"principalPaid"`
+	InterestPaid          float64    `json:"interestPaid"`
+	TotalPaid             float64    `json:"totalPaid"`
+	InstallmentsDue       int        `json:"installmentsDue"`
+	InstallmentsPaid      int        `json:"installmentsPaid"`
+	RemainingInstallments int        `json:"remainingInstallments"`
+	ExpectedCloseDate     *time.Time `json:"expectedCloseDate,omitempty"`
+	NextDue               *LoanDue   `json:"nextDue,omitempty"`
+	Missed                []LoanDue  `json:"missed"`
+	MissedAmount          float64    `json:"missedAmount"`
+}
+
+type LoanDetailResponse struct {
+	LoanSummary
+	Schedule []AmortizationRow `json:"schedule"`
+	Payments []LoanPayment     `json:"payments"`
+}
+
+type PrepaymentRequest struct {
+	Amount float64 `json:"amount" binding:"gt=0,lte=100000000"`
+	// Mode defaults to reduce_tenure, keeping the EMI and closing the loan sooner
+	Mode string `json:"mode" binding:"omitempty,oneof=reduce_tenure reduce_emi"`
+}
+
+// PrepaymentSimulation compares the rest of a loan with and without a prepayment made today
+type PrepaymentSimulation struct {
+	Mode                 string            `json:"mode"`
+	Amount               float64           `json:"amount"`
+	OutstandingPrincipal float64           `json:"outstandingPrincipal"`
+	CurrentEMI           float64           `json:"currentEmi"`
+	CurrentInstallments  int               `json:"currentInstallments"`
+	CurrentInterest      float64           `json:"currentInterest"`
+	NewEMI               float64           `json:"newEmi"`
+	NewInstallments      int               `json:"newInstallments"`
+	NewInterest          float64           `json:"newInterest"`
+	InterestSaved        float64           `json:"interestSaved"`
+	InstallmentsSaved    int               `json:"installmentsSaved"`
+	NewCloseDate         *time.Time        `json:"newCloseDate,omitempty"`
+	Schedule             []AmortizationRow `json:"schedule"`
+}
+
+type LoanDuesResponse struct {
+	Upcoming     []LoanDue `json:"upcoming"`
+	Missed       []LoanDue `json:"missed"`
+	MissedAmount float64   `json:"missedAmount"`
+}
+
+// EarningsLoans folds loan repayments into an earnings response. EMI logged as an
+// "emi" expense is already in NetEarnings, so only loan payments beyond it are taken off.
+type EarningsLoans struct {
+	LoanPayments          float64   `json:"loanPayments"`
+	NetAfterLoans         float64   `json:"netAfterLoans"`
+	PreviousLoanPayments  float64   `json:"previousLoanPayments"`
+	PreviousNetAfterLoans float64   `json:"previousNetAfterLoans"`
+	Upcoming              []LoanDue `json:"upcoming"`
+	Missed                []LoanDue `json:"missed"`
+	MissedAmount          float64   `json:"missedAmount"`
+}
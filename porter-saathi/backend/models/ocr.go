@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Decisions returned with every OCR result
+const (
+	OCRDecisionAutoAccept = "auto_accept"
+	OCRDecisionConfirm    = "confirm"
+	OCRDecisionReject     = "reject"
+)
+
+// What happened to the card address on the driver's profile
+const (
+	AddressStatusSaved               = "saved"
+	AddressStatusPendingConfirmation = "pending_confirmation"
+)
+
+// OCR job lifecycle
+const (
+	OCRJobQueued     = "queued"
+	OCRJobProcessing = "processing"
+	OCRJobCompleted  = "completed"
+	OCRJobDead       = "dead" // retries exhausted
+)
+
+// OCRRequest represents the request structure for OCR processing
+type OCRRequest struct {
+	ImageData string `json:"imageData"`
+	ImageType string `json:"imageType"`
+	// DocumentType refers to an already uploaded documents.<type> entry instead of ImageData
+	DocumentType string `json:"documentType"`
+	// Expected carries what the driver typed during signup, before an account exists
+	Expected *OCRExpectedFields `json:"expected"`
+	// CallbackURL is notified with the finished job when processing asynchronously
+	CallbackURL string `json:"callbackUrl"`
+}
+
+// OCRExpectedFields are the driver-entered values to cross-check against the card
+type OCRExpectedFields struct {
+	Name         string `bson:"name,omitempty" json:"name"`
+	AadharNumber string `bson:"aadhar_number,omitempty" json:"aadharNumber"`
+	DateOfBirth  string `bson:"date_of_birth,omitempty" json:"dateOfBirth"`
+}
+
+// OCRResponse represents the response structure for OCR processing
+type OCRResponse struct {
+	Success       bool                       `bson:"success" json:"success"`
+	Message       string                     `bson:"message" json:"message"`
+	ExtractedData map[string]interface{}     `bson:"extracted_data,omitempty" json:"extractedData,omitempty"`
+	Confidence    int                        `bson:"confidence,omitempty" json:"confidence,omitempty"`
+	FieldScores   map[string]FieldConfidence `bson:"field_scores,omitempty" json:"fieldScores,omitempty"`
+	Decision      string                     `bson:"decision,omitempty" json:"decision,omitempty"` // auto_accept, confirm or reject
+	RawText       string                     `bson:"raw_text,omitempty" json:"rawText,omitempty"`
+	Source        string                     `bson:"source,omitempty" json:"source,omitempty"` // "qr", "ocr" or "simulated" when no OCR engine read the image
+	MatchReport   *AadhaarMatchReport        `bson:"match_report,omitempty" json:"matchReport,omitempty"`
+	Address       *Address                   `bson:"address,omitempty" json:"address,omitempty"`
+	AddressStatus string                     `bson:"address_status,omitempty" json:"addressStatus,omitempty"` // saved or pending_confirmation
+}
+
+// BoundingBox locates a field on the uploaded image, in pixels
+type BoundingBox struct {
+	X      int `bson:"x" json:"x"`
+	Y      int `bson:"y" json:"y"`
+	Width  int `bson:"width" json:"width"`
+	Height int `bson:"height" json:"height"`
+}
+
+// OCRWord is a single word recognised by the OCR engine
+type OCRWord struct {
+	Text       string      `json:"text"`
+	Confidence float64     `json:"confidence"`
+	Box        BoundingBox `json:"box"`
+}
+
+// FieldConfidence explains how sure we are about one extracted field
+type FieldConfidence struct {
+	Value       string       `bson:"value" json:"value"`
+	Score       float64      `bson:"score" json:"score"`
+	Engine      float64      `bson:"engine" json:"engine"`                           // character confidence from the OCR engine
+	Valid       bool         `bson:"valid" json:"valid"`                             // checksum / format check
+	Agreement   *float64     `bson:"agreement,omitempty" json:"agreement,omitempty"` // English vs regional text on the card
+	Plausible   bool         `bson:"plausible" json:"plausible"`
+	Issues      []string     `bson:"issues,omitempty" json:"issues,omitempty"`
+	BoundingBox *BoundingBox `bson:"bounding_box,omitempty" json:"boundingBox,omitempty"`
+}
+
+// OCRJob is an Aadhaar OCR request queued for background processing
+type OCRJob struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       *primitive.ObjectID `bson:"user_id,omitempty" json:"userId,omitempty"`
+	TokenHash    string              `bson:"token_hash,omitempty" json:"-"` // SHA-256 of the read token for anonymous jobs
+	Status       string              `bson:"status" json:"status"`
+	Image        []byte              `bson:"image,omitempty" json:"-"`
+	Expected     *OCRExpectedFields  `bson:"expected,omitempty" json:"-"`
+	CallbackURL  string              `bson:"callback_url,omitempty" json:"-"`
+	DocumentType string              `bson:"document_type,omitempty" json:"documentType,omitempty"`
+	DocumentFile string              `bson:"document_file,omitempty" json:"-"`
+	Attempts     int                 `bson:"attempts" json:"attempts"`
+	MaxAttempts  int                 `bson:"max_attempts" json:"maxAttempts"`
+	LastError    string              `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	Result       *OCRResponse        `bson:"result,omitempty" json:"result,omitempty"`
+	AvailableAt  time.Time           `bson:"available_at" json:"-"`
+	LeaseOwner   string              `bson:"lease_owner,omitempty" json:"-"` // set per claim; the final update must match it
+	LeaseUntil   *time.Time          `bson:"lease_until,omitempty" json:"-"`
+	CreatedAt    time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updatedAt"`
+	CompletedAt  *time.Time          `bson:"completed_at,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt    time.Time           `bson:"expires_at" json:"expiresAt"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statement is the signed record behind a PDF earnings statement, kept so that
+// anyone scanning the statement's QR code can check it against the server
+type Statement struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"userId"`
+	From        string             `bson:"from" json:"from"`
+	To          string             `bson:"to" json:"to"`
+	Language    string             `bson:"language" json:"language"`
+	Revenue     float64            `bson:"revenue" json:"revenue"`
+	Expenses    float64            `bson:"expenses" json:"expenses"`
+	NetEarnings float64            `bson:"net_earnings" json:"netEarnings"`
+	Trips       int                `bson:"trips" json:"trips"`
+	Months      []StatementMonth   `bson:"months" json:"months"`
+	// Signature is an Ed25519 signature over the statement's canonical payload
+	PayloadHash string `bson:"payload_hash" json:"payloadHash"`
+	Signature   string `bson:"signature" json:"signature"`
+	KeyID       string `bson:"key_id" json:"keyId"`
+	// TokenHash is the SHA-256 of the random token in the QR code's link, which is
+	// needed to verify the statement
+	TokenHash string    `bson:"token_hash" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"createdAt"`
+}
+
+// StatementMonth is one month's totals on a statement
+type StatementMonth struct {
+	Month       string  `bson:"month" json:"month"` // YYYY-MM
+	Revenue     float64 `bson:"revenue" json:"revenue"`
+	Expenses    float64 `bson:"expenses" json:"expenses"`
+	NetEarnings float64 `bson:"net_earnings" json:"netEarnings"`
+	Trips       int     `bson:"trips" json:"trips"`
+}
+
+// StatementVerification is what a bank or verifier sees after scanning the QR code
+type StatementVerification struct {
+	Valid       bool             `json:"valid"`
+	StatementID string           `json:"statementId"`
+	DriverName  string           `json:"driverName"`
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Revenue     float64          `json:"revenue"`
+	Expenses    float64          `json:"expenses"`
+	NetEarnings float64          `json:"netEarnings"`
+	Trips       int              `json:"trips"`
+	Months      []StatementMonth `json:"months"`
+	KeyID       string           `json:"keyId"`
+	IssuedAt    time.Time        `json:"issuedAt"`
+}
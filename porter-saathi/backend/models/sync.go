@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// Sync operation types
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// Sync operation results
+const (
+	SyncApplied   = "applied"
+	SyncDuplicate = "duplicate"
+	SyncConflict  = "conflict"
+	SyncNotFound  = "not_found"
+	SyncRejected  = "rejected"
+)
+
+// SyncOperation is one change queued on the device while offline. The entry is
+// named by its server id or, for entries never synced, by its clientId.
+type SyncOperation struct {
+	OpID            string           `json:"opId" binding:"required,max=100"`
+	Type            string           `json:"type" binding:"required,oneof=create update delete"`
+	ID              string           `json:"id"`
+	ClientID        string           `json:"clientId" binding:"max=100"`
+	ClientTimestamp time.Time        `json:"clientTimestamp" binding:"required"`
+	Data            *EarningsRequest `json:"data"`
+	Reason          string           `json:"reason"`
+}
+
+type SyncRequest struct {
+	// Cursor is the value returned by the previous sync, 0 for a full download
+	Cursor     int64           `json:"cursor" binding:"gte=0"`
+	Operations []SyncOperation `json:"operations" binding:"max=200,dive"`
+}
+
+type SyncResult struct {
+	OpID     string    `json:"opId"`
+	Status   string    `json:"status"`
+	ID       string    `json:"id,omitempty"`
+	ClientID string    `json:"clientId,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Earnings *Earnings `json:"earnings,omitempty"`
+}
+
+type SyncResponse struct {
+	Results []SyncResult `json:"results"`
+	// Changes are every entry written since the request's cursor, deleted ones included
+	Changes []Earnings `json:"changes"`
+	Cursor  int64      `json:"cursor"`
+	HasMore bool       `json:"hasMore"`
+}
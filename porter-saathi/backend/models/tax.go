@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Basis of the income a tax estimate is worked out on
+const (
+	TaxBasisPresumptive = "presumptive"
+	TaxBasisActual      = "actual"
+)
+
+// Advance tax installment states
+const (
+	InstallmentPast     = "past"
+	InstallmentUpcoming = "upcoming"
+)
+
+// TaxVehicle is a goods carriage the driver owns, with what Section 44AE needs to know about it
+type TaxVehicle struct {
+	VehicleNumber        string     `bson:"vehicle_number" json:"vehicleNumber"`
+	GrossVehicleWeightKg float64    `bson:"gross_vehicle_weight_kg" json:"grossVehicleWeightKg"`
+	OwnedFrom            time.Time  `bson:"owned_from" json:"ownedFrom"`
+	OwnedTo              *time.Time `bson:"owned_to,omitempty" json:"ownedTo,omitempty"`
+}
+
+// TaxProfile holds the driver's goods carriages, one document per driver
+type TaxProfile struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	Vehicles  []TaxVehicle       `bson:"vehicles" json:"vehicles"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+type TaxVehicleRequest struct {
+	VehicleNumber        string  `json:"vehicleNumber" binding:"required,max=20"`
+	GrossVehicleWeightKg float64 `json:"grossVehicleWeightKg" binding:"gt=0,lte=100000"`
+	OwnedFrom            string  `json:"ownedFrom" binding:"required"`
+	OwnedTo              string  `json:"ownedTo"`
+}
+
+type TaxProfileRequest struct {
+	Vehicles []TaxVehicleRequest `json:"vehicles" binding:"max=50,dive"`
+}
+
+// VehiclePresumptiveIncome is one vehicle's deemed income for the year
+type VehiclePresumptiveIncome struct {
+	VehicleNumber        string  `json:"vehicleNumber"`
+	GrossVehicleWeightKg float64 `json:"grossVehicleWeightKg"`
+	HeavyGoodsVehicle    bool    `json:"heavyGoodsVehicle"`
+	MonthsOwned          int     `json:"monthsOwned"`
+	RatePerMonth         float64 `json:"ratePerMonth"`
+	Income               float64 `json:"income"`
+}
+
+// SlabTax is the tax charged on the part of income falling in one slab
+type SlabTax struct {
+	From float64  `json:"from"`
+	UpTo *float64 `json:"upTo,omitempty"`
+	Rate float64  `json:"rate"`
+	Tax  float64  `json:"tax"`
+}
+
+type TaxComputation struct {
+	TaxableIncome   float64   `json:"taxableIncome"`
+	Slabs           []SlabTax `json:"slabs"`
+	TaxBeforeRebate float64   `json:"taxBeforeRebate"`
+	Rebate          float64   `json:"rebate"`
+	Cess            float64   `json:"cess"`
+	TotalTax        float64   `json:"totalTax"`
+}
+
+// AdvanceTaxInstallment is one due date with how much should have been paid by then
+type AdvanceTaxInstallment struct {
+	DueDate           time.Time `json:"dueDate"`
+	CumulativePercent float64   `json:"cumulativePercent"`
+	CumulativeAmount  float64   `json:"cumulativeAmount"`
+	Amount            float64   `json:"amount"`
+	Status            string    `json:"status"`
+}
+
+// TaxEstimate compares Section 44AE presumptive income with actual net earnings for a financial year
+type TaxEstimate struct {
+	FinancialYear string    `json:"financialYear"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	InProgress    bool      `json:"inProgress"`
+	RulesYear     string    `json:"rulesYear"`
+	// Eligible is false when the driver owned more goods carriages at once than 44AE allows
+	Eligible          bool                       `json:"eligible"`
+	MaxVehiclesOwned  int                        `json:"maxVehiclesOwned"`
+	Notes             []string                   `json:"notes"`
+	Vehicles          []VehiclePresumptiveIncome `json:"vehicles"`
+	PresumptiveIncome float64                    `json:"presumptiveIncome"`
+	// Actual net earnings so far, and for a year in progress the full year at the current pace
+	ActualNetEarnings    float64                 `json:"actualNetEarnings"`
+	ProjectedNetEarnings float64                 `json:"projectedNetEarnings"`
+	DeclaredIncome       float64                 `json:"declaredIncome"`
+	Basis                string                  `json:"basis"`
+	Tax                  TaxComputation          `json:"tax"`
+	AdvanceTaxApplies    bool                    `json:"advanceTaxApplies"`
+	AdvanceTax           []AdvanceTaxInstallment `json:"advanceTax"`
+	Months               []EarningsBucket        `json:"months"`
+}
+
+// TaxYearSummary is one line of the year-by-year overview
+type TaxYearSummary struct {
+	FinancialYear     string  `json:"financialYear"`
+	ActualNetEarnings float64 `json:"actualNetEarnings"`
+	PresumptiveIncome float64 `json:"presumptiveIncome"`
+	DeclaredIncome    float64 `json:"declaredIncome"`
+	Basis             string  `json:"basis"`
+	EstimatedTax      float64 `json:"estimatedTax"`
+}
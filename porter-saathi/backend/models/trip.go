@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment modes a trip can be settled in
+const (
+	PaymentModeCash   = "cash"
+	PaymentModeUPI    = "upi"
+	PaymentModeWallet = "wallet"
+)
+
+// Trip is a single delivery. Daily Earnings documents with source "trips" are rolled up from these.
+type Trip struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID             primitive.ObjectID `bson:"user_id" json:"userId"`
+	Date               time.Time          `bson:"date" json:"date"`
+	StartTime          *time.Time         `bson:"start_time,omitempty" json:"startTime,omitempty"`
+	PickupArea         string             `bson:"pickup_area" json:"pickupArea"`
+	DropArea           string             `bson:"drop_area" json:"dropArea"`
+	DistanceKm         float64            `bson:"distance_km" json:"distanceKm"`
+	DurationMinutes    int                `bson:"duration_minutes" json:"durationMinutes"`
+	Fare               float64            `bson:"fare" json:"fare"`
+	PlatformCommission float64            `bson:"platform_commission" json:"platformCommission"`
+	Tips               float64            `bson:"tips" json:"tips"`
+	WaitingCharges     float64            `bson:"waiting_charges" json:"waitingCharges"`
+	PaymentMode        string             `bson:"payment_mode" json:"paymentMode"`
+	// Derived on every write
+	GrossAmount  float64   `bson:"gross_amount" json:"grossAmount"`
+	NetAmount    float64   `bson:"net_amount" json:"netAmount"`
+	EarningPerKm float64   `bson:"earning_per_km" json:"earningPerKm"`
+	CreatedAt    time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+type TripRequest struct {
+	Date               string  `json:"date" binding:"required"`
+	StartTime          string  `json:"startTime"`
+	PickupArea         string  `json:"pickupArea" binding:"required"`
+	DropArea           string  `json:"dropArea" binding:"required"`
+	DistanceKm         float64 `json:"distanceKm" binding:"gte=0,lte=2000"`
+	DurationMinutes    int     `json:"durationMinutes" binding:"gte=0,lte=1440"`
+	Fare               float64 `json:"fare" binding:"gte=0,lte=100000"`
+	PlatformCommission float64 `json:"platformCommission" binding:"gte=0,lte=100000"`
+	Tips               float64 `json:"tips" binding:"gte=0,lte=100000"`
+	WaitingCharges     float64 `json:"waitingCharges" binding:"gte=0,lte=100000"`
+	PaymentMode        string  `json:"paymentMode" binding:"required,oneof=cash upi wallet"`
+}
+
+type TripListResponse struct {
+	Trips   []Trip          `json:"trips"`
+	Summary EarningsSummary `json:"summary"`
+}
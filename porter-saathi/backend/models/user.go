@@ -7,18 +7,29 @@ import (
 )
 
 type User struct {
-	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Mobile            string             `bson:"mobile" json:"mobile" binding:"required"`
-	Name              string             `bson:"name" json:"name"`
-	AadharNumber      string             `bson:"aadhar_number" json:"aadharNumber"`
-	LicenseNumber     string             `bson:"license_number" json:"licenseNumber"`
-	VehicleNumber     string             `bson:"vehicle_number" json:"vehicleNumber"`
-	EmergencyContact  string             `bson:"emergency_contact" json:"emergencyContact"`
-	PreferredLanguage string             `bson:"preferred_language" json:"preferredLanguage"`
-	Documents         Documents          `bson:"documents" json:"documents"`
-	IsVerified        bool               `bson:"is_verified" json:"isVerified"`
-	CreatedAt         time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt         time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Mobile           string             `bson:"mobile" json:"mobile" binding:"required"`
+	Name             string             `bson:"name" json:"name"`
+	AadharNumber     string             `bson:"aadhar_number" json:"aadharNumber"`
+	DateOfBirth      string             `bson:"date_of_birth,omitempty" json:"dateOfBirth,omitempty"`
+	LicenseNumber    string             `bson:"license_number" json:"licenseNumber"`
+	VehicleNumber    string             `bson:"vehicle_number" json:"vehicleNumber"`
+	EmergencyContact string             `bson:"emergency_contact" json:"emergencyContact"`
+	Address          *Address           `bson:"address,omitempty" json:"address,omitempty"`
+	// PendingAddress was read from the driver's card by OCR and is saved as Address once they confirm it
+	PendingAddress    *Address `bson:"pending_address,omitempty" json:"pendingAddress,omitempty"`
+	PreferredLanguage string   `bson:"preferred_language" json:"preferredLanguage"`
+	// City is where the driver works and VehicleClass one of VehicleClasses; together they pick peers for benchmarks
+	City         string `bson:"city,omitempty" json:"city,omitempty"`
+	VehicleClass string `bson:"vehicle_class,omitempty" json:"vehicleClass,omitempty"`
+	// Timezone is an IANA zone name (IST when empty); earnings days and weeks are bucketed in it
+	Timezone     string              `bson:"timezone,omitempty" json:"timezone,omitempty"`
+	WeekStart    string              `bson:"week_start,omitempty" json:"weekStart,omitempty"`
+	Documents    Documents           `bson:"documents" json:"documents"`
+	AadhaarMatch *AadhaarMatchReport `bson:"aadhaar_match,omitempty" json:"aadhaarMatch,omitempty"`
+	IsVerified   bool                `bson:"is_verified" json:"isVerified"`
+	CreatedAt    time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updatedAt"`
 }
 
 type Documents struct {
@@ -34,6 +45,9 @@ type DocumentFile struct {
 	FileSize    int64     `bson:"file_size" json:"fileSize"`
 	ContentType string    `bson:"content_type" json:"contentType"`
 	UploadedAt  time.Time `bson:"uploaded_at" json:"uploadedAt"`
+	// Extraction is the OCR result for this file, when it has been processed
+	Extraction  *OCRResponse `bson:"extraction,omitempty" json:"extraction,omitempty"`
+	ExtractedAt *time.Time   `bson:"extracted_at,omitempty" json:"extractedAt,omitempty"`
 }
 
 type LoginRequest struct {
@@ -45,6 +59,7 @@ type SignupRequest struct {
 	Mobile           string `json:"mobile" binding:"required"`
 	Name             string `json:"name" binding:"required"`
 	AadharNumber     string `json:"aadharNumber" binding:"required"`
+	DateOfBirth      string `json:"dateOfBirth"`
 	LicenseNumber    string `json:"licenseNumber" binding:"required"`
 	VehicleNumber    string `json:"vehicleNumber" binding:"required"`
 	EmergencyContact string `json:"emergencyContact" binding:"required"`
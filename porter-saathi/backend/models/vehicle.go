@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OdometerReading is a reading the driver noted for their vehicle on a day
+type OdometerReading struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"userId"`
+	VehicleNumber string             `bson:"vehicle_number" json:"vehicleNumber"`
+	Date          time.Time          `bson:"date" json:"date"`
+	ReadingKm     float64            `bson:"reading_km" json:"readingKm"`
+	Note          string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+type OdometerRequest struct {
+	Date      string  `json:"date" binding:"required"`
+	ReadingKm float64 `json:"readingKm" binding:"gt=0,lt=10000000"`
+	Note      string  `json:"note" binding:"max=200"`
+}
+
+// FuelFillUp is one visit to the pump. The odometer reading at the fill-up is what
+// lets consecutive full-tank fill-ups be turned into km per litre.
+type FuelFillUp struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"userId"`
+	VehicleNumber string             `bson:"vehicle_number" json:"vehicleNumber"`
+	Date          time.Time          `bson:"date" json:"date"`
+	OdometerKm    float64            `bson:"odometer_km" json:"odometerKm"`
+	Litres        float64            `bson:"litres" json:"litres"`
+	PricePerLitre float64            `bson:"price_per_litre" json:"pricePerLitre"`
+	Amount        float64            `bson:"amount" json:"amount"`
+	FullTank      bool               `bson:"full_tank" json:"fullTank"`
+	Note          string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+type FuelFillUpRequest struct {
+	Date          string  `json:"date" binding:"required"`
+	OdometerKm    float64 `json:"odometerKm" binding:"gt=0,lt=10000000"`
+	Litres        float64 `json:"litres" binding:"gt=0,lte=500"`
+	PricePerLitre float64 `json:"pricePerLitre" binding:"gt=0,lte=500"`
+	// Defaults to true; partial fill-ups are carried into the next full-tank interval
+	FullTank *bool  `json:"fullTank"`
+	Note     string `json:"note" binding:"max=200"`
+}
+
+// FuelEfficiencyInterval is the driving between two full-tank fill-ups
+type FuelEfficiencyInterval struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	StartKm    float64   `json:"startKm"`
+	EndKm      float64   `json:"endKm"`
+	DistanceKm float64   `json:"distanceKm"`
+	Litres     float64   `json:"litres"`
+	FuelCost   float64   `json:"fuelCost"`
+	KmPerLitre float64   `json:"kmPerLitre"`
+	CostPerKm  float64   `json:"costPerKm"`
+	// Baseline is the median of the preceding intervals; a drop well below it is flagged
+	BaselineKmPerLitre *float64 `json:"baselineKmPerLitre,omitempty"`
+	DropPercentage     *float64 `json:"dropPercentage,omitempty"`
+	Flagged            bool     `json:"flagged"`
+}
+
+// VehicleMetrics are the running costs for one period. Per-km figures are omitted
+// when no distance was recorded.
+type VehicleMetrics struct {
+	Start            time.Time                `json:"start"`
+	End              time.Time                `json:"end"`
+	Label            string                   `json:"label,omitempty"`
+	DistanceKm       float64                  `json:"distanceKm"`
+	FuelLitres       float64                  `json:"fuelLitres"`
+	FuelCost         float64                  `json:"fuelCost"`
+	MaintenanceCost  float64                  `json:"maintenanceCost"`
+	NetEarnings      float64                  `json:"netEarnings"`
+	KmPerLitre       *float64                 `json:"kmPerLitre,omitempty"`
+	RunningCostPerKm *float64                 `json:"runningCostPerKm,omitempty"` // fuel plus maintenance
+	NetEarningPerKm  *float64                 `json:"netEarningPerKm,omitempty"`
+	Alerts           []FuelEfficiencyInterval `json:"alerts,omitempty"`
+}
+
+type VehicleEfficiencyResponse struct {
+	VehicleNumber string                   `json:"vehicleNumber"`
+	Granularity   string                   `json:"granularity"`
+	Periods       []VehicleMetrics         `json:"periods"`
+	Intervals     []FuelEfficiencyInterval `json:"intervals"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Match statuses for a single field and for the report as a whole
+const (
+	MatchStatusMatch    = "match"
+	MatchStatusPartial  = "partial"
+	MatchStatusMismatch = "mismatch"
+	MatchStatusMissing  = "missing"
+
+	VerificationVerified    = "verified"
+	VerificationNeedsReview = "needs_review"
+	VerificationMismatch    = "mismatch"
+)
+
+// AadhaarMatchReport compares what was read from the Aadhaar card with what the driver entered
+type AadhaarMatchReport struct {
+	Status    string       `bson:"status" json:"status"`
+	Source    string       `bson:"source" json:"source"` // "qr", "ocr" or "simulated"
+	Fields    []FieldMatch `bson:"fields" json:"fields"`
+	CheckedAt time.Time    `bson:"checked_at" json:"checkedAt"`
+}
+
+// FieldMatch is the outcome of comparing one field
+type FieldMatch struct {
+	Field     string  `bson:"field" json:"field"`
+	Entered   string  `bson:"entered" json:"entered"`
+	Extracted string  `bson:"extracted" json:"extracted"`
+	Score     float64 `bson:"score" json:"score"`
+	Status    string  `bson:"status" json:"status"`
+	Reason    string  `bson:"reason,omitempty" json:"reason,omitempty"`
+	Action    string  `bson:"action,omitempty" json:"action,omitempty"` // what the driver can do to fix it
+}
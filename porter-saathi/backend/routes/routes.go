@@ -33,7 +33,9 @@ func SetupRoutes(r *gin.Engine) {
 		ocr := v1.Group("/ocr")
 		{
 			ocr.GET("/status", controllers.GetOCRStatus)
-			ocr.POST("/process-aadhaar", controllers.ProcessAadhaarOCR)
+			ocr.POST("/process-aadhaar", middleware.OptionalAuthMiddleware(), controllers.ProcessAadhaarOCR)
+			ocr.POST("/jobs", middleware.OptionalAuthMiddleware(), controllers.SubmitOCRJob)
+			ocr.GET("/jobs/:id", middleware.OptionalAuthMiddleware(), controllers.GetOCRJob)
 		}
 
 		// Empowerment routes (public for accessibility)
@@ -43,6 +45,10 @@ func SetupRoutes(r *gin.Engine) {
 			empowerment.POST("/query", controllers.ProcessEmpowermentQuery)
 		}
 
+		// Statement verification (public so banks can check a statement's QR code)
+		v1.GET("/statements/public-key", controllers.GetStatementPublicKey)
+		v1.GET("/statements/:id/verify", controllers.VerifyStatement)
+
 		// Protected routes (authentication required)
 		protected := v1.Group("/")
 		protected.Use(middleware.AuthMiddleware())
@@ -53,6 +59,7 @@ func SetupRoutes(r *gin.Engine) {
 				user.GET("/profile", controllers.GetUserProfile)
 				user.PUT("/profile", controllers.UpdateUserProfile)
 				user.POST("/upload-document", controllers.UploadDocument)
+				user.POST("/address/confirm", controllers.ConfirmAadhaarAddress)
 			}
 
 			// Earnings routes
@@ -60,13 +67,93 @@ func SetupRoutes(r *gin.Engine) {
 			{
 				earnings.GET("/", controllers.GetEarnings)
 				earnings.GET("/weekly", controllers.GetWeeklyEarnings)
+				earnings.GET("/summary", controllers.GetEarningsSummary)
+				earnings.GET("/statement", controllers.GetEarningsStatement)
+				earnings.GET("/insights", controllers.GetEarningsInsights)
+				earnings.GET("/benchmarks", controllers.GetEarningsBenchmarks)
+				earnings.GET("/goals", controllers.GetEarningsGoals)
+				earnings.PUT("/goals", controllers.UpdateEarningsGoals)
 				earnings.POST("/", controllers.AddEarnings)
+				earnings.POST("/sync", controllers.SyncEarnings)
+				earnings.GET("/flagged", controllers.GetFlaggedEarnings)
+				earnings.GET("/:id", controllers.GetEarningsByID)
+				earnings.PUT("/:id", controllers.UpdateEarnings)
+				earnings.DELETE("/:id", controllers.DeleteEarnings)
+				earnings.GET("/:id/history", controllers.GetEarningsHistory)
+				earnings.POST("/:id/confirm", controllers.ConfirmEarnings)
+				earnings.POST("/:id/restore", controllers.RestoreEarnings)
+			}
+
+			// Vehicle running costs for the driver's registered vehicle
+			vehicle := protected.Group("/vehicle")
+			{
+				vehicle.GET("/odometer", controllers.GetOdometerReadings)
+				vehicle.POST("/odometer", controllers.AddOdometerReading)
+				vehicle.DELETE("/odometer/:id", controllers.DeleteOdometerReading)
+				vehicle.GET("/fuel", controllers.GetFuelFillUps)
+				vehicle.POST("/fuel", controllers.AddFuelFillUp)
+				vehicle.DELETE("/fuel/:id", controllers.DeleteFuelFillUp)
+				vehicle.GET("/efficiency", controllers.GetVehicleEfficiency)
+			}
+
+			// Vehicle loans, EMI payments and dues
+			loans := protected.Group("/loans")
+			{
+				loans.GET("/", controllers.GetLoans)
+				loans.POST("/", controllers.AddLoan)
+				loans.GET("/dues", controllers.GetLoanDues)
+				loans.GET("/:id", controllers.GetLoan)
+				loans.PUT("/:id", controllers.UpdateLoan)
+				loans.DELETE("/:id", controllers.DeleteLoan)
+				loans.POST("/:id/payments", controllers.AddLoanPayment)
+				loans.DELETE("/:id/payments/:paymentId", controllers.DeleteLoanPayment)
+				loans.POST("/:id/prepayment", controllers.SimulatePrepayment)
+			}
+
+			// Income tax estimates under Section 44AE
+			tax := protected.Group("/tax")
+			{
+				tax.GET("/profile", controllers.GetTaxProfile)
+				tax.PUT("/profile", controllers.UpdateTaxProfile)
+				tax.GET("/estimate", controllers.GetTaxEstimate)
+				tax.GET("/years", controllers.GetTaxYears)
+			}
+
+			// Fleets: owners manage vehicles and invite drivers, drivers answer invites and see what is shared
+			fleet := protected.Group("/fleet")
+			{
+				fleet.POST("/", controllers.CreateFleet)
+				fleet.GET("/", controllers.GetFleet)
+				fleet.PUT("/", controllers.UpdateFleet)
+				fleet.GET("/dashboard", controllers.GetFleetDashboard)
+				fleet.POST("/vehicles", controllers.AddFleetVehicle)
+				fleet.PUT("/vehicles/:id", controllers.UpdateFleetVehicle)
+				fleet.DELETE("/vehicles/:id", controllers.DeleteFleetVehicle)
+				fleet.PUT("/vehicles/:id/driver", controllers.AssignFleetVehicle)
+				fleet.POST("/drivers", controllers.InviteFleetDriver)
+				fleet.DELETE("/drivers/:id", controllers.RemoveFleetDriver)
+				fleet.GET("/invites", controllers.GetFleetInvites)
+				fleet.POST("/invites/:id/accept", controllers.AcceptFleetInvite)
+				fleet.POST("/invites/:id/decline", controllers.DeclineFleetInvite)
+				fleet.GET("/membership", controllers.GetFleetMembership)
+				fleet.DELETE("/membership", controllers.LeaveFleet)
+			}
+
+			// Trip routes; each change refreshes the day's earnings rollup
+			trips := protected.Group("/trips")
+			{
+				trips.GET("/", controllers.GetTrips)
+				trips.POST("/", controllers.AddTrip)
+				trips.GET("/:id", controllers.GetTripByID)
+				trips.PUT("/:id", controllers.UpdateTrip)
+				trips.DELETE("/:id", controllers.DeleteTrip)
 			}
 
 			// Chat routes
 			chat := protected.Group("/chat")
 			{
 				chat.POST("/message", controllers.SendMessage)
+				chat.POST("/message/stream", controllers.StreamMessage)
 				chat.GET("/history/:sessionId", controllers.GetChatHistory)
 			}
 
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/llm"
+	"porter-saathi-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// summaryMaxTokens caps the length of a session summary
+const summaryMaxTokens = 400
+
+// ChatHistory turns a session into role-structured turns for the model: the newest
+// messages that fit in budget tokens, oldest first. The last message, the question
+// being asked, is always included. Turns before the window are covered by the
+// session's summary once SummarizeChatSession has caught up with them.
+func ChatHistory(session models.ChatSession, budget int) []llm.Message {
+	messages := unsummarizedMessages(session)
+	window := messages[historyWindowStart(messages, budget):]
+
+	turns := make([]llm.Message, 0, len(window))
+	for _, message := range window {
+		// The conversation sent to the model starts with the driver
+		if len(turns) == 0 && message.Sender != "user" {
+			continue
+		}
+		turns = append(turns, chatTurn(message))
+	}
+	return turns
+}
+
+// SummarizeChatSession folds older turns into the session's summary once the turns
+// after it no longer fit in the history budget, keeping the newest half of the budget
+// word for word so the summary is not rewritten on every turn. It is meant to run in
+// the background after a turn is saved; the summary is only stored if no other
+// summary was written in the meantime.
+func SummarizeChatSession(session models.ChatSession, settings llm.SessionConfig) {
+	messages := unsummarizedMessages(session)
+	if historyTokens(messages) <= settings.HistoryTokens {
+		return
+	}
+	keepFrom := historyWindowStart(messages, settings.HistoryTokens/2)
+	// Fold a reply in with its question rather than leave it to open the window
+	for keepFrom < len(messages)-1 && messages[keepFrom].Sender != "user" {
+		keepFrom++
+	}
+	if keepFrom == 0 {
+		return
+	}
+
+	client, err := llm.Default()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), settings.Timeout)
+	defer cancel()
+
+	temperature := 0.2
+	request := llm.Request{
+		Model:       settings.Model,
+		Temperature: &temperature,
+		MaxTokens:   summaryMaxTokens,
+		System: fmt.Sprintf(`You keep a running summary of a conversation between a truck driver and the Porter Saathi assistant.
+		Update the summary with the new turns. Keep names, amounts, dates, vehicle details, the driver's problems, what was suggested or tried, and what is still open.
+		Write plain text in the language: %s, in at most 150 words.`, session.Language),
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: summaryPrompt(session.Summary, messages[:keepFrom])}},
+	}
+	response, err := client.Chat(ctx, request)
+	if err != nil {
+		log.Printf("Error summarizing chat session %s: %v", session.ID.Hex(), err)
+		return
+	}
+
+	summarizedCount := session.SummarizedCount + keepFrom
+	now := time.Now()
+	filter := bson.M{"_id": session.ID, "summarized_count": session.SummarizedCount}
+	if session.SummarizedCount == 0 {
+		// Sessions from before summaries have no summarized_count
+		filter["summarized_count"] = bson.M{"$in": []interface{}{0, nil}}
+	}
+	_, err = config.GetDB().Collection("chat_sessions").UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{
+		"summary":          strings.TrimSpace(response.Text),
+		"summarized_count": summarizedCount,
+		"summarized_at":    now,
+	}})
+	if err != nil {
+		log.Printf("Error saving chat summary for %s: %v", session.ID.Hex(), err)
+	}
+}
+
+func unsummarizedMessages(session models.ChatSession) []models.ChatMessage {
+	if session.SummarizedCount <= 0 || session.SummarizedCount > len(session.Messages) {
+		return session.Messages
+	}
+	return session.Messages[session.SummarizedCount:]
+}
+
+// historyWindowStart returns the index of the oldest message that still fits in
+// budget tokens counting back from the newest, which always fits
+func historyWindowStart(messages []models.ChatMessage, budget int) int {
+	used := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		used += messageTokens(messages[i])
+		if used > budget && i < len(messages)-1 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func historyTokens(messages []models.ChatMessage) int {
+	total := 0
+	for _, message := range messages {
+		total += messageTokens(message)
+	}
+	return total
+}
+
+func messageTokens(message models.ChatMessage) int {
+	return llm.EstimateTokens(message.Text) + llm.MessageOverheadTokens
+}
+
+func chatTurn(message models.ChatMessage) llm.Message {
+	if message.Sender == "user" {
+		return llm.Message{Role: llm.RoleUser, Content: message.Text}
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: message.Text}
+}
+
+func summaryPrompt(summary string, messages []models.ChatMessage) string {
+	var prompt strings.Builder
+	if summary != "" {
+		prompt.WriteString("Summary so far:\n" + summary + "\n\n")
+	}
+	prompt.WriteString("New turns:\n")
+	for _, message := range messages {
+		speaker := "Driver"
+		if message.Sender != "user" {
+			speaker = "Assistant"
+		}
+		prompt.WriteString(speaker + ": " + message.Text + "\n")
+	}
+	return prompt.String()
+}
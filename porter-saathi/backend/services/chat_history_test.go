@@ -0,0 +1,191 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/llm"
+	"porter-saathi-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// chatSession alternates driver and assistant messages, starting with the driver
+func chatSession(texts ...string) models.ChatSession {
+	session := models.ChatSession{ID: primitive.NewObjectID(), Language: "hindi"}
+	for i, text := range texts {
+		sender := "user"
+		if i%2 == 1 {
+			sender = "ai"
+		}
+		session.Messages = append(session.Messages, models.ChatMessage{Sender: sender, Text: text})
+	}
+	return session
+}
+
+func turnTexts(turns []llm.Message) []string {
+	texts := make([]string, len(turns))
+	for i, turn := range turns {
+		texts[i] = turn.Role + ":" + turn.Content
+	}
+	return texts
+}
+
+func TestChatHistory(t *testing.T) {
+	// Each message is 12 characters, so 3 tokens of text plus the turn overhead
+	perMessage := 3 + llm.MessageOverheadTokens
+	session := chatSession("question one", "answer one..", "question two", "answer two..", "question 3..")
+
+	tests := []struct {
+		name    string
+		session func() models.ChatSession
+		budget  int
+		want    []string
+	}{
+		{
+			name:    "everything fits",
+			session: func() models.ChatSession { return session },
+			budget:  100,
+			want:    []string{"user:question one", "assistant:answer one..", "user:question two", "assistant:answer two..", "user:question 3.."},
+		},
+		{
+			name:    "newest turns that fit",
+			session: func() models.ChatSession { return session },
+			budget:  3 * perMessage,
+			want:    []string{"user:question two", "assistant:answer two..", "user:question 3.."},
+		},
+		{
+			name:    "window never opens on a reply",
+			session: func() models.ChatSession { return session },
+			budget:  2 * perMessage,
+			want:    []string{"user:question 3.."},
+		},
+		{
+			name:    "question always sent",
+			session: func() models.ChatSession { return session },
+			budget:  1,
+			want:    []string{"user:question 3.."},
+		},
+		{
+			name: "summarized turns left out",
+			session: func() models.ChatSession {
+				summarized := session
+				summarized.Summary = "Driver asked two questions"
+				summarized.SummarizedCount = 4
+				return summarized
+			},
+			budget: 100,
+			want:   []string{"user:question 3.."},
+		},
+		{
+			name: "summarized count beyond the messages",
+			session: func() models.ChatSession {
+				broken := session
+				broken.SummarizedCount = 10
+				return broken
+			},
+			budget: 100,
+			want:   []string{"user:question one", "assistant:answer one..", "user:question two", "assistant:answer two..", "user:question 3.."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := turnTexts(ChatHistory(tt.session(), tt.budget))
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("ChatHistory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// mockResponse answers any command: an empty cursor for reads, one document for writes
+var mockResponse = mtest.CreateSuccessResponse(
+	bson.E{Key: "n", Value: 1},
+	bson.E{Key: "nModified", Value: 1},
+	bson.E{Key: "cursor", Value: bson.D{
+		{Key: "id", Value: int64(0)},
+		{Key: "ns", Value: "porter_saathi.chat_sessions"},
+		{Key: "firstBatch", Value: bson.A{}},
+	}},
+)
+
+func TestSummarizeChatSession(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	defer func(db *mongo.Database) { config.DB = db }(config.DB)
+
+	settings := llm.SessionConfig{Model: "fake", Timeout: time.Second, HistoryTokens: 30}
+	long := chatSession(
+		"Diesel ka rate kya hai aaj Delhi mein?", "Aaj Delhi mein diesel 87.62 rupaye litre hai.",
+		"Mera mileage kam kyun ho raha hai?", "Tyre pressure aur air filter check karwaiye.",
+		"Service kab karwani chahiye?", "Har 10,000 km par service karwaiye.",
+		"Aur toll ka kharcha kaise kam karun?",
+	)
+
+	mt.Run("folds older turns into the summary", func(mt *mtest.T) {
+		fake := llm.NewFakeClient("Driver asked about diesel prices and mileage.")
+		llm.SetDefault(fake)
+		config.DB = mt.DB
+		mt.AddMockResponses(mockResponse)
+
+		SummarizeChatSession(long, settings)
+
+		requests := fake.Requests()
+		if len(requests) != 1 {
+			mt.Fatalf("sent %d requests to the model, want 1", len(requests))
+		}
+		prompt := requests[0].Messages[0].Content
+		if !strings.Contains(prompt, "Driver: Diesel ka rate") || strings.Contains(prompt, "toll") {
+			mt.Errorf("summary prompt should hold the oldest turns and not the newest:\n%s", prompt)
+		}
+
+		var update bson.Raw
+		for _, started := range mt.GetAllStartedEvents() {
+			if started.CommandName == "update" {
+				update = started.Command
+			}
+		}
+		if update == nil {
+			mt.Fatal("summary was not saved")
+		}
+		set := update.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+		if summary := set.Lookup("summary").StringValue(); summary != "Driver asked about diesel prices and mileage." {
+			mt.Errorf("saved summary = %q", summary)
+		}
+		summarized := int(set.Lookup("summarized_count").AsInt64())
+		if summarized <= 0 || summarized >= len(long.Messages) || long.Messages[summarized].Sender != "user" {
+			mt.Errorf("summarized_count = %d, want a count that leaves the newest question and starts on one", summarized)
+		}
+	})
+
+	mt.Run("leaves short sessions alone", func(mt *mtest.T) {
+		fake := llm.NewFakeClient()
+		llm.SetDefault(fake)
+		config.DB = mt.DB
+
+		SummarizeChatSession(chatSession("Namaste", "Namaste! Kaise madad karun?"), settings)
+
+		if len(fake.Requests()) != 0 || len(mt.GetAllStartedEvents()) != 0 {
+			mt.Errorf("a session within the budget was summarized")
+		}
+	})
+
+	mt.Run("keeps the summary when the model fails", func(mt *mtest.T) {
+		fake := llm.NewFakeClient()
+		fake.Err = llm.ErrEmptyResponse
+		llm.SetDefault(fake)
+		config.DB = mt.DB
+
+		SummarizeChatSession(long, settings)
+
+		if len(mt.GetAllStartedEvents()) != 0 {
+			mt.Errorf("session was written although no summary was made")
+		}
+	})
+}
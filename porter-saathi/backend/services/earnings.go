@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+	"porter-saathi-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultEarningsCacheTTL is how long a snapshot is reused when EARNINGS_CACHE_TTL_SECONDS is not set
+	defaultEarningsCacheTTL = 30 * time.Second
+	// earningsCacheSweepSize is how many snapshots are held before expired ones are cleared out
+	earningsCacheSweepSize = 10000
+	// derivedCacheTTL is the longest a figure built from a driver's earnings, such as goal
+	// progress, is reused; writes drop it sooner through Invalidate
+	derivedCacheTTL = 5 * time.Minute
+)
+
+var (
+	earningsService     *EarningsService
+	earningsServiceOnce sync.Once
+)
+
+// Earnings returns the shared earnings service. Snapshots are cached for
+// EARNINGS_CACHE_TTL_SECONDS (default 30); 0 turns the cache off.
+func Earnings() *EarningsService {
+	earningsServiceOnce.Do(func() {
+		ttl := defaultEarningsCacheTTL
+		if seconds, err := strconv.Atoi(os.Getenv("EARNINGS_CACHE_TTL_SECONDS")); err == nil && seconds >= 0 {
+			ttl = time.Duration(seconds) * time.Second
+		}
+		earningsService = NewEarningsService(ttl)
+	})
+	return earningsService
+}
+
+// EarningsService computes a driver's day, week and last-7-days totals with one
+// aggregation, and caches them briefly so the app and the chat assistant can ask
+// often. Every earnings write must call Invalidate.
+type EarningsService struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[earningsCacheKey]earningsCacheEntry
+	// derived holds figures built from a driver's earnings by name, dropped along with their snapshots
+	derived map[primitive.ObjectID]map[string]derivedCacheEntry
+	// generations counts invalidations per driver, so a snapshot read before a write is never cached after it
+	generations map[primitive.ObjectID]uint64
+}
+
+type earningsCacheKey struct {
+	userID         primitive.ObjectID
+	includeFlagged bool
+}
+
+type earningsCacheEntry struct {
+	snapshot  EarningsSnapshot
+	expiresAt time.Time
+}
+
+type derivedCacheEntry struct {
+	value     any
+	expiresAt time.Time
+}
+
+func NewEarningsService(ttl time.Duration) *EarningsService {
+	return &EarningsService{
+		ttl:         ttl,
+		entries:     make(map[earningsCacheKey]earningsCacheEntry),
+		derived:     make(map[primitive.ObjectID]map[string]derivedCacheEntry),
+		generations: make(map[primitive.ObjectID]uint64),
+	}
+}
+
+// EarningsDay is one day of a driver's earnings with expenses by category
+type EarningsDay struct {
+	Date       time.Time          `bson:"_id"`
+	Revenue    float64            `bson:"revenue"`
+	Expenses   float64            `bson:"expenses"`
+	Trips      int                `bson:"trips"`
+	Categories map[string]float64 `bson:"-"`
+}
+
+// EarningsSnapshot is a driver's earnings today, over the 7 days before today,
+// this week and the week before, on their own calendar
+type EarningsSnapshot struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+
+	StartOfDay    time.Time
+	EndOfDay      time.Time
+	LastWeekStart time.Time
+	StartOfWeek   time.Time
+	EndOfWeek     time.Time
+	PrevWeekStart time.Time
+
+	Today        models.EarningsSummary
+	LastWeek     models.EarningsSummary
+	CurrentWeek  models.EarningsSummary
+	PreviousWeek models.EarningsSummary
+	WeeklyData   []models.DailyEarnings
+}
+
+// Overview is today against last week, as returned by GET /earnings
+func (s EarningsSnapshot) Overview() models.EarningsResponse {
+	return models.EarningsResponse{Today: s.Today, LastWeek: s.LastWeek}
+}
+
+// Weekly is this week day by day against last week, as returned by GET /earnings/weekly
+func (s EarningsSnapshot) Weekly() models.WeeklyEarningsResponse {
+	return models.WeeklyEarningsResponse{
+		WeeklyData:       s.WeeklyData,
+		CurrentWeek:      s.CurrentWeek,
+		PreviousWeek:     s.PreviousWeek,
+		GrowthPercentage: GrowthPercentage(s.CurrentWeek.NetEarnings, s.PreviousWeek.NetEarnings),
+		WeekStartDate:    s.StartOfWeek,
+	}
+}
+
+// Snapshot returns the driver's earnings as of now. Entries waiting for confirmation
+// are only counted when includeFlagged is set. The result may be up to the cache TTL
+// old, but never older than the driver's last write.
+func (s *EarningsService) Snapshot(userID primitive.ObjectID, now time.Time, includeFlagged bool) (EarningsSnapshot, error) {
+	key := earningsCacheKey{userID: userID, includeFlagged: includeFlagged}
+
+	s.mu.Lock()
+	entry, found := s.entries[key]
+	generation := s.generations[userID]
+	s.mu.Unlock()
+	// A snapshot taken yesterday is stale once the driver's day has turned over
+	if found && now.Before(entry.expiresAt) && now.Before(entry.snapshot.EndOfDay) {
+		return entry.snapshot, nil
+	}
+
+	loc, weekStart := UserCalendar(userID)
+	snapshot, err := s.buildSnapshot(userID, utils.StartOfDay(now, loc), loc, weekStart, includeFlagged)
+	if err != nil || s.ttl == 0 {
+		return snapshot, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generations[userID] != generation {
+		return snapshot, nil
+	}
+	if len(s.entries) >= earningsCacheSweepSize {
+		for cached, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, cached)
+			}
+		}
+	}
+	s.entries[key] = earningsCacheEntry{snapshot: snapshot, expiresAt: now.Add(s.ttl)}
+	return snapshot, nil
+}
+
+// Invalidate drops the driver's cached snapshots and figures; call it after every write
+// to their earnings, goals or calendar
+func (s *EarningsService) Invalidate(userID primitive.ObjectID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generations[userID]++
+	delete(s.entries, earningsCacheKey{userID: userID, includeFlagged: false})
+	delete(s.entries, earningsCacheKey{userID: userID, includeFlagged: true})
+	delete(s.derived, userID)
+}
+
+// Cached returns a figure built from the driver's earnings, such as goal progress or
+// insights, reusing the last one built under name until the driver's next write, the
+// end of their day or derivedCacheTTL, whichever comes first.
+func Cached[T any](s *EarningsService, userID primitive.ObjectID, name string, now time.Time, build func() (T, error)) (T, error) {
+	s.mu.Lock()
+	entry, found := s.derived[userID][name]
+	generation := s.generations[userID]
+	s.mu.Unlock()
+	if found && now.Before(entry.expiresAt) {
+		return entry.value.(T), nil
+	}
+
+	value, err := build()
+	if err != nil || s.ttl == 0 {
+		return value, err
+	}
+	loc, _ := UserCalendar(userID)
+	expiresAt := utils.StartOfDay(now, loc).AddDate(0, 0, 1)
+	if limit := now.Add(derivedCacheTTL); limit.Before(expiresAt) {
+		expiresAt = limit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generations[userID] != generation {
+		return value, nil
+	}
+	if len(s.derived) >= earningsCacheSweepSize {
+		for cachedUser, figures := range s.derived {
+			for cachedName, cached := range figures {
+				if !now.Before(cached.expiresAt) {
+					delete(figures, cachedName)
+				}
+			}
+			if len(figures) == 0 {
+				delete(s.derived, cachedUser)
+			}
+		}
+	}
+	if s.derived[userID] == nil {
+		s.derived[userID] = make(map[string]derivedCacheEntry)
+	}
+	s.derived[userID][name] = derivedCacheEntry{value: value, expiresAt: expiresAt}
+	return value, nil
+}
+
+func (s *EarningsService) buildSnapshot(userID primitive.ObjectID, startOfDay time.Time, loc *time.Location, weekStart time.Weekday, includeFlagged bool) (EarningsSnapshot, error) {
+	snapshot := EarningsSnapshot{
+		Location:      loc,
+		WeekStart:     weekStart,
+		StartOfDay:    startOfDay,
+		EndOfDay:      startOfDay.AddDate(0, 0, 1),
+		LastWeekStart: startOfDay.AddDate(0, 0, -7),
+		StartOfWeek:   utils.StartOfWeek(startOfDay, loc, weekStart),
+	}
+	snapshot.EndOfWeek = snapshot.StartOfWeek.AddDate(0, 0, 7)
+	snapshot.PrevWeekStart = snapshot.StartOfWeek.AddDate(0, 0, -7)
+
+	// One query covers all four periods
+	from := snapshot.LastWeekStart
+	if snapshot.PrevWeekStart.Before(from) {
+		from = snapshot.PrevWeekStart
+	}
+	days, err := DailyEarnings(userID, from, snapshot.EndOfWeek, includeFlagged)
+	if err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Today = summarizeDays(days, snapshot.StartOfDay, snapshot.EndOfDay)
+	snapshot.LastWeek = summarizeDays(days, snapshot.LastWeekStart, snapshot.StartOfDay)
+	// Today's categories are compared with an average day last week
+	CompareCategories(&snapshot.Today, snapshot.LastWeek, 1.0/7)
+	snapshot.CurrentWeek = summarizeDays(days, snapshot.StartOfWeek, snapshot.EndOfWeek)
+	snapshot.PreviousWeek = summarizeDays(days, snapshot.PrevWeekStart, snapshot.StartOfWeek)
+	CompareCategories(&snapshot.CurrentWeek, snapshot.PreviousWeek, 1)
+
+	// Keyed by Unix time, as the database returns dates in UTC
+	byDay := make(map[int64]EarningsDay, len(days))
+	for _, day := range days {
+		byDay[day.Date.Unix()] = day
+	}
+	for i := 0; i < 7; i++ {
+		date := snapshot.StartOfWeek.AddDate(0, 0, i)
+		day := byDay[date.Unix()]
+		snapshot.WeeklyData = append(snapshot.WeeklyData, models.DailyEarnings{
+			Date:        date,
+			DayName:     date.Format("Mon"),
+			Revenue:     day.Revenue,
+			Expenses:    day.Expenses,
+			Trips:       day.Trips,
+			NetEarnings: day.Revenue - day.Expenses,
+		})
+	}
+	return snapshot, nil
+}
+
+// DailyEarnings totals the driver's entries in [from, end) per day, with expenses by
+// category, in a single aggregation. Entries waiting for confirmation are only
+// counted when includeFlagged is set.
+func DailyEarnings(userID primitive.ObjectID, from, end time.Time, includeFlagged bool) ([]EarningsDay, error) {
+	match := bson.M{
+		"user_id":    userID,
+		"deleted_at": bson.M{"$exists": false},
+		"date":       bson.M{"$gte": from, "$lt": end},
+	}
+	if !includeFlagged {
+		match["review_status"] = bson.M{"$ne": models.ReviewFlagged}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		// Dates are stored as the driver's local midnight, so each date is one day
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$date",
+			"revenue":  bson.M{"$sum": "$revenue"},
+			"expenses": bson.M{"$sum": "$expenses"},
+			"trips":    bson.M{"$sum": "$trips"},
+			"items":    bson.M{"$push": bson.M{"$ifNull": bson.A{"$expense_items", bson.A{}}}},
+			// Expenses recorded only as a total count as other
+			"unitemized": bson.M{"$sum": bson.M{"$max": bson.A{
+				0,
+				bson.M{"$subtract": bson.A{"$expenses", bson.M{"$sum": "$expense_items.amount"}}},
+			}}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	cursor, err := config.GetDB().Collection("earnings").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		EarningsDay `bson:",inline"`
+		Items       [][]models.ExpenseItem `bson:"items"`
+		Unitemized  float64                `bson:"unitemized"`
+	}
+	if err := cursor.All(context.Background(), &results); err != nil {
+		return nil, err
+	}
+
+	days := make([]EarningsDay, 0, len(results))
+	for _, result := range results {
+		day := result.EarningsDay
+		day.Categories = make(map[string]float64)
+		for _, items := range result.Items {
+			for _, item := range items {
+				day.Categories[item.Category] += item.Amount
+			}
+		}
+		if result.Unitemized > 0.005 {
+			day.Categories[models.ExpenseOther] += result.Unitemized
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// summarizeDays totals the days in [from, to)
+func summarizeDays(days []EarningsDay, from, to time.Time) models.EarningsSummary {
+	var summary models.EarningsSummary
+	amounts := make(map[string]float64)
+	for _, day := range days {
+		if day.Date.Before(from) || !day.Date.Before(to) {
+			continue
+		}
+		summary.Revenue += day.Revenue
+		summary.Expenses += day.Expenses
+		summary.Trips += day.Trips
+		for category, amount := range day.Categories {
+			amounts[category] += amount
+		}
+	}
+	summary.NetEarnings = summary.Revenue - summary.Expenses
+	summary.Categories = CategoryTotals(amounts, summary.Expenses)
+	return summary
+}
+
+// GrowthPercentage is the change from previous to current, or 0 when previous is not positive
+func GrowthPercentage(current, previous float64) float64 {
+	if previous <= 0 {
+		return 0
+	}
+	return (current - previous) / previous * 100
+}
+
+// CategoryTotals lists spend per category in report order with each one's share of totalExpenses
+func CategoryTotals(amounts map[string]float64, totalExpenses float64) []models.CategoryTotal {
+	categories := []models.CategoryTotal{}
+	for _, category := range models.ExpenseCategories {
+		amount := amounts[category]
+		if amount <= 0 {
+			continue
+		}
+		total := models.CategoryTotal{Category: category, Amount: utils.RoundMoney(amount)}
+		if totalExpenses > 0 {
+			total.Percentage = math.Round(amount/totalExpenses*1000) / 10
+		}
+		categories = append(categories, total)
+	}
+	return categories
+}
+
+// CompareCategories adds period-over-period changes to current's categories.
+// previous is multiplied by scale first, so periods of different length can be compared.
+func CompareCategories(current *models.EarningsSummary, previous models.EarningsSummary, scale float64) {
+	previousAmounts := make(map[string]float64)
+	for _, category := range previous.Categories {
+		previousAmounts[category.Category] = category.Amount * scale
+	}
+
+	currentByCategory := make(map[string]models.CategoryTotal)
+	for _, category := range current.Categories {
+		currentByCategory[category.Category] = category
+	}
+
+	compared := []models.CategoryTotal{}
+	for _, name := range models.ExpenseCategories {
+		category, inCurrent := currentByCategory[name]
+		previousAmount, inPrevious := previousAmounts[name]
+		if !inCurrent && !inPrevious {
+			continue
+		}
+		category.Category = name
+
+		previousAmount = utils.RoundMoney(previousAmount)
+		change := utils.RoundMoney(category.Amount - previousAmount)
+		category.PreviousAmount = &previousAmount
+		category.Change = &change
+		if previousAmount > 0 {
+			changePercentage := math.Round(change/previousAmount*1000) / 10
+			category.ChangePercentage = &changePercentage
+		}
+		compared = append(compared, category)
+	}
+	current.Categories = compared
+}
+
+// UserCalendar returns the driver's timezone and week start, defaulting to IST and Monday
+func UserCalendar(userID primitive.ObjectID) (*time.Location, time.Weekday) {
+	var user models.User
+	err := config.GetDB().Collection("users").FindOne(
+		context.Background(),
+		bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"timezone": 1, "week_start": 1}),
+	).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf("Error loading calendar settings for %s: %v", userID.Hex(), err)
+	}
+	return utils.LoadTimezone(user.Timezone), utils.WeekStartOrDefault(user.WeekStart)
+}
@@ -0,0 +1,346 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const (
+	aadhaarQRDelimiter     = 0xFF
+	aadhaarQRSignatureSize = 256
+	aadhaarQRHashSize      = 32
+)
+
+var (
+	ErrAadhaarQRNotFound      = errors.New("no QR code found in image")
+	ErrNotAadhaarSecureQR     = errors.New("QR code is not an Aadhaar secure QR")
+	ErrUIDAICertNotConfigured = errors.New("UIDAI_CERT_PATH not set")
+)
+
+// AadhaarQRData holds the fields carried in an Aadhaar secure QR code
+type AadhaarQRData struct {
+	Version           string `json:"version"`
+	ReferenceID       string `json:"referenceId"`
+	AadhaarLastFour   string `json:"aadhaarLastFour"`
+	Name              string `json:"name"`
+	DateOfBirth       string `json:"dateOfBirth"`
+	Gender            string `json:"gender"`
+	CareOf            string `json:"careOf,omitempty"`
+	District          string `json:"district,omitempty"`
+	Landmark          string `json:"landmark,omitempty"`
+	House             string `json:"house,omitempty"`
+	Location          string `json:"location,omitempty"`
+	PinCode           string `json:"pinCode,omitempty"`
+	PostOffice        string `json:"postOffice,omitempty"`
+	State             string `json:"state,omitempty"`
+	Street            string `json:"street,omitempty"`
+	SubDistrict       string `json:"subDistrict,omitempty"`
+	VTC               string `json:"vtc,omitempty"`
+	MobileLastFour    string `json:"mobileLastFour,omitempty"`
+	HasEmail          bool   `json:"hasEmail"`
+	HasMobile         bool   `json:"hasMobile"`
+	Photo             []byte `json:"photo,omitempty"` // JPEG 2000 as issued by UIDAI
+	SignatureVerified bool   `json:"signatureVerified"`
+
+	emailHash  []byte
+	mobileHash []byte
+	signedData []byte
+	signature  []byte
+}
+
+// Address joins the address components in the order printed on the card
+func (d *AadhaarQRData) Address() string {
+	var parts []string
+	seen := make(map[string]bool)
+	for _, part := range []string{d.CareOf, d.House, d.Street, d.Landmark, d.Location, d.VTC, d.PostOffice, d.SubDistrict, d.District, d.State} {
+		// VTC, sub-district and district frequently repeat the same name
+		key := strings.ToLower(strings.TrimSpace(part))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			parts = append(parts, strings.TrimSpace(part))
+		}
+	}
+	address := strings.Join(parts, ", ")
+	if d.PinCode != "" {
+		address += " - " + d.PinCode
+	}
+	return address
+}
+
+// MatchesMobile checks a mobile number against the hash embedded in the QR
+func (d *AadhaarQRData) MatchesMobile(mobile string) bool {
+	return len(d.mobileHash) > 0 && d.matchesHash(mobile, d.mobileHash)
+}
+
+// MatchesEmail checks an email address against the hash embedded in the QR
+func (d *AadhaarQRData) MatchesEmail(email string) bool {
+	return len(d.emailHash) > 0 && d.matchesHash(strings.TrimSpace(email), d.emailHash)
+}
+
+// matchesHash applies SHA-256 as many times as the last Aadhaar digit (at least once)
+func (d *AadhaarQRData) matchesHash(value string, expected []byte) bool {
+	rounds := 1
+	if n := len(d.AadhaarLastFour); n > 0 {
+		if digit := int(d.AadhaarLastFour[n-1] - '0'); digit > 1 && digit <= 9 {
+			rounds = digit
+		}
+	}
+
+	sum := []byte(value)
+	for i := 0; i < rounds; i++ {
+		h := sha256.Sum256(sum)
+		sum = []byte(hex.EncodeToString(h[:]))
+	}
+
+	decoded, err := hex.DecodeString(string(sum))
+	return err == nil && bytes.Equal(decoded, expected)
+}
+
+// DecodeAadhaarQRImage locates the secure QR in an image, parses it and verifies its signature.
+// A payload that parses but fails verification is still returned with SignatureVerified unset.
+func DecodeAadhaarQRImage(imageBytes []byte) (*AadhaarQRData, error) {
+	img, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	content, err := FindQRCode(img)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ParseAadhaarSecureQR(content)
+	if err != nil {
+		return nil, err
+	}
+
+	data.SignatureVerified = VerifyAadhaarQRSignature(data) == nil
+	return data, nil
+}
+
+// FindQRCode returns the text content of the first QR code found in the image
+func FindQRCode(img image.Image) (string, error) {
+	source := gozxing.NewLuminanceSourceFromImage(img)
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	reader := qrcode.NewQRCodeReader()
+
+	// Phone photos vary a lot in lighting, so try the local binarizer before the global one
+	for _, binarizer := range []gozxing.Binarizer{
+		gozxing.NewHybridBinarizer(source),
+		gozxing.NewGlobalHistgramBinarizer(source),
+	} {
+		bitmap, err := gozxing.NewBinaryBitmap(binarizer)
+		if err != nil {
+			continue
+		}
+		result, err := reader.Decode(bitmap, hints)
+		if err == nil && result.GetText() != "" {
+			return result.GetText(), nil
+		}
+	}
+
+	return "", ErrAadhaarQRNotFound
+}
+
+// ParseAadhaarSecureQR decodes the big-integer payload of an Aadhaar secure QR code
+func ParseAadhaarSecureQR(content string) (*AadhaarQRData, error) {
+	content = strings.TrimSpace(content)
+	number, ok := new(big.Int).SetString(content, 10)
+	if !ok {
+		return nil, ErrNotAadhaarSecureQR
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(number.Bytes()))
+	if err != nil {
+		return nil, ErrNotAadhaarSecureQR
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress QR payload: %w", err)
+	}
+	if len(raw) <= aadhaarQRSignatureSize {
+		return nil, ErrNotAadhaarSecureQR
+	}
+
+	data := &AadhaarQRData{
+		signedData: raw[:len(raw)-aadhaarQRSignatureSize],
+		signature:  raw[len(raw)-aadhaarQRSignatureSize:],
+	}
+
+	// V2 onwards the payload starts with an explicit version field and carries
+	// the last four mobile digits; the original format starts with the indicator.
+	fieldCount := 16
+	if bytes.HasPrefix(data.signedData, []byte("V")) {
+		fieldCount = 18
+	}
+
+	// The photo is binary and may contain the delimiter, so only split the text fields
+	fields := make([]string, 0, fieldCount)
+	start := 0
+	for i := 0; i < len(data.signedData) && len(fields) < fieldCount; i++ {
+		if data.signedData[i] == aadhaarQRDelimiter {
+			fields = append(fields, latin1ToString(data.signedData[start:i]))
+			start = i + 1
+		}
+	}
+	if len(fields) < fieldCount {
+		return nil, ErrNotAadhaarSecureQR
+	}
+
+	if fieldCount == 18 {
+		data.Version = fields[0]
+		data.MobileLastFour = fields[17]
+		fields = fields[1:17]
+	} else {
+		data.Version = "V1"
+	}
+
+	indicator := fields[0]
+	data.ReferenceID = fields[1]
+	data.Name = fields[2]
+	data.DateOfBirth = strings.ReplaceAll(fields[3], "-", "/")
+	data.Gender = expandAadhaarGender(fields[4])
+	data.CareOf = fields[5]
+	data.District = fields[6]
+	data.Landmark = fields[7]
+	data.House = fields[8]
+	data.Location = fields[9]
+	data.PinCode = fields[10]
+	data.PostOffice = fields[11]
+	data.State = fields[12]
+	data.Street = fields[13]
+	data.SubDistrict = fields[14]
+	data.VTC = fields[15]
+
+	if len(data.ReferenceID) >= 4 {
+		data.AadhaarLastFour = data.ReferenceID[:4]
+	}
+
+	// Email and mobile hashes sit between the photo and the signature
+	photoEnd := len(data.signedData)
+	switch indicator {
+	case "1":
+		data.HasEmail = true
+	case "2":
+		data.HasMobile = true
+	case "3":
+		data.HasEmail = true
+		data.HasMobile = true
+	}
+	if data.HasMobile && photoEnd-aadhaarQRHashSize >= start {
+		data.mobileHash = data.signedData[photoEnd-aadhaarQRHashSize : photoEnd]
+		photoEnd -= aadhaarQRHashSize
+	}
+	if data.HasEmail && photoEnd-aadhaarQRHashSize >= start {
+		data.emailHash = data.signedData[photoEnd-aadhaarQRHashSize : photoEnd]
+		photoEnd -= aadhaarQRHashSize
+	}
+	if photoEnd > start {
+		data.Photo = data.signedData[start:photoEnd]
+	}
+
+	return data, nil
+}
+
+// VerifyAadhaarQRSignature checks the SHA256withRSA signature against the UIDAI certificate
+func VerifyAadhaarQRSignature(data *AadhaarQRData) error {
+	publicKey, err := loadUIDAIPublicKey()
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(data.signedData)
+	return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], data.signature)
+}
+
+// UIDAICertConfigured reports whether a UIDAI certificate has been loaded successfully
+func UIDAICertConfigured() bool {
+	_, err := loadUIDAIPublicKey()
+	return err == nil
+}
+
+var (
+	uidaiKeyOnce sync.Once
+	uidaiKey     *rsa.PublicKey
+	uidaiKeyErr  error
+)
+
+func loadUIDAIPublicKey() (*rsa.PublicKey, error) {
+	uidaiKeyOnce.Do(func() {
+		certPath := os.Getenv("UIDAI_CERT_PATH")
+		if certPath == "" {
+			uidaiKeyErr = ErrUIDAICertNotConfigured
+			return
+		}
+
+		certBytes, err := os.ReadFile(certPath)
+		if err != nil {
+			uidaiKeyErr = fmt.Errorf("failed to read UIDAI certificate: %w", err)
+			return
+		}
+
+		// UIDAI distributes the certificate both as PEM and as raw DER
+		if block, _ := pem.Decode(certBytes); block != nil {
+			certBytes = block.Bytes
+		}
+
+		cert, err := x509.ParseCertificate(certBytes)
+		if err != nil {
+			uidaiKeyErr = fmt.Errorf("failed to parse UIDAI certificate: %w", err)
+			return
+		}
+
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			uidaiKeyErr = errors.New("UIDAI certificate does not contain an RSA public key")
+			return
+		}
+		uidaiKey = key
+	})
+
+	return uidaiKey, uidaiKeyErr
+}
+
+// latin1ToString converts ISO-8859-1 bytes, the encoding used for QR text fields
+func latin1ToString(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return strings.TrimSpace(string(runes))
+}
+
+func expandAadhaarGender(code string) string {
+	switch strings.ToUpper(strings.TrimSpace(code)) {
+	case "M":
+		return "Male"
+	case "F":
+		return "Female"
+	case "T":
+		return "Transgender"
+	}
+	return code
+}
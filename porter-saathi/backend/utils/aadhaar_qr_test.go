@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// secureQR builds the decimal payload of a secure QR from its text fields, photo,
+// email and mobile hashes, with a dummy signature
+func secureQR(t *testing.T, fields []string, photo []byte, hashes ...[]byte) string {
+	t.Helper()
+	var raw bytes.Buffer
+	for _, field := range fields {
+		raw.WriteString(field)
+		raw.WriteByte(aadhaarQRDelimiter)
+	}
+	raw.Write(photo)
+	for _, hash := range hashes {
+		raw.Write(hash)
+	}
+	raw.Write(bytes.Repeat([]byte{0x5A}, aadhaarQRSignatureSize))
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(raw.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(compressed.Bytes()).String()
+}
+
+// qrHash hashes value the way UIDAI does, rounds times
+func qrHash(value string, rounds int) []byte {
+	sum := []byte(value)
+	for i := 0; i < rounds; i++ {
+		h := sha256.Sum256(sum)
+		sum = []byte(hex.EncodeToString(h[:]))
+	}
+	decoded, _ := hex.DecodeString(string(sum))
+	return decoded
+}
+
+func TestParseAadhaarSecureQR(t *testing.T) {
+	// Indicator, reference ID, name, DOB, gender, then the address fields
+	v1Fields := []string{
+		"2", "524620190101120000000", "Rajesh Kumar", "01-01-1990", "M",
+		"S/O Ramesh Kumar", "New Delhi", "Near Bus Stand", "12", "Karol Bagh",
+		"110001", "Karol Bagh", "Delhi", "MG Road", "Karol Bagh", "New Delhi",
+	}
+	photo := []byte{0xFF, 0x4F, 0xFF, 0x51, 0x00} // photo bytes can contain the delimiter
+	// The reference ID starts with the last four Aadhaar digits; the last of them is the hash round count
+	mobileHash := qrHash("9876543210", 6)
+	// V2 adds a version field in front and the last four mobile digits at the end
+	v2Fields := append(append([]string{"V2"}, v1Fields...), "3210")
+
+	tests := []struct {
+		name    string
+		payload string
+		check   func(t *testing.T, data *AadhaarQRData)
+	}{
+		{
+			name:    "v1 with mobile",
+			payload: secureQR(t, v1Fields, photo, mobileHash),
+			check: func(t *testing.T, data *AadhaarQRData) {
+				if data.Version != "V1" || data.Name != "Rajesh Kumar" || data.DateOfBirth != "01/01/1990" || data.Gender != "Male" {
+					t.Errorf("unexpected identity fields: %+v", data)
+				}
+				if data.AadhaarLastFour != "5246" || data.PinCode != "110001" || data.State != "Delhi" {
+					t.Errorf("unexpected reference or address fields: %+v", data)
+				}
+				if !data.HasMobile || data.HasEmail {
+					t.Errorf("HasMobile = %v, HasEmail = %v, want true, false", data.HasMobile, data.HasEmail)
+				}
+				if !bytes.Equal(data.Photo, photo) {
+					t.Errorf("Photo = %x, want %x", data.Photo, photo)
+				}
+				if !data.MatchesMobile("9876543210") || data.MatchesMobile("9876543211") {
+					t.Error("mobile hash did not match only the right number")
+				}
+				if got := data.Address(); got != "S/O Ramesh Kumar, 12, MG Road, Near Bus Stand, Karol Bagh, New Delhi, Delhi - 110001" {
+					t.Errorf("Address() = %q", got)
+				}
+			},
+		},
+		{
+			name:    "v2 with mobile digits",
+			payload: secureQR(t, v2Fields, photo, mobileHash),
+			check: func(t *testing.T, data *AadhaarQRData) {
+				if data.Version != "V2" || data.MobileLastFour != "3210" || data.Name != "Rajesh Kumar" {
+					t.Errorf("unexpected v2 fields: %+v", data)
+				}
+			},
+		},
+		{
+			name:    "no email or mobile",
+			payload: secureQR(t, append([]string{"0"}, v1Fields[1:]...), photo),
+			check: func(t *testing.T, data *AadhaarQRData) {
+				if data.HasMobile || data.HasEmail || data.MatchesMobile("9876543210") {
+					t.Errorf("unexpected contact hashes: %+v", data)
+				}
+				if !bytes.Equal(data.Photo, photo) {
+					t.Errorf("Photo = %x, want %x", data.Photo, photo)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseAadhaarSecureQR(tt.payload)
+			if err != nil {
+				t.Fatalf("ParseAadhaarSecureQR() error = %v", err)
+			}
+			tt.check(t, data)
+		})
+	}
+}
+
+func TestParseAadhaarSecureQRRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"not a number", "https://example.com"},
+		{"not gzip", "123456789"},
+		{"too few fields", secureQR(t, []string{"2", "524620190101120000000", "Rajesh Kumar"}, nil)},
+		{"empty", strings.Repeat(" ", 3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAadhaarSecureQR(tt.payload); err == nil {
+				t.Error("ParseAadhaarSecureQR() succeeded, want an error")
+			}
+		})
+	}
+}
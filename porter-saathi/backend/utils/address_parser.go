@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"porter-saathi-backend/models"
+)
+
+//go:embed data/pincodes.csv
+var pinCodeCSV []byte
+
+// PinCodeInfo is the district and state of a PIN code's postal region. The bundled
+// dataset only lists regions by prefix, so they are hints rather than a validation.
+type PinCodeInfo struct {
+	District string
+	State    string
+}
+
+var (
+	pinCodeOnce  sync.Once
+	pinCodeTable map[string]PinCodeInfo
+)
+
+func loadPinCodes() map[string]PinCodeInfo {
+	pinCodeOnce.Do(func() {
+		pinCodeTable = make(map[string]PinCodeInfo)
+		scanner := bufio.NewScanner(bytes.NewReader(pinCodeCSV))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Split(line, ",")
+			if len(fields) != 3 {
+				continue
+			}
+			pinCodeTable[fields[0]] = PinCodeInfo{District: fields[1], State: fields[2]}
+		}
+	})
+	return pinCodeTable
+}
+
+// LookupPinCode finds the district and state for a PIN code using the longest
+// matching prefix in the bundled dataset. District is empty when only the state is known.
+func LookupPinCode(pin string) (PinCodeInfo, bool) {
+	if !sixDigitPin.MatchString(pin) {
+		return PinCodeInfo{}, false
+	}
+
+	table := loadPinCodes()
+	var info PinCodeInfo
+	found := false
+	for length := 2; length <= len(pin); length++ {
+		if entry, ok := table[pin[:length]]; ok {
+			if entry.District == "" {
+				entry.District = info.District
+			}
+			info = entry
+			found = true
+		}
+	}
+	return info, found
+}
+
+var (
+	sixDigitPin      = regexp.MustCompile(`^[1-9]\d{5}$`)
+	pinInAddress     = regexp.MustCompile(`(?:^|\D)([1-9]\d{2}\s?\d{3})(?:\D|$)`)
+	careOfPrefix     = regexp.MustCompile(`(?i)^(?:s/o|d/o|w/o|c/o|h/o)|^(?:पुत्र|पुत्री|पत्नी|द्वारा|తండ్రి|భర్త|த/பெ|க/பெ)`)
+	housePrefix      = regexp.MustCompile(`(?i)^(?:h\.?\s?no\.?|house|flat|plot|door|d\.?\s?no\.?|#|मकान|घर|ఇంటి|வீடு)`)
+	landmarkPrefix   = regexp.MustCompile(`(?i)^(?:near|opp\.?|opposite|behind|beside|next to|पास|के पास|सामने|దగ్గర|அருகில்)`)
+	streetKeywords   = regexp.MustCompile(`(?i)\b(?:street|st|road|rd|marg|lane|gali|cross|main|highway)\b|मार्ग|रोड|स्ट्रीट|गली|రోడ్|వీధి|சாலை|தெரு`)
+	addressSeparator = regexp.MustCompile(`[,\n;]+`)
+)
+
+// stateNames maps English and regional spellings of states to the canonical English name
+var stateNames = map[string]string{
+	"delhi": "Delhi", "new delhi": "Delhi", "दिल्ली": "Delhi", "नई दिल्ली": "Delhi",
+	"haryana": "Haryana", "हरियाणा": "Haryana",
+	"punjab": "Punjab", "पंजाब": "Punjab",
+	"chandigarh": "Chandigarh", "चंडीगढ़": "Chandigarh",
+	"himachal pradesh": "Himachal Pradesh", "हिमाचल प्रदेश": "Himachal Pradesh",
+	"jammu and kashmir": "Jammu and Kashmir", "jammu & kashmir": "Jammu and Kashmir", "जम्मू और कश्मीर": "Jammu and Kashmir",
+	"ladakh": "Ladakh", "लद्दाख": "Ladakh",
+	"uttar pradesh": "Uttar Pradesh", "उत्तर प्रदेश": "Uttar Pradesh",
+	"uttarakhand": "Uttarakhand", "उत्तराखंड": "Uttarakhand",
+	"rajasthan": "Rajasthan", "राजस्थान": "Rajasthan",
+	"gujarat": "Gujarat", "गुजरात": "Gujarat",
+	"maharashtra": "Maharashtra", "महाराष्ट्र": "Maharashtra",
+	"goa": "Goa", "गोवा": "Goa",
+	"madhya pradesh": "Madhya Pradesh", "मध्य प्रदेश": "Madhya Pradesh",
+	"chhattisgarh": "Chhattisgarh", "छत्तीसगढ़": "Chhattisgarh",
+	"telangana": "Telangana", "तेलंगाना": "Telangana", "తెలంగాణ": "Telangana",
+	"andhra pradesh": "Andhra Pradesh", "आंध्र प्रदेश": "Andhra Pradesh", "ఆంధ్ర ప్రదేశ్": "Andhra Pradesh",
+	"karnataka": "Karnataka", "कर्नाटक": "Karnataka", "కర్ణాటక": "Karnataka",
+	"tamil nadu": "Tamil Nadu", "तमिलनाडु": "Tamil Nadu", "தமிழ்நாடு": "Tamil Nadu", "தமிழ் நாடு": "Tamil Nadu",
+	"puducherry": "Puducherry", "pondicherry": "Puducherry", "புதுச்சேரி": "Puducherry",
+	"kerala": "Kerala", "केरल": "Kerala", "கேரளா": "Kerala",
+	"west bengal": "West Bengal", "पश्चिम बंगाल": "West Bengal",
+	"sikkim": "Sikkim", "सिक्किम": "Sikkim",
+	"odisha": "Odisha", "orissa": "Odisha", "ओडिशा": "Odisha",
+	"assam": "Assam", "असम": "Assam",
+	"arunachal pradesh": "Arunachal Pradesh", "meghalaya": "Meghalaya", "manipur": "Manipur",
+	"mizoram": "Mizoram", "nagaland": "Nagaland", "tripura": "Tripura",
+	"bihar": "Bihar", "बिहार": "Bihar",
+	"jharkhand": "Jharkhand", "झारखंड": "Jharkhand",
+	"andaman and nicobar islands": "Andaman and Nicobar Islands",
+}
+
+// CanonicalState returns the English state name for an English or regional spelling
+func CanonicalState(name string) (string, bool) {
+	state, ok := stateNames[strings.ToLower(strings.TrimSpace(name))]
+	return state, ok
+}
+
+// ParseIndianAddress splits a free-text Indian address into its components and
+// validates the PIN code against the bundled dataset. Regional-script addresses are
+// parsed the same way and keep their native spellings.
+func ParseIndianAddress(raw string) models.Address {
+	address := models.Address{Raw: strings.TrimSpace(raw)}
+
+	text := address.Raw
+	if match := pinInAddress.FindStringSubmatchIndex(text); match != nil {
+		address.PinCode = strings.ReplaceAll(text[match[2]:match[3]], " ", "")
+		text = text[:match[2]] + text[match[3]:]
+	}
+
+	var parts []string
+	for _, part := range addressSeparator.Split(text, -1) {
+		part = strings.Trim(strings.TrimSpace(part), "-.:")
+		part = strings.TrimSpace(part)
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	// State is normally the last component
+	for i := len(parts) - 1; i >= 0; i-- {
+		if _, ok := CanonicalState(parts[i]); ok {
+			address.State = parts[i]
+			parts = append(parts[:i], parts[i+1:]...)
+			break
+		}
+	}
+
+	pinInfo, pinKnown := LookupPinCode(address.PinCode)
+
+	// District: prefer the component naming the PIN code's district, else the one before the state
+	districtIndex := -1
+	if pinInfo.District != "" {
+		for i, part := range parts {
+			if strings.EqualFold(part, pinInfo.District) {
+				districtIndex = i
+			}
+		}
+	}
+	if districtIndex < 0 && len(parts) > 2 {
+		districtIndex = len(parts) - 1
+	}
+	if districtIndex >= 0 {
+		address.District = parts[districtIndex]
+		parts = append(parts[:districtIndex], parts[districtIndex+1:]...)
+	}
+
+	var remaining []string
+	for _, part := range parts {
+		switch {
+		case address.CareOf == "" && careOfPrefix.MatchString(part):
+			address.CareOf = part
+		case address.House == "" && (housePrefix.MatchString(part) || startsWithDigit(part)) && !streetKeywords.MatchString(part):
+			address.House = part
+		case address.Landmark == "" && landmarkPrefix.MatchString(part):
+			address.Landmark = part
+		case address.Street == "" && streetKeywords.MatchString(part):
+			address.Street = part
+		default:
+			remaining = append(remaining, part)
+		}
+	}
+
+	// The component closest to the district is the village/town, anything before it the locality
+	if len(remaining) > 0 {
+		address.VillageTown = remaining[len(remaining)-1]
+		if len(remaining) > 1 {
+			address.Locality = strings.Join(remaining[:len(remaining)-1], ", ")
+		}
+	}
+
+	validatePinCode(&address, pinInfo, pinKnown)
+	return address
+}
+
+// ValidateAddressPinCode checks an already structured address against the PIN code dataset
+func ValidateAddressPinCode(address *models.Address) {
+	address.StateHint, address.DistrictHint = "", ""
+	address.Issues = nil
+	pinInfo, pinKnown := LookupPinCode(address.PinCode)
+	validatePinCode(address, pinInfo, pinKnown)
+}
+
+// StructuredAddress maps the secure QR address fields onto a structured address
+func (d *AadhaarQRData) StructuredAddress() models.Address {
+	address := models.Address{
+		CareOf:      strings.TrimSpace(d.CareOf),
+		House:       strings.TrimSpace(d.House),
+		Street:      strings.TrimSpace(d.Street),
+		Landmark:    strings.TrimSpace(d.Landmark),
+		Locality:    strings.TrimSpace(d.Location),
+		VillageTown: strings.TrimSpace(d.VTC),
+		District:    strings.TrimSpace(d.District),
+		State:       strings.TrimSpace(d.State),
+		PinCode:     strings.TrimSpace(d.PinCode),
+		Raw:         d.Address(),
+	}
+	ValidateAddressPinCode(&address)
+	return address
+}
+
+// validatePinCode checks the PIN code's format and gives the state and district of its
+// postal region as hints. Nothing is filled in or judged from them, as a region is not
+// precise enough to say the PIN code exists.
+func validatePinCode(address *models.Address, pinInfo PinCodeInfo, pinKnown bool) {
+	switch {
+	case address.PinCode == "":
+		address.Issues = append(address.Issues, "Address has no PIN code")
+	case !sixDigitPin.MatchString(address.PinCode):
+		address.Issues = append(address.Issues, "PIN code must be six digits and cannot start with 0")
+	case !pinKnown:
+		address.Issues = append(address.Issues, fmt.Sprintf("PIN code %s is not in a known postal region", address.PinCode))
+	default:
+		address.StateHint = pinInfo.State
+		address.DistrictHint = pinInfo.District
+	}
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
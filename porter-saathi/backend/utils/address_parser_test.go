@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"porter-saathi-backend/models"
+)
+
+func TestLookupPinCode(t *testing.T) {
+	tests := []struct {
+		pin       string
+		want      PinCodeInfo
+		wantFound bool
+	}{
+		{"110001", PinCodeInfo{District: "New Delhi", State: "Delhi"}, true},
+		{"110092", PinCodeInfo{District: "New Delhi", State: "Delhi"}, true},
+		{"400001", PinCodeInfo{District: "Mumbai", State: "Maharashtra"}, true},
+		{"410001", PinCodeInfo{State: "Maharashtra"}, true},
+		{"012345", PinCodeInfo{}, false},
+		{"11000", PinCodeInfo{}, false},
+		{"11000a", PinCodeInfo{}, false},
+	}
+
+	for _, tt := range tests {
+		got, found := LookupPinCode(tt.pin)
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("LookupPinCode(%q) = %+v, %v, want %+v, %v", tt.pin, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
+
+func TestParseIndianAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want models.Address
+	}{
+		{
+			name: "full address",
+			raw:  "S/O Ramesh Kumar, H.No. 12, MG Road, Near Bus Stand, Karol Bagh, New Delhi, Delhi - 110001",
+			want: models.Address{
+				CareOf: "S/O Ramesh Kumar", House: "H.No. 12", Street: "MG Road", Landmark: "Near Bus Stand",
+				VillageTown: "Karol Bagh", District: "New Delhi", State: "Delhi", PinCode: "110001",
+				StateHint: "Delhi", DistrictHint: "New Delhi",
+			},
+		},
+		{
+			name: "region is only a hint",
+			raw:  "12, MG Road, Karol Bagh, Mumbai, Maharashtra 110001",
+			want: models.Address{
+				House: "12", Street: "MG Road", VillageTown: "Karol Bagh", District: "Mumbai", State: "Maharashtra",
+				PinCode: "110001", StateHint: "Delhi", DistrictHint: "New Delhi",
+			},
+		},
+		{
+			name: "nothing filled in from the region",
+			raw:  "12, Karol Bagh 110001",
+			want: models.Address{
+				House: "12", VillageTown: "Karol Bagh", PinCode: "110001", StateHint: "Delhi", DistrictHint: "New Delhi",
+			},
+		},
+		{
+			name: "PIN code outside known regions",
+			raw:  "12, Karol Bagh 990001",
+			want: models.Address{
+				House: "12", VillageTown: "Karol Bagh", PinCode: "990001",
+				Issues: []string{"PIN code 990001 is not in a known postal region"},
+			},
+		},
+		{
+			name: "devanagari keeps native spellings",
+			raw:  "मकान 12, गली 3, करोल बाग, नई दिल्ली, दिल्ली 110001",
+			want: models.Address{
+				House: "मकान 12", Street: "गली 3", VillageTown: "करोल बाग", District: "नई दिल्ली", State: "दिल्ली",
+				PinCode: "110001", StateHint: "Delhi", DistrictHint: "New Delhi",
+			},
+		},
+		{
+			name: "no PIN code",
+			raw:  "Gali 3, Rampur",
+			want: models.Address{Street: "Gali 3", VillageTown: "Rampur", Issues: []string{"Address has no PIN code"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseIndianAddress(tt.raw)
+			tt.want.Raw = tt.raw
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseIndianAddress(%q) =\n%+v\nwant\n%+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
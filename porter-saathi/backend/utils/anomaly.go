@@ -0,0 +1,42 @@
+package utils
+
+import "math"
+
+// AnomalyZThreshold is how many standard deviations from a driver's usual figures an entry must be to be flagged
+const AnomalyZThreshold = 3.0
+
+// Baseline is the mean and spread of a driver's recent figures
+type Baseline struct {
+	Mean   float64
+	StdDev float64
+	Count  int
+}
+
+// NewBaseline summarises values with their sample standard deviation
+func NewBaseline(values []float64) Baseline {
+	baseline := Baseline{Count: len(values)}
+	if len(values) == 0 {
+		return baseline
+	}
+	for _, value := range values {
+		baseline.Mean += value
+	}
+	baseline.Mean /= float64(len(values))
+	if len(values) < 2 {
+		return baseline
+	}
+
+	var squares float64
+	for _, value := range values {
+		squares += (value - baseline.Mean) * (value - baseline.Mean)
+	}
+	baseline.StdDev = math.Sqrt(squares / float64(len(values)-1))
+	return baseline
+}
+
+// ZScore is how many standard deviations value is from the mean. The spread is
+// never taken as less than minSpread, so a driver who earns almost the same every
+// day is not flagged for an ordinary good day.
+func (b Baseline) ZScore(value, minSpread float64) float64 {
+	return (value - b.Mean) / math.Max(b.StdDev, minSpread)
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cityAliases maps old and alternate city names to the one drivers are grouped under
+var cityAliases = map[string]string{
+	"bangalore": "bengaluru",
+	"bombay":    "mumbai",
+	"madras":    "chennai",
+	"calcutta":  "kolkata",
+	"gurgaon":   "gurugram",
+	"poona":     "pune",
+	"mysore":    "mysuru",
+	"baroda":    "vadodara",
+}
+
+// CityKey normalises a city name for grouping: lower case, single spaces, common aliases resolved
+func CityKey(city string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(city), " "))
+	if alias, ok := cityAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// CityName turns a city key back into a display name
+func CityName(key string) string {
+	words := strings.Fields(key)
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(first)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// PercentileValues returns the values at each percentile (0-100) of values, interpolating between neighbours
+func PercentileValues(values []float64, percentiles []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	result := make([]float64, len(percentiles))
+	if len(sorted) == 0 {
+		return result
+	}
+	for i, percentile := range percentiles {
+		position := percentile / 100 * float64(len(sorted)-1)
+		lower := int(position)
+		if lower >= len(sorted)-1 {
+			result[i] = sorted[len(sorted)-1]
+			continue
+		}
+		result[i] = sorted[lower] + (position-float64(lower))*(sorted[lower+1]-sorted[lower])
+	}
+	return result
+}
+
+// PercentileRank places value on a grid of values at the given percentiles,
+// interpolating between grid points. Values beyond the grid are extrapolated from
+// the nearest segment and kept within 0-100.
+func PercentileRank(grid, percentiles []float64, value float64) float64 {
+	if len(grid) == 0 {
+		return 0
+	}
+	if len(grid) == 1 {
+		return percentiles[0]
+	}
+
+	i := 1
+	for i < len(grid)-1 && value > grid[i] {
+		i++
+	}
+	if grid[i] == grid[i-1] {
+		if value < grid[i-1] {
+			return percentiles[i-1]
+		}
+		return percentiles[i]
+	}
+	share := (value - grid[i-1]) / (grid[i] - grid[i-1])
+	rank := percentiles[i-1] + share*(percentiles[i]-percentiles[i-1])
+	return math.Min(100, math.Max(0, rank))
+}
+
+// LaplaceNoise draws from a Laplace distribution centred on zero
+func LaplaceNoise(scale float64) float64 {
+	u := rand.Float64() - 0.5
+	if u == -0.5 {
+		return 0
+	}
+	return -scale * math.Copysign(math.Log(1-2*math.Abs(u)), u)
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// ForecastZ80 is the z-score of an 80% band around a forecast
+const ForecastZ80 = 1.2816
+
+// SeasonalModel is an exponentially weighted level with an additive offset for each
+// day of the week. Additive offsets stay stable when some days are zero or negative.
+type SeasonalModel struct {
+	Level   float64
+	Offsets [7]float64
+	// Sigma is the typical one-day-ahead forecast error
+	Sigma        float64
+	Observations int
+}
+
+// seasonalShrinkage pulls a weekday's offset towards zero until it has a few weeks of data
+const seasonalShrinkage = 2.0
+
+// FitSeasonalModel fits the model to a daily series. days[i] is the date of values[i]
+// and alpha (0-1) is how much weight the latest day gets in the level.
+func FitSeasonalModel(values []float64, days []time.Time, alpha float64) SeasonalModel {
+	var model SeasonalModel
+	model.Observations = len(values)
+	if len(values) == 0 {
+		return model
+	}
+
+	var mean float64
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+
+	var sums, counts [7]float64
+	for i, value := range values {
+		weekday := days[i].Weekday()
+		sums[weekday] += value - mean
+		counts[weekday]++
+	}
+	for weekday := range model.Offsets {
+		model.Offsets[weekday] = sums[weekday] / (counts[weekday] + seasonalShrinkage)
+	}
+
+	// Run the level over the deseasonalised series, keeping the one-step-ahead
+	// errors once the first week has warmed it up
+	model.Level = values[0] - model.Offsets[days[0].Weekday()]
+	var squaredErrors float64
+	var errorCount int
+	for i := 1; i < len(values); i++ {
+		offset := model.Offsets[days[i].Weekday()]
+		if i >= 7 {
+			forecastError := values[i] - (model.Level + offset)
+			squaredErrors += forecastError * forecastError
+			errorCount++
+		}
+		model.Level = alpha*(values[i]-offset) + (1-alpha)*model.Level
+	}
+
+	if errorCount >= 3 {
+		model.Sigma = math.Sqrt(squaredErrors / float64(errorCount))
+	} else {
+		var variance float64
+		for _, value := range values {
+			variance += (value - mean) * (value - mean)
+		}
+		model.Sigma = math.Sqrt(variance / float64(len(values)))
+	}
+	return model
+}
+
+// Predict is the expected value for a day
+func (m SeasonalModel) Predict(day time.Time) float64 {
+	return m.Level + m.Offsets[day.Weekday()]
+}
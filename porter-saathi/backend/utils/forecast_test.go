@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// dailySeries returns weeks of consecutive days from a Monday, valued by value(day)
+func dailySeries(weeks int, value func(day time.Time) float64) ([]float64, []time.Time) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC) // a Monday
+	var values []float64
+	var days []time.Time
+	for i := 0; i < weeks*7; i++ {
+		day := start.AddDate(0, 0, i)
+		days = append(days, day)
+		values = append(values, value(day))
+	}
+	return values, days
+}
+
+func TestFitSeasonalModel(t *testing.T) {
+	sunday := time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC)
+	monday := sunday.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name  string
+		weeks int
+		value func(day time.Time) float64
+		check func(t *testing.T, model SeasonalModel)
+	}{
+		{
+			name:  "flat",
+			weeks: 4,
+			value: func(time.Time) float64 { return 1500 },
+			check: func(t *testing.T, model SeasonalModel) {
+				if math.Abs(model.Predict(monday)-1500) > 1e-9 || model.Sigma > 1e-9 {
+					t.Errorf("Predict = %v, Sigma = %v, want 1500 and 0", model.Predict(monday), model.Sigma)
+				}
+			},
+		},
+		{
+			name:  "sundays off",
+			weeks: 8,
+			value: func(day time.Time) float64 {
+				if day.Weekday() == time.Sunday {
+					return 0
+				}
+				return 1400
+			},
+			check: func(t *testing.T, model SeasonalModel) {
+				if model.Predict(sunday) >= model.Predict(monday) {
+					t.Errorf("Sunday %v should be predicted below Monday %v", model.Predict(sunday), model.Predict(monday))
+				}
+				// Shrinkage keeps the offsets from reaching the full gap with only 8 weeks
+				if model.Predict(monday) < 1200 || model.Predict(sunday) > 400 {
+					t.Errorf("Monday %v and Sunday %v are too close to the mean", model.Predict(monday), model.Predict(sunday))
+				}
+			},
+		},
+		{
+			name:  "rising level",
+			weeks: 6,
+			value: func(day time.Time) float64 { return float64(day.YearDay()) * 10 },
+			check: func(t *testing.T, model SeasonalModel) {
+				mean := float64(1+42) / 2 * 10
+				if model.Level <= mean {
+					t.Errorf("Level = %v, want above the mean %v as recent days weigh more", model.Level, mean)
+				}
+				if model.Sigma <= 0 {
+					t.Errorf("Sigma = %v, want the level's lag to show as error", model.Sigma)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, days := dailySeries(tt.weeks, tt.value)
+			model := FitSeasonalModel(values, days, 0.3)
+			if model.Observations != len(values) {
+				t.Errorf("Observations = %d, want %d", model.Observations, len(values))
+			}
+			tt.check(t, model)
+		})
+	}
+}
+
+func TestFitSeasonalModelEmpty(t *testing.T) {
+	model := FitSeasonalModel(nil, nil, 0.3)
+	if model.Observations != 0 || model.Level != 0 || model.Sigma != 0 {
+		t.Errorf("FitSeasonalModel(nil) = %+v, want the zero model", model)
+	}
+}
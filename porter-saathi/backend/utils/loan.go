@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"porter-saathi-backend/models"
+)
+
+// maxLoanInstallments stops a schedule whose EMI barely covers the interest from running forever
+const maxLoanInstallments = 600
+
+// EMIAmount is the equal monthly installment that repays principal over months at
+// annualRate percent on the reducing balance, rounded up to the rupee
+func EMIAmount(principal, annualRate float64, months int) float64 {
+	if months <= 0 {
+		return 0
+	}
+	rate := annualRate / 1200
+	if rate == 0 {
+		return math.Ceil(principal / float64(months))
+	}
+	growth := math.Pow(1+rate, float64(months))
+	return math.Ceil(principal * rate * growth / (growth - 1))
+}
+
+// EMIDueDate is the due date of installment number (from 1). The EMI day is kept to
+// the last day of shorter months.
+func EMIDueDate(first time.Time, emiDay, number int) time.Time {
+	month := time.Date(first.Year(), first.Month()+time.Month(number-1), 1, 0, 0, 0, 0, first.Location())
+	lastDay := month.AddDate(0, 1, -1).Day()
+	return month.AddDate(0, 0, min(emiDay, lastDay)-1)
+}
+
+// AmortizationSchedule splits each EMI into interest and principal from installment
+// number start onwards, until balance is repaid. The last installment only pays what is left.
+func AmortizationSchedule(balance, annualRate, emi float64, start int, dueDate func(int) time.Time) []models.AmortizationRow {
+	rate := annualRate / 1200
+	rows := []models.AmortizationRow{}
+	for number := start; balance > 0.005 && len(rows) < maxLoanInstallments; number++ {
+		interest := RoundMoney(balance * rate)
+		payment := math.Min(emi, balance+interest)
+		principal := RoundMoney(payment - interest)
+		balance = RoundMoney(balance - principal)
+		rows = append(rows, models.AmortizationRow{
+			Number:    number,
+			DueDate:   dueDate(number),
+			EMI:       RoundMoney(payment),
+			Interest:  interest,
+			Principal: principal,
+			Balance:   math.Max(balance, 0),
+		})
+		if principal <= 0 {
+			// The EMI does not cover the interest, so the loan would never be repaid
+			break
+		}
+	}
+	return rows
+}
+
+// LoanState is a loan replayed from its payments up to a day
+type LoanState struct {
+	Balance         float64
+	UnpaidInterest  float64
+	PrincipalPaid   float64
+	InterestPaid    float64
+	TotalPaid       float64
+	EMIPaid         float64
+	InstallmentsDue int
+}
+
+// ReplayLoan walks the loan's due dates and payments in date order up to and including
+// until. Interest for a month is charged on its due date on the balance then
+// outstanding; payments clear charged interest first and the rest reduces principal,
+// so paying late costs more and prepaying saves interest. Due dates stop counting
+// once the loan is repaid.
+func ReplayLoan(principal, annualRate float64, dueDate func(int) time.Time, payments []models.LoanPayment, until time.Time) LoanState {
+	rate := annualRate / 1200
+	state := LoanState{Balance: principal}
+
+	sorted := append([]models.LoanPayment(nil), payments...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	next := 0
+	for number := 1; ; number++ {
+		due := dueDate(number)
+		// Payments made before this due date
+		for ; next < len(sorted) && sorted[next].Date.Before(due) && !sorted[next].Date.After(until); next++ {
+			state.applyPayment(sorted[next])
+		}
+		if due.After(until) || state.Balance <= 0.005 || number > maxLoanInstallments {
+			break
+		}
+		state.UnpaidInterest = RoundMoney(state.UnpaidInterest + state.Balance*rate)
+		state.InstallmentsDue++
+	}
+	for ; next < len(sorted) && !sorted[next].Date.After(until); next++ {
+		state.applyPayment(sorted[next])
+	}
+	return state
+}
+
+func (state *LoanState) applyPayment(payment models.LoanPayment) {
+	state.TotalPaid += payment.Amount
+	if payment.Type != models.LoanPaymentPrepayment {
+		state.EMIPaid += payment.Amount
+	}
+	interest := math.Min(payment.Amount, state.UnpaidInterest)
+	principal := math.Min(payment.Amount-interest, state.Balance)
+	state.UnpaidInterest = RoundMoney(state.UnpaidInterest - interest)
+	state.Balance = RoundMoney(state.Balance - principal)
+	state.InterestPaid += interest
+	state.PrincipalPaid += principal
+}
+
+// ScheduleInterest totals the interest in a schedule
+func ScheduleInterest(rows []models.AmortizationRow) float64 {
+	total := 0.0
+	for _, row := range rows {
+		total += row.Interest
+	}
+	return RoundMoney(total)
+}
+
+// RoundMoney rounds an amount to the nearest paisa
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"porter-saathi-backend/models"
+)
+
+func monthlyDueDates(first time.Time) func(int) time.Time {
+	return func(number int) time.Time {
+		return EMIDueDate(first, first.Day(), number)
+	}
+}
+
+func TestEMIAmount(t *testing.T) {
+	tests := []struct {
+		principal, rate float64
+		months          int
+		want            float64
+	}{
+		{100000, 12, 12, 8885},
+		{500000, 10.5, 36, 16252},
+		{12000, 0, 12, 1000},
+		{10000, 0, 3, 3334},
+		{100000, 12, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := EMIAmount(tt.principal, tt.rate, tt.months); got != tt.want {
+			t.Errorf("EMIAmount(%v, %v, %d) = %v, want %v", tt.principal, tt.rate, tt.months, got, tt.want)
+		}
+	}
+}
+
+func TestEMIDueDate(t *testing.T) {
+	first := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		number int
+		want   time.Time
+	}{
+		{1, time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{2, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{3, time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{4, time.Date(2024, time.April, 30, 0, 0, 0, 0, time.UTC)},
+		{13, time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := EMIDueDate(first, 31, tt.number); !got.Equal(tt.want) {
+			t.Errorf("EMIDueDate(%d) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestAmortizationSchedule(t *testing.T) {
+	dueDate := monthlyDueDates(time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name                string
+		balance, rate, emi  float64
+		start               int
+		wantRows            int
+		wantFirstInterest   float64
+		wantLastBalance     float64
+		wantPrincipalRepaid float64
+		wantShortLastEMI    bool
+	}{
+		{"year at 12%", 100000, 12, 8885, 1, 12, 1000, 0, 100000, true},
+		{"interest free", 12000, 0, 1000, 1, 12, 0, 0, 12000, false},
+		{"resumes mid loan", 50000, 12, 8885, 7, 6, 500, 0, 50000, true},
+		{"EMI below interest", 100000, 24, 1500, 1, 1, 2000, 100500, -500, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := AmortizationSchedule(tt.balance, tt.rate, tt.emi, tt.start, dueDate)
+			if len(rows) != tt.wantRows {
+				t.Fatalf("got %d rows, want %d", len(rows), tt.wantRows)
+			}
+			if rows[0].Number != tt.start || !rows[0].DueDate.Equal(dueDate(tt.start)) {
+				t.Errorf("first row is installment %d due %v, want %d due %v", rows[0].Number, rows[0].DueDate, tt.start, dueDate(tt.start))
+			}
+			if rows[0].Interest != tt.wantFirstInterest {
+				t.Errorf("first interest = %v, want %v", rows[0].Interest, tt.wantFirstInterest)
+			}
+			last := rows[len(rows)-1]
+			if last.Balance != tt.wantLastBalance {
+				t.Errorf("last balance = %v, want %v", last.Balance, tt.wantLastBalance)
+			}
+			repaid := 0.0
+			for _, row := range rows {
+				repaid += row.Principal
+				if math.Abs(row.EMI-(row.Interest+row.Principal)) > 0.005 {
+					t.Errorf("installment %d: EMI %v is not interest %v plus principal %v", row.Number, row.EMI, row.Interest, row.Principal)
+				}
+			}
+			if RoundMoney(repaid) != tt.wantPrincipalRepaid {
+				t.Errorf("principal repaid = %v, want %v", RoundMoney(repaid), tt.wantPrincipalRepaid)
+			}
+			if tt.wantShortLastEMI && last.EMI >= tt.emi {
+				t.Errorf("last EMI = %v, want only what was left, below %v", last.EMI, tt.emi)
+			}
+		})
+	}
+}
+
+func TestReplayLoan(t *testing.T) {
+	first := time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC)
+	dueDate := monthlyDueDates(first)
+	onTime := func(count int, amount float64) []models.LoanPayment {
+		var payments []models.LoanPayment
+		for number := 1; number <= count; number++ {
+			payments = append(payments, models.LoanPayment{Date: dueDate(number), Amount: amount, Type: models.LoanPaymentEMI})
+		}
+		return payments
+	}
+	scheduleInterest := ScheduleInterest(AmortizationSchedule(100000, 12, 8885, 1, dueDate))
+
+	tests := []struct {
+		name     string
+		payments []models.LoanPayment
+		until    time.Time
+		want     LoanState
+	}{
+		{
+			name:  "before the first due date",
+			until: first.AddDate(0, 0, -1),
+			want:  LoanState{Balance: 100000},
+		},
+		{
+			name:  "two EMIs missed",
+			until: dueDate(2),
+			want:  LoanState{Balance: 100000, UnpaidInterest: 2000, InstallmentsDue: 2},
+		},
+		{
+			name:     "repaid on time",
+			payments: onTime(12, 8885),
+			until:    dueDate(12),
+			want: LoanState{
+				PrincipalPaid: 100000, InterestPaid: scheduleInterest, TotalPaid: 12 * 8885, EMIPaid: 12 * 8885,
+				InstallmentsDue: 12,
+			},
+		},
+		{
+			name:     "prepaid before the first EMI",
+			payments: []models.LoanPayment{{Date: first.AddDate(0, 0, -10), Amount: 50000, Type: models.LoanPaymentPrepayment}},
+			until:    dueDate(1),
+			want:     LoanState{Balance: 50000, UnpaidInterest: 500, PrincipalPaid: 50000, TotalPaid: 50000, InstallmentsDue: 1},
+		},
+		{
+			name:     "payments after until are ignored",
+			payments: onTime(3, 8885),
+			until:    dueDate(1),
+			want:     LoanState{Balance: 92115, PrincipalPaid: 7885, InterestPaid: 1000, TotalPaid: 8885, EMIPaid: 8885, InstallmentsDue: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReplayLoan(100000, 12, dueDate, tt.payments, tt.until)
+			got.PrincipalPaid = RoundMoney(got.PrincipalPaid)
+			got.InterestPaid = RoundMoney(got.InterestPaid)
+			if got != tt.want {
+				t.Errorf("ReplayLoan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"porter-saathi-backend/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration is a one-off data fix, recorded by name in the migrations collection once applied
+type migration struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+var migrations = []migration{
+	{Name: "earnings-local-dates", Run: migrateEarningsLocalDates},
+	{Name: "earnings-sync-seq", Run: migrateEarningsSyncSeq},
+}
+
+// RunMigrations applies every migration that has not run against this database yet
+func RunMigrations() {
+	ctx := context.Background()
+	collection := config.GetDB().Collection("migrations")
+
+	for _, m := range migrations {
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": m.Name})
+		if err != nil {
+			log.Printf("Error checking migration %s: %v", m.Name, err)
+			return
+		}
+		if count > 0 {
+			continue
+		}
+
+		log.Printf("Running migration %s...", m.Name)
+		if err := m.Run(ctx); err != nil {
+			// Stop here so later migrations never run on top of a failed one
+			log.Printf("Migration %s failed: %v", m.Name, err)
+			return
+		}
+		if _, err := collection.InsertOne(ctx, bson.M{"_id": m.Name, "applied_at": time.Now()}); err != nil {
+			log.Printf("Error recording migration %s: %v", m.Name, err)
+			return
+		}
+		log.Printf("Migration %s completed", m.Name)
+	}
+}
+
+// migrateEarningsLocalDates gives drivers the default calendar settings and moves
+// earnings and trip dates to local midnight in the driver's timezone. Dates used to be
+// stored as UTC midnight (typed-in days) or as the moment of entry (older records).
+func migrateEarningsLocalDates(ctx context.Context) error {
+	db := config.GetDB()
+
+	_, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"timezone": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"timezone": DefaultTimezone}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("users").UpdateMany(ctx,
+		bson.M{"week_start": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"week_start": strings.ToLower(DefaultWeekStart.String())}},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"earnings", "trips"} {
+		collection := db.Collection(name)
+		userIDs, err := collection.Distinct(ctx, "user_id", bson.M{})
+		if err != nil {
+			return err
+		}
+
+		repaired := 0
+		for _, value := range userIDs {
+			userID, ok := value.(primitive.ObjectID)
+			if !ok {
+				continue
+			}
+
+			var user struct {
+				Timezone string `bson:"timezone"`
+			}
+			db.Collection("users").FindOne(ctx, bson.M{"_id": userID}, options.FindOne().SetProjection(bson.M{"timezone": 1})).Decode(&user)
+			loc := LoadTimezone(user.Timezone)
+
+			cursor, err := collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"date": 1}))
+			if err != nil {
+				return err
+			}
+			var records []struct {
+				ID   primitive.ObjectID `bson:"_id"`
+				Date time.Time          `bson:"date"`
+			}
+			if err := cursor.All(ctx, &records); err != nil {
+				return err
+			}
+
+			for _, record := range records {
+				date := localRecordDate(record.Date, loc)
+				if date.Equal(record.Date) {
+					continue
+				}
+				if _, err := collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{"date": date}}); err != nil {
+					return err
+				}
+				repaired++
+			}
+		}
+		log.Printf("Repaired %d %s dates", repaired, name)
+	}
+	return nil
+}
+
+// localRecordDate maps a stored date to local midnight of the day the driver meant
+func localRecordDate(stored time.Time, loc *time.Location) time.Time {
+	utc := stored.UTC()
+	if utc.Hour() == 0 && utc.Minute() == 0 && utc.Second() == 0 && utc.Nanosecond() == 0 {
+		// A YYYY-MM-DD parsed as UTC: keep the calendar day
+		return time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, loc)
+	}
+	return StartOfDay(stored, loc)
+}
+
+// migrateEarningsSyncSeq numbers existing earnings entries so the first sync can page through them
+func migrateEarningsSyncSeq(ctx context.Context) error {
+	collection := config.GetDB().Collection("earnings")
+	cursor, err := collection.Find(ctx,
+		bson.M{"sync_seq": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"user_id": 1}).SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return err
+	}
+	var records []struct {
+		ID     primitive.ObjectID `bson:"_id"`
+		UserID primitive.ObjectID `bson:"user_id"`
+	}
+	if err := cursor.All(ctx, &records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		seq, err := NextSyncSequence(ctx, record.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{"sync_seq": seq}})
+		ReleaseSyncSequence(ctx, record.UserID, seq)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Numbered %d earnings entries for sync", len(records))
+	return nil
+}
@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"porter-saathi-backend/models"
+)
+
+// Indic Unicode blocks share the ISCII layout, so one offset table covers
+// Devanagari, Telugu, Tamil and the other scripts drivers write in.
+var indicBlocks = []rune{0x0900, 0x0980, 0x0A00, 0x0A80, 0x0B00, 0x0B80, 0x0C00, 0x0C80, 0x0D00}
+
+var indicVowels = map[rune]string{
+	0x05: "a", 0x06: "aa", 0x07: "i", 0x08: "ii", 0x09: "u", 0x0A: "uu", 0x0B: "ri", 0x0C: "li",
+	0x0D: "e", 0x0E: "e", 0x0F: "e", 0x10: "ai", 0x11: "o", 0x12: "o", 0x13: "o", 0x14: "au",
+}
+
+var indicVowelSigns = map[rune]string{
+	0x3E: "aa", 0x3F: "i", 0x40: "ii", 0x41: "u", 0x42: "uu", 0x43: "ri", 0x44: "ri",
+	0x45: "e", 0x46: "e", 0x47: "e", 0x48: "ai", 0x49: "o", 0x4A: "o", 0x4B: "o", 0x4C: "au",
+}
+
+var indicConsonants = map[rune]string{
+	0x15: "k", 0x16: "kh", 0x17: "g", 0x18: "gh", 0x19: "n",
+	0x1A: "ch", 0x1B: "chh", 0x1C: "j", 0x1D: "jh", 0x1E: "n",
+	0x1F: "t", 0x20: "th", 0x21: "d", 0x22: "dh", 0x23: "n",
+	0x24: "t", 0x25: "th", 0x26: "d", 0x27: "dh", 0x28: "n", 0x29: "n",
+	0x2A: "p", 0x2B: "ph", 0x2C: "b", 0x2D: "bh", 0x2E: "m",
+	0x2F: "y", 0x30: "r", 0x31: "r", 0x32: "l", 0x33: "l", 0x34: "l", 0x35: "v",
+	0x36: "sh", 0x37: "sh", 0x38: "s", 0x39: "h",
+	0x58: "q", 0x59: "kh", 0x5A: "g", 0x5B: "z", 0x5C: "r", 0x5D: "rh", 0x5E: "f", 0x5F: "y",
+}
+
+const (
+	indicCandrabindu = 0x01
+	indicAnusvara    = 0x02
+	indicVisarga     = 0x03
+	indicVirama      = 0x4D
+)
+
+var nonLetterPattern = regexp.MustCompile(`[^a-z\s]`)
+
+// indicOffset returns the position of r within its Indic block, or -1
+func indicOffset(r rune) rune {
+	for _, base := range indicBlocks {
+		if r >= base && r < base+0x80 {
+			return r - base
+		}
+	}
+	return -1
+}
+
+// TransliterateToLatin romanises Indic-script text with a simple phonetic scheme.
+// Latin text passes through unchanged, so mixed input is safe.
+func TransliterateToLatin(text string) string {
+	var out strings.Builder
+	pendingVowel := false // a consonant was written and still carries its inherent "a"
+
+	flush := func() {
+		if pendingVowel {
+			out.WriteString("a")
+			pendingVowel = false
+		}
+	}
+
+	for _, r := range text {
+		offset := indicOffset(r)
+		if offset < 0 {
+			flush()
+			out.WriteRune(r)
+			continue
+		}
+
+		if consonant, ok := indicConsonants[offset]; ok {
+			flush()
+			out.WriteString(consonant)
+			pendingVowel = true
+			continue
+		}
+		if sign, ok := indicVowelSigns[offset]; ok {
+			out.WriteString(sign)
+			pendingVowel = false
+			continue
+		}
+		if vowel, ok := indicVowels[offset]; ok {
+			flush()
+			out.WriteString(vowel)
+			continue
+		}
+
+		switch offset {
+		case indicVirama:
+			pendingVowel = false
+		case indicAnusvara, indicCandrabindu:
+			flush()
+			out.WriteString("n")
+		case indicVisarga:
+			flush()
+			out.WriteString("h")
+		default:
+			if offset >= 0x66 && offset <= 0x6F {
+				flush()
+				out.WriteRune('0' + offset - 0x66)
+			}
+			// Nukta, length marks and other signs carry no sound of their own
+		}
+	}
+	flush()
+
+	return out.String()
+}
+
+// PhoneticKey reduces a romanised name token to a spelling-insensitive key,
+// so that "Rajesh", "Raajesh" and the romanised "राजेश" collapse together.
+func PhoneticKey(token string) string {
+	key := strings.ToLower(TransliterateToLatin(token))
+	key = nonLetterPattern.ReplaceAllString(key, "")
+
+	replacer := strings.NewReplacer(
+		"aa", "a", "ee", "i", "ii", "i", "oo", "u", "uu", "u",
+		"ph", "f", "w", "v", "q", "k", "z", "j", "x", "ks", "ck", "k",
+		"chh", "c", "ch", "c", "sh", "s", "kh", "k", "gh", "g",
+		"jh", "j", "th", "t", "dh", "d", "bh", "b",
+	)
+	key = replacer.Replace(key)
+
+	// Collapse doubled letters ("Kummar" vs "Kumar")
+	var collapsed []rune
+	for _, r := range key {
+		if len(collapsed) == 0 || collapsed[len(collapsed)-1] != r {
+			collapsed = append(collapsed, r)
+		}
+	}
+	key = string(collapsed)
+
+	// Silent trailing schwa and y/i endings vary freely between scripts
+	if len(key) > 2 {
+		key = strings.TrimSuffix(key, "a")
+	}
+	if strings.HasSuffix(key, "y") {
+		key = strings.TrimSuffix(key, "y") + "i"
+	}
+
+	return key
+}
+
+// nameTokens splits a name into words, treating dots as separators for initials
+func nameTokens(name string) []string {
+	name = strings.Map(func(r rune) rune {
+		if r == '.' || r == ',' || r == '-' {
+			return ' '
+		}
+		return r
+	}, name)
+
+	var tokens []string
+	for _, field := range strings.Fields(name) {
+		// Honorifics and relation prefixes are not part of the name itself
+		switch strings.ToLower(field) {
+		case "mr", "mrs", "ms", "shri", "sri", "smt", "kumari", "s/o", "d/o", "w/o", "c/o":
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// NameComparison is the word-by-word outcome of comparing two names
+type NameComparison struct {
+	Score    float64 // 0 to 1, for display and ranking
+	Status   string  // models.MatchStatusMatch, MatchStatusPartial or MatchStatusMismatch
+	Initials bool    // some words only matched as initials
+	MissingA int     // words of b with no counterpart in a
+	MissingB int     // words of a with no counterpart in b
+}
+
+// Words pair up when their keys agree, differ only in vowels, or one is an initial
+const (
+	tokenExact   = 1.0
+	tokenVowels  = 0.95
+	tokenInitial = 0.9
+)
+
+// CompareNames pairs the words of two personal names across scripts, tolerating
+// spelling variation, initials ("R. K. Sharma") and word order. Every word has to
+// pair with a word of the other name for a match: a word that pairs with nothing
+// while the other name also has a leftover word ("Rakesh Kumar" against "Rajesh Kumar")
+// is a mismatch, and a word that is only missing from one side, such as a surname,
+// or a word only matched as an initial makes the result partial at most.
+func CompareNames(a, b string) NameComparison {
+	tokensA := nameTokens(a)
+	tokensB := nameTokens(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return NameComparison{Status: models.MatchStatusMismatch, MissingA: len(tokensB), MissingB: len(tokensA)}
+	}
+
+	keysA := make([]string, len(tokensA))
+	for i, token := range tokensA {
+		keysA[i] = PhoneticKey(token)
+	}
+	keysB := make([]string, len(tokensB))
+	for i, token := range tokensB {
+		keysB[i] = PhoneticKey(token)
+	}
+
+	type pair struct {
+		a, b  int
+		score float64
+	}
+	var pairs []pair
+	for i, keyA := range keysA {
+		for j, keyB := range keysB {
+			if score := tokenSimilarity(keyA, keyB); score >= tokenInitial {
+				pairs = append(pairs, pair{i, j, score})
+			}
+		}
+	}
+	// Strongest pairs first; ties keep the words in their written order
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].score > pairs[j].score })
+
+	usedA := make([]bool, len(keysA))
+	usedB := make([]bool, len(keysB))
+	result := NameComparison{}
+	total := 0.0
+	paired := 0
+	for _, p := range pairs {
+		if usedA[p.a] || usedB[p.b] {
+			continue
+		}
+		usedA[p.a], usedB[p.b] = true, true
+		total += p.score
+		paired++
+		if p.score < tokenVowels {
+			result.Initials = true
+		}
+	}
+	result.MissingB = len(keysA) - paired
+	result.MissingA = len(keysB) - paired
+	result.Score = 2 * total / float64(len(keysA)+len(keysB))
+
+	switch {
+	case result.MissingA > 0 && result.MissingB > 0:
+		result.Status = models.MatchStatusMismatch
+	case result.MissingA > 0 || result.MissingB > 0 || result.Initials:
+		result.Status = models.MatchStatusPartial
+	default:
+		result.Status = models.MatchStatusMatch
+	}
+	return result
+}
+
+// NameSimilarity scores two personal names between 0 and 1 word by word; see CompareNames
+func NameSimilarity(a, b string) float64 {
+	return CompareNames(a, b).Score
+}
+
+// tokenSimilarity compares two phonetic keys. Only identical keys, keys that differ
+// in their vowels alone ("Mohammed" and "Mohamad") and initials score high enough to
+// pair; anything else, however close ("Rakesh" and "Rajesh"), is a different word.
+func tokenSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return tokenExact
+	}
+	if len(a) == 1 || len(b) == 1 {
+		if a[0] == b[0] {
+			return tokenInitial
+		}
+		return 0
+	}
+	if a[0] == b[0] && consonantSkeleton(a) == consonantSkeleton(b) {
+		return tokenVowels
+	}
+	return min(JaroWinkler(a, b), tokenInitial-0.01)
+}
+
+// consonantSkeleton drops the vowels after the first letter of a phonetic key
+func consonantSkeleton(key string) string {
+	var out strings.Builder
+	for i, r := range key {
+		if i > 0 && strings.ContainsRune("aeiou", r) {
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of two strings
+func JaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo := max(0, i-window)
+		hi := min(len(rb), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"testing"
+
+	"porter-saathi-backend/models"
+)
+
+func TestCompareNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		entered string
+		card    string
+		want    string
+	}{
+		{"identical", "Rajesh Kumar", "Rajesh Kumar", models.MatchStatusMatch},
+		{"case and spacing", "  rajesh   KUMAR ", "Rajesh Kumar", models.MatchStatusMatch},
+		{"honorific ignored", "Shri Rajesh Kumar", "Rajesh Kumar", models.MatchStatusMatch},
+		{"word order", "Sharma Rajesh", "Rajesh Sharma", models.MatchStatusMatch},
+		{"doubled letters", "Rajesh Kummar", "Rajesh Kumar", models.MatchStatusMatch},
+		{"long vowel spelling", "Raajesh Kumaar", "Rajesh Kumar", models.MatchStatusMatch},
+		{"vowel variant", "Mohammed Ali", "Mohamad Ali", models.MatchStatusMatch},
+		{"devanagari card", "Rajesh Kumar Sharma", "राजेश कुमार शर्मा", models.MatchStatusMatch},
+		{"different given name", "Rakesh Kumar", "Rajesh Kumar", models.MatchStatusMismatch},
+		{"different surname", "Rajesh Kumar", "Rajesh Verma", models.MatchStatusMismatch},
+		{"missing surname", "Rajesh", "Rajesh Kumar Sharma", models.MatchStatusPartial},
+		{"missing middle name", "Rajesh Sharma", "Rajesh Kumar Sharma", models.MatchStatusPartial},
+		{"extra word", "Rajesh Kumar Sharma", "Rajesh Sharma", models.MatchStatusPartial},
+		{"initials", "R. K. Sharma", "Rajesh Kumar Sharma", models.MatchStatusPartial},
+		{"wrong initial", "S. K. Sharma", "Rajesh Kumar Sharma", models.MatchStatusMismatch},
+		{"single wrong name", "Rakesh", "Rajesh Kumar Sharma", models.MatchStatusMismatch},
+		{"unrelated", "Suresh Patel", "Rajesh Kumar", models.MatchStatusMismatch},
+		{"empty", "", "Rajesh Kumar", models.MatchStatusMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareNames(tt.entered, tt.card)
+			if got.Status != tt.want {
+				t.Errorf("CompareNames(%q, %q) = %s (score %.2f), want %s", tt.entered, tt.card, got.Status, got.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareNamesScore(t *testing.T) {
+	exact := CompareNames("Rajesh Kumar", "Rajesh Kumar").Score
+	if exact != 1 {
+		t.Errorf("identical names scored %.2f, want 1", exact)
+	}
+	partial := CompareNames("Rajesh", "Rajesh Kumar Sharma").Score
+	mismatch := CompareNames("Rakesh Kumar", "Rajesh Kumar").Score
+	if partial >= exact || mismatch >= exact {
+		t.Errorf("partial %.2f and mismatch %.2f should score below an exact match", partial, mismatch)
+	}
+}
+
+func TestPhoneticKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Rajesh", "राजेश"},
+		{"Kumar", "कुमार"},
+		{"Sharma", "शर्मा"},
+		{"Rajesh", "Raajesh"},
+		{"Kumar", "Kummar"},
+	}
+
+	for _, tt := range tests {
+		if ka, kb := PhoneticKey(tt.a), PhoneticKey(tt.b); ka != kb {
+			t.Errorf("PhoneticKey(%q) = %q, PhoneticKey(%q) = %q, want equal", tt.a, ka, tt.b, kb)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"porter-saathi-backend/config"
+	"porter-saathi-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dayFields are the fields, per collection, holding days stored as local midnight in
+// the owner's timezone. Fleet vehicles belong to the owner's fleet rather than to them.
+var dayFields = []struct {
+	collection string
+	fields     []string
+}{
+	{"earnings", []string{"date"}},
+	{"trips", []string{"date"}},
+	{"odometer_readings", []string{"date"}},
+	{"fuel_fillups", []string{"date"}},
+	{"loans", []string{"disbursed_on", "first_emi_date"}},
+	{"loan_payments", []string{"date"}},
+	{"fleet_vehicles", []string{"insurance_expiry", "permit_expiry", "fitness_expiry", "puc_expiry"}},
+}
+
+// MoveRecordDays re-dates a driver's records when they change timezone: each keeps the
+// calendar day it had, now at local midnight in the new zone. Only values at midnight in
+// the old zone are moved, so it can be run again after a failure, or back the other way
+// to undo it. Moved earnings get a new sync sequence so devices pick up the new dates.
+func MoveRecordDays(ctx context.Context, userID primitive.ObjectID, from, to *time.Location) error {
+	db := config.GetDB()
+
+	fleetIDs, err := db.Collection("fleets").Distinct(ctx, "_id", bson.M{"owner_id": userID})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range dayFields {
+		collection := db.Collection(entry.collection)
+		filter := bson.M{"user_id": userID}
+		if entry.collection == "fleet_vehicles" {
+			if len(fleetIDs) == 0 {
+				continue
+			}
+			filter = bson.M{"fleet_id": bson.M{"$in": fleetIDs}}
+		}
+
+		projection := bson.M{}
+		for _, field := range entry.fields {
+			projection[field] = 1
+		}
+		cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(projection))
+		if err != nil {
+			return err
+		}
+		var records []bson.M
+		if err := cursor.All(ctx, &records); err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			set := bson.M{}
+			for _, field := range entry.fields {
+				stored, ok := record[field].(primitive.DateTime)
+				if !ok {
+					continue
+				}
+				if moved, ok := moveDay(stored.Time(), from, to); ok {
+					set[field] = moved
+				}
+			}
+			if len(set) == 0 {
+				continue
+			}
+			if err := setMovedDays(ctx, collection, userID, record["_id"], set); err != nil {
+				return err
+			}
+		}
+	}
+
+	return moveTaxProfileDays(ctx, userID, from, to)
+}
+
+// setMovedDays saves one record's new dates; earnings also take a sync sequence
+func setMovedDays(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, id interface{}, set bson.M) error {
+	if collection.Name() != "earnings" {
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+		return err
+	}
+
+	seq, err := NextSyncSequence(ctx, userID)
+	if err != nil {
+		return err
+	}
+	defer ReleaseSyncSequence(ctx, userID, seq)
+	set["sync_seq"] = seq
+	set["updated_at"] = time.Now()
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// moveTaxProfileDays re-dates the ownership periods of the driver's goods carriages
+func moveTaxProfileDays(ctx context.Context, userID primitive.ObjectID, from, to *time.Location) error {
+	collection := config.GetDB().Collection("tax_profiles")
+	var profile models.TaxProfile
+	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&profile)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range profile.Vehicles {
+		vehicle := &profile.Vehicles[i]
+		if moved, ok := moveDay(vehicle.OwnedFrom, from, to); ok {
+			vehicle.OwnedFrom = moved
+			changed = true
+		}
+		if vehicle.OwnedTo != nil {
+			if moved, ok := moveDay(*vehicle.OwnedTo, from, to); ok {
+				vehicle.OwnedTo = &moved
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": profile.ID}, bson.M{"$set": bson.M{"vehicles": profile.Vehicles}})
+	return err
+}
+
+// moveDay maps local midnight in one zone to local midnight of the same calendar day in
+// another. Values that are not midnight in the old zone are left alone.
+func moveDay(stored time.Time, from, to *time.Location) (time.Time, bool) {
+	local := stored.In(from)
+	if local.Hour() != 0 || local.Minute() != 0 || local.Second() != 0 || local.Nanosecond() != 0 {
+		return stored, false
+	}
+	moved := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, to)
+	return moved, !moved.Equal(stored)
+}
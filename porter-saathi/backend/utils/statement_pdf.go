@@ -0,0 +1,508 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"porter-saathi-backend/models"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// StatementDocument is everything printed on a PDF statement
+type StatementDocument struct {
+	Statement     *models.Statement
+	DriverName    string
+	Mobile        string
+	VehicleNumber string
+	Categories    []models.CategoryTotal
+	VerifyURL     string
+}
+
+// statementLabels are the local-language captions printed under the English ones
+var statementLabels = map[string]map[string]string{
+	"hi": {
+		"title":       "कमाई विवरण",
+		"driver":      "चालक",
+		"mobile":      "मोबाइल",
+		"vehicle":     "वाहन",
+		"period":      "अवधि",
+		"issued":      "जारी करने की तिथि",
+		"revenue":     "कुल आय",
+		"expenses":    "खर्च",
+		"netEarnings": "शुद्ध कमाई",
+		"trips":       "ट्रिप",
+		"month":       "महीना",
+		"monthly":     "मासिक योग",
+		"chart":       "मासिक शुद्ध कमाई",
+		"breakdown":   "खर्च का विवरण",
+		"verify":      "इस विवरण की जाँच के लिए QR कोड स्कैन करें",
+	},
+	"te": {
+		"title":       "సంపాదన వివరాలు",
+		"driver":      "డ్రైవర్",
+		"mobile":      "మొబైల్",
+		"vehicle":     "వాహనం",
+		"period":      "కాలం",
+		"issued":      "జారీ చేసిన తేదీ",
+		"revenue":     "మొత్తం ఆదాయం",
+		"expenses":    "ఖర్చులు",
+		"netEarnings": "నికర సంపాదన",
+		"trips":       "ట్రిప్పులు",
+		"month":       "నెల",
+		"monthly":     "నెలవారీ మొత్తాలు",
+		"chart":       "నెలవారీ నికర సంపాదన",
+		"breakdown":   "ఖర్చుల వివరాలు",
+		"verify":      "ఈ వివరాలను ధృవీకరించడానికి QR కోడ్‌ను స్కాన్ చేయండి",
+	},
+	"ta": {
+		"title":       "வருமான அறிக்கை",
+		"driver":      "ஓட்டுநர்",
+		"mobile":      "கைபேசி",
+		"vehicle":     "வாகனம்",
+		"period":      "காலம்",
+		"issued":      "வழங்கிய தேதி",
+		"revenue":     "மொத்த வருவாய்",
+		"expenses":    "செலவுகள்",
+		"netEarnings": "நிகர வருமானம்",
+		"trips":       "பயணங்கள்",
+		"month":       "மாதம்",
+		"monthly":     "மாதாந்திர மொத்தம்",
+		"chart":       "மாதாந்திர நிகர வருமானம்",
+		"breakdown":   "செலவு விவரம்",
+		"verify":      "இந்த அறிக்கையைச் சரிபார்க்க QR குறியீட்டை ஸ்கேன் செய்யவும்",
+	},
+}
+
+var englishStatementLabels = map[string]string{
+	"title":       "Earnings Statement",
+	"driver":      "Driver",
+	"mobile":      "Mobile",
+	"vehicle":     "Vehicle",
+	"period":      "Period",
+	"issued":      "Issued on",
+	"revenue":     "Total revenue",
+	"expenses":    "Expenses",
+	"netEarnings": "Net earnings",
+	"trips":       "Trips",
+	"month":       "Month",
+	"monthly":     "Monthly totals",
+	"chart":       "Monthly net earnings",
+	"breakdown":   "Expense breakdown",
+	"verify":      "Scan the QR code to verify this statement",
+}
+
+// statementFonts are the Noto fonts the Docker image installs for each language
+var statementFonts = map[string]string{
+	"hi": "NotoSansDevanagari-Regular.ttf",
+	"te": "NotoSansTelugu-Regular.ttf",
+	"ta": "NotoSansTamil-Regular.ttf",
+}
+
+// StatementLanguage maps an app language such as "hi-IN" to a statement language
+func StatementLanguage(language string) string {
+	code := strings.ToLower(strings.SplitN(strings.TrimSpace(language), "-", 2)[0])
+	if _, ok := statementLabels[code]; ok {
+		return code
+	}
+	return "en"
+}
+
+// userTextFonts pick a Noto font for text the driver typed, by the script it is written in.
+// NotoSans covers Latin with diacritics and is used for everything else.
+var userTextFonts = []struct {
+	script *unicode.RangeTable
+	file   string
+}{
+	{unicode.Devanagari, "NotoSansDevanagari-Regular.ttf"},
+	{unicode.Telugu, "NotoSansTelugu-Regular.ttf"},
+	{unicode.Tamil, "NotoSansTamil-Regular.ttf"},
+	{unicode.Bengali, "NotoSansBengali-Regular.ttf"},
+	{unicode.Gujarati, "NotoSansGujarati-Regular.ttf"},
+	{unicode.Gurmukhi, "NotoSansGurmukhi-Regular.ttf"},
+	{unicode.Kannada, "NotoSansKannada-Regular.ttf"},
+	{unicode.Malayalam, "NotoSansMalayalam-Regular.ttf"},
+	{unicode.Oriya, "NotoSansOriya-Regular.ttf"},
+}
+
+const userTextDefaultFont = "NotoSans-Regular.ttf"
+
+// loadStatementFont reads the font for a language from STATEMENT_FONT_DIR
+func loadStatementFont(language string) []byte {
+	name, ok := statementFonts[language]
+	if !ok {
+		return nil
+	}
+	return loadFontFile(name)
+}
+
+// loadFontFile reads a font file from STATEMENT_FONT_DIR
+func loadFontFile(name string) []byte {
+	dir := getEnv("STATEMENT_FONT_DIR", "/usr/share/fonts/noto")
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		log.Printf("Statement font %s not available: %v", name, err)
+		return nil
+	}
+	return data
+}
+
+// userTextFontFile returns the font file that can draw text, by its first Indic letter
+func userTextFontFile(text string) string {
+	for _, r := range text {
+		for _, font := range userTextFonts {
+			if unicode.Is(font.script, r) {
+				return font.file
+			}
+		}
+	}
+	return userTextDefaultFont
+}
+
+// statementWriter wraps the PDF with the bilingual label helpers
+type statementWriter struct {
+	pdf   *fpdf.Fpdf
+	local map[string]string
+	// userFonts maps a font file to the family it was registered as, or "" if it is missing
+	userFonts map[string]string
+}
+
+// RenderStatementPDF writes an A4 statement with English labels and, when the
+// font is available, the driver's language underneath. Driver-supplied details are
+// drawn in a Noto font matching their script. fpdf does not shape
+// Indic scripts, so conjuncts print in their decomposed form; the text stays
+// readable and the English captions remain authoritative.
+func RenderStatementPDF(doc StatementDocument, w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+
+	writer := &statementWriter{pdf: pdf, userFonts: make(map[string]string)}
+	language := StatementLanguage(doc.Statement.Language)
+	if font := loadStatementFont(language); font != nil {
+		pdf.AddUTF8FontFromBytes("local", "", font)
+		writer.local = statementLabels[language]
+	}
+
+	statement := doc.Statement
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Statement %s  |  Key %s  |  Page %d/{nb}", statement.ID.Hex(), statement.KeyID, pdf.PageNo()), "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	// Title
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, "Porter Saathi - "+englishStatementLabels["title"], "", 1, "L", false, 0, "")
+	writer.localLine("title", 12)
+	pdf.Ln(3)
+
+	// Driver details
+	details := [][2]string{
+		{"driver", doc.DriverName},
+		{"mobile", MaskMobile(doc.Mobile)},
+		{"vehicle", doc.VehicleNumber},
+		{"period", statement.From + " to " + statement.To},
+		{"issued", statement.CreatedAt.Format("02 Jan 2006 15:04 MST")},
+	}
+	for _, detail := range details {
+		writer.labelCell(detail[0], 45, 6)
+		writer.userText(detail[1], 10)
+		pdf.CellFormat(0, 6, detail[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Summary box
+	boxY := pdf.GetY()
+	pdf.SetFillColor(240, 245, 250)
+	pdf.Rect(15, boxY, 180, 22, "F")
+	summary := [][2]string{
+		{"revenue", FormatRupees(statement.Revenue)},
+		{"expenses", FormatRupees(statement.Expenses)},
+		{"netEarnings", FormatRupees(statement.NetEarnings)},
+		{"trips", fmt.Sprintf("%d", statement.Trips)},
+	}
+	for i, item := range summary {
+		x := 15 + float64(i)*45
+		pdf.SetXY(x+3, boxY+2)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(42, 4, englishStatementLabels[item[0]], "", 2, "L", false, 0, "")
+		if writer.local != nil {
+			pdf.SetFont("local", "", 7)
+			pdf.CellFormat(42, 4, writer.local[item[0]], "", 2, "L", false, 0, "")
+		}
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(42, 8, item[1], "", 0, "L", false, 0, "")
+	}
+	pdf.SetXY(15, boxY+26)
+
+	// Monthly totals table
+	writer.heading("monthly")
+	widths := []float64{40, 40, 40, 40, 20}
+	columns := []string{"month", "revenue", "expenses", "netEarnings", "trips"}
+	pdf.SetFillColor(225, 232, 240)
+	for i, column := range columns {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(widths[i], 6, englishStatementLabels[column], "", 0, "L", true, 0, "")
+	}
+	pdf.Ln(-1)
+	if writer.local != nil {
+		for i, column := range columns {
+			pdf.SetFont("local", "", 7)
+			pdf.CellFormat(widths[i], 5, writer.local[column], "", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.SetFont("Helvetica", "", 9)
+	for _, month := range statement.Months {
+		pdf.CellFormat(widths[0], 6, statementMonthLabel(month.Month), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 6, FormatRupees(month.Revenue), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 6, FormatRupees(month.Expenses), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 6, FormatRupees(month.NetEarnings), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[4], 6, fmt.Sprintf("%d", month.Trips), "B", 1, "L", false, 0, "")
+	}
+	pdf.Ln(5)
+
+	writer.monthlyChart(statement.Months)
+	writer.categoryBreakdown(doc.Categories)
+	writer.verification(doc)
+
+	return pdf.Output(w)
+}
+
+// userText selects a Unicode font that can draw text the driver supplied, such as a
+// name in Devanagari. The core Helvetica font only covers Latin-1, so it is only used
+// when the text fits in it and no Noto font is installed.
+func (s *statementWriter) userText(text string, size float64) {
+	file := userTextFontFile(text)
+	family, loaded := s.userFonts[file]
+	if !loaded {
+		if font := loadFontFile(file); font != nil {
+			family = "user" + strconv.Itoa(len(s.userFonts))
+			s.pdf.AddUTF8FontFromBytes(family, "", font)
+		}
+		s.userFonts[file] = family
+	}
+	if family == "" {
+		s.pdf.SetFont("Helvetica", "", size)
+		return
+	}
+	s.pdf.SetFont(family, "", size)
+}
+
+// localLine prints a local-language caption on its own line
+func (s *statementWriter) localLine(key string, size float64) {
+	if s.local == nil {
+		return
+	}
+	s.pdf.SetFont("local", "", size)
+	s.pdf.CellFormat(0, size*0.5, s.local[key], "", 1, "L", false, 0, "")
+}
+
+// labelCell prints "English / local" in a fixed-width cell
+func (s *statementWriter) labelCell(key string, width, height float64) {
+	x := s.pdf.GetX()
+	s.pdf.SetFont("Helvetica", "B", 10)
+	english := englishStatementLabels[key]
+	s.pdf.CellFormat(s.pdf.GetStringWidth(english)+1, height, english, "", 0, "L", false, 0, "")
+	if s.local != nil {
+		s.pdf.SetFont("local", "", 8)
+		s.pdf.CellFormat(0, height, "/ "+s.local[key], "", 0, "L", false, 0, "")
+	}
+	s.pdf.SetX(x + width)
+}
+
+// heading prints a section title with its local caption
+func (s *statementWriter) heading(key string) {
+	s.pdf.SetFont("Helvetica", "B", 12)
+	s.pdf.CellFormat(0, 7, englishStatementLabels[key], "", 1, "L", false, 0, "")
+	s.localLine(key, 9)
+	s.pdf.Ln(1)
+}
+
+// monthlyChart draws net earnings per month as bars, negative months below the axis
+func (s *statementWriter) monthlyChart(months []models.StatementMonth) {
+	if len(months) == 0 {
+		return
+	}
+	const chartHeight = 45.0
+	if s.pdf.GetY()+chartHeight+25 > 277 {
+		s.pdf.AddPage()
+	}
+	s.heading("chart")
+
+	var highest, lowest float64
+	for _, month := range months {
+		highest = max(highest, month.NetEarnings)
+		lowest = min(lowest, month.NetEarnings)
+	}
+	span := highest - lowest
+	if span <= 0 {
+		span = 1
+	}
+
+	top := s.pdf.GetY()
+	left, width := 15.0, 180.0
+	axis := top + chartHeight*highest/span
+	slot := width / float64(len(months))
+	barWidth := min(slot*0.6, 18)
+
+	s.pdf.SetDrawColor(160, 160, 160)
+	s.pdf.Line(left, axis, left+width, axis)
+	for i, month := range months {
+		height := chartHeight * month.NetEarnings / span
+		x := left + float64(i)*slot + (slot-barWidth)/2
+		if month.NetEarnings >= 0 {
+			s.pdf.SetFillColor(46, 125, 50)
+			s.pdf.Rect(x, axis-height, barWidth, height, "F")
+		} else {
+			s.pdf.SetFillColor(198, 40, 40)
+			s.pdf.Rect(x, axis, barWidth, -height, "F")
+		}
+	}
+
+	s.pdf.SetY(top + chartHeight + 1)
+	s.pdf.SetFont("Helvetica", "", 6)
+	for i, month := range months {
+		s.pdf.SetX(left + float64(i)*slot)
+		label := month.Month
+		if slot >= 14 {
+			label = statementMonthLabel(month.Month)
+		}
+		s.pdf.CellFormat(slot, 4, label, "", 0, "C", false, 0, "")
+	}
+	s.pdf.Ln(8)
+}
+
+// categoryBreakdown lists expenses by category with their share of the total
+func (s *statementWriter) categoryBreakdown(categories []models.CategoryTotal) {
+	if len(categories) == 0 {
+		return
+	}
+	if s.pdf.GetY()+float64(len(categories))*6+20 > 277 {
+		s.pdf.AddPage()
+	}
+	s.heading("breakdown")
+	s.pdf.SetFont("Helvetica", "", 9)
+	for _, category := range categories {
+		s.pdf.CellFormat(60, 6, categoryLabel(category.Category), "B", 0, "L", false, 0, "")
+		s.pdf.CellFormat(50, 6, FormatRupees(category.Amount), "B", 0, "L", false, 0, "")
+		s.pdf.CellFormat(30, 6, fmt.Sprintf("%.1f%%", category.Percentage), "B", 1, "L", false, 0, "")
+	}
+	s.pdf.Ln(5)
+}
+
+// verification prints the QR code and the signature a verifier can check
+func (s *statementWriter) verification(doc StatementDocument) {
+	const size = 35.0
+	if s.pdf.GetY()+size+10 > 277 {
+		s.pdf.AddPage()
+	}
+	top := s.pdf.GetY()
+	if err := drawQRCode(s.pdf, doc.VerifyURL, 15, top, size); err != nil {
+		log.Printf("Error drawing statement QR code: %v", err)
+	}
+
+	s.pdf.SetXY(15+size+5, top)
+	s.pdf.SetFont("Helvetica", "B", 10)
+	s.pdf.CellFormat(0, 6, englishStatementLabels["verify"], "", 2, "L", false, 0, "")
+	if s.local != nil {
+		s.pdf.SetFont("local", "", 8)
+		s.pdf.CellFormat(0, 5, s.local["verify"], "", 2, "L", false, 0, "")
+	}
+	s.pdf.SetFont("Helvetica", "", 7)
+	for _, line := range []string{
+		doc.VerifyURL,
+		"Statement ID: " + doc.Statement.ID.Hex(),
+		"Signing key: " + doc.Statement.KeyID + " (Ed25519)",
+		"SHA-256: " + doc.Statement.PayloadHash,
+	} {
+		s.pdf.CellFormat(0, 4, line, "", 2, "L", false, 0, "")
+	}
+	s.pdf.MultiCell(140, 3.5, "Signature: "+doc.Statement.Signature, "", "L", false)
+}
+
+// drawQRCode draws the code module by module so no image encoding is needed
+func drawQRCode(pdf *fpdf.Fpdf, contents string, x, y, size float64) error {
+	hints := map[gozxing.EncodeHintType]interface{}{
+		gozxing.EncodeHintType_ERROR_CORRECTION: "M",
+		gozxing.EncodeHintType_MARGIN:           0,
+	}
+	matrix, err := qrcode.NewQRCodeWriter().Encode(contents, gozxing.BarcodeFormat_QR_CODE, 0, 0, hints)
+	if err != nil {
+		return err
+	}
+	module := size / float64(matrix.GetWidth())
+	pdf.SetFillColor(0, 0, 0)
+	for row := 0; row < matrix.GetHeight(); row++ {
+		for col := 0; col < matrix.GetWidth(); col++ {
+			if matrix.Get(col, row) {
+				pdf.Rect(x+float64(col)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+	return nil
+}
+
+// statementMonthLabel turns "2024-03" into "Mar 2024"
+func statementMonthLabel(month string) string {
+	var year, number int
+	if _, err := fmt.Sscanf(month, "%d-%d", &year, &number); err != nil || number < 1 || number > 12 {
+		return month
+	}
+	return fmt.Sprintf("%s %d", []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}[number-1], year)
+}
+
+// FormatRupees formats an amount with Indian digit grouping. The core PDF fonts
+// have no rupee sign, so "Rs." is used.
+func FormatRupees(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	text := fmt.Sprintf("%.2f", amount)
+	whole, fraction := text[:len(text)-3], text[len(text)-3:]
+	if len(whole) > 3 {
+		head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+		var groups []string
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		if head != "" {
+			groups = append([]string{head}, groups...)
+		}
+		whole = strings.Join(groups, ",") + "," + tail
+	}
+	return "Rs. " + sign + whole + fraction
+}
+
+// MaskMobile keeps only the last four digits of a mobile number
+func MaskMobile(mobile string) string {
+	if len(mobile) <= 4 {
+		return mobile
+	}
+	return strings.Repeat("X", len(mobile)-4) + mobile[len(mobile)-4:]
+}
+
+// categoryLabel turns "loading_unloading" into "Loading unloading"
+func categoryLabel(category string) string {
+	label := strings.ReplaceAll(category, "_", " ")
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"porter-saathi-backend/models"
+)
+
+var (
+	statementKeyOnce sync.Once
+	statementKey     ed25519.PrivateKey
+)
+
+// ErrStatementSigningDisabled is returned when no statement signing key is configured
+var ErrStatementSigningDisabled = errors.New("statement signing key is not configured")
+
+// statementSigningKey loads the Ed25519 key from STATEMENT_SIGNING_KEY (a base64
+// 32-byte seed). There is no fallback: a key anyone could derive would let them
+// forge statements, so signed statements are unavailable until one is set.
+func statementSigningKey() (ed25519.PrivateKey, error) {
+	statementKeyOnce.Do(func() {
+		value := os.Getenv("STATEMENT_SIGNING_KEY")
+		if value == "" {
+			log.Printf("STATEMENT_SIGNING_KEY not set, signed PDF statements are disabled")
+			return
+		}
+		seed, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			log.Printf("STATEMENT_SIGNING_KEY must be a base64 encoded %d byte seed, signed PDF statements are disabled", ed25519.SeedSize)
+			return
+		}
+		statementKey = ed25519.NewKeyFromSeed(seed)
+	})
+	if statementKey == nil {
+		return nil, ErrStatementSigningDisabled
+	}
+	return statementKey, nil
+}
+
+// StatementSigningConfigured reports whether signed statements can be issued
+func StatementSigningConfigured() bool {
+	_, err := statementSigningKey()
+	return err == nil
+}
+
+// StatementPublicKey returns the base64 public key verifiers can use offline
+func StatementPublicKey() (string, error) {
+	key, err := statementSigningKey()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), nil
+}
+
+// StatementKeyID identifies the signing key so a rotated key can be told apart
+func StatementKeyID() (string, error) {
+	key, err := statementSigningKey()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// statementPayload is the canonical text that gets signed. Amounts are fixed to
+// two decimals so a round trip through the database cannot change the payload.
+func statementPayload(statement *models.Statement) []byte {
+	var b strings.Builder
+	b.WriteString("porter-saathi-statement:v1\n")
+	fmt.Fprintf(&b, "id:%s\n", statement.ID.Hex())
+	fmt.Fprintf(&b, "user:%s\n", statement.UserID.Hex())
+	fmt.Fprintf(&b, "period:%s..%s\n", statement.From, statement.To)
+	fmt.Fprintf(&b, "totals:%.2f|%.2f|%.2f|%d\n", statement.Revenue, statement.Expenses, statement.NetEarnings, statement.Trips)
+	fmt.Fprintf(&b, "issued:%s\n", statement.CreatedAt.UTC().Format(time.RFC3339))
+	for _, month := range statement.Months {
+		fmt.Fprintf(&b, "month:%s|%.2f|%.2f|%.2f|%d\n", month.Month, month.Revenue, month.Expenses, month.NetEarnings, month.Trips)
+	}
+	return []byte(b.String())
+}
+
+// SignStatement fills in the statement's payload hash, signature and key ID
+func SignStatement(statement *models.Statement) error {
+	key, err := statementSigningKey()
+	if err != nil {
+		return err
+	}
+	keyID, _ := StatementKeyID()
+
+	payload := statementPayload(statement)
+	sum := sha256.Sum256(payload)
+	statement.PayloadHash = hex.EncodeToString(sum[:])
+	statement.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	statement.KeyID = keyID
+	return nil
+}
+
+// VerifyStatement checks a stored statement against its signature
+func VerifyStatement(statement *models.Statement) bool {
+	key, err := statementSigningKey()
+	if err != nil {
+		return false
+	}
+	if keyID, _ := StatementKeyID(); statement.KeyID != keyID {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(statement.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key.Public().(ed25519.PublicKey), statementPayload(statement), signature)
+}
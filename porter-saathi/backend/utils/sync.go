@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"porter-saathi-backend/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// syncPendingTimeout is how long a handed-out sequence may stay unwritten before syncs
+// stop waiting for it, so a request that died mid-write cannot stall a driver's sync
+const syncPendingTimeout = time.Minute
+
+// syncCounter is the driver's document in sync_counters
+type syncCounter struct {
+	Seq     int64 `bson:"seq"`
+	Pending []struct {
+		Seq int64     `bson:"seq"`
+		At  time.Time `bson:"at"`
+	} `bson:"pending"`
+}
+
+// NextSyncSequence hands out the driver's next sync sequence number. Every write to
+// an earnings entry takes one, so "everything after sequence N" is the delta a device needs.
+// The number stays pending until ReleaseSyncSequence is called once the write is done.
+func NextSyncSequence(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	now := time.Now()
+	var counter syncCounter
+	err := config.GetDB().Collection("sync_counters").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": userID},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"seq": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$seq", 0}}, 1}}}}},
+			// Pending entries older than the timeout are dropped as they are no longer waited for
+			{{Key: "$set", Value: bson.M{"pending": bson.M{"$concatArrays": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$pending", bson.A{}}},
+					"cond":  bson.M{"$gt": bson.A{"$$this.at", now.Add(-syncPendingTimeout)}},
+				}},
+				bson.A{bson.M{"seq": "$seq", "at": now}},
+			}}}}},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	return counter.Seq, err
+}
+
+// ReleaseSyncSequence marks a sequence number's write as finished, whether it was saved or not
+func ReleaseSyncSequence(ctx context.Context, userID primitive.ObjectID, seq int64) error {
+	_, err := config.GetDB().Collection("sync_counters").UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$pull": bson.M{"pending": bson.M{"seq": seq}}},
+	)
+	return err
+}
+
+// SyncLowWaterMark is the highest sequence number a sync may read up to: every write
+// numbered at or below it has finished. Writes can finish out of order, so reading past
+// a pending number could move a device's cursor beyond a change it has not seen yet.
+func SyncLowWaterMark(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	var counter syncCounter
+	err := config.GetDB().Collection("sync_counters").FindOne(ctx, bson.M{"_id": userID}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	low := counter.Seq
+	cutoff := time.Now().Add(-syncPendingTimeout)
+	for _, pending := range counter.Pending {
+		if pending.At.After(cutoff) && pending.Seq-1 < low {
+			low = pending.Seq - 1
+		}
+	}
+	return low, nil
+}
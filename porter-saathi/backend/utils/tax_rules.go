@@ -0,0 +1,260 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"porter-saathi-backend/models"
+)
+
+//go:embed data/tax_rules.json
+var defaultTaxRules []byte
+
+// TaxYearRules are the presumptive rates, slabs and advance tax dates for one financial year
+type TaxYearRules struct {
+	Presumptive struct {
+		HeavyGVWThresholdKg     float64 `json:"heavyGvwThresholdKg"`
+		HeavyPerTonPerMonth     float64 `json:"heavyPerTonPerMonth"`
+		OtherPerVehiclePerMonth float64 `json:"otherPerVehiclePerMonth"`
+		MaxVehicles             int     `json:"maxVehicles"`
+	} `json:"presumptive"`
+	Slabs []struct {
+		UpTo *float64 `json:"upTo"`
+		Rate float64  `json:"rate"`
+	} `json:"slabs"`
+	Rebate struct {
+		IncomeUpTo float64 `json:"incomeUpTo"`
+		MaxRebate  float64 `json:"maxRebate"`
+	} `json:"rebate"`
+	CessPercent float64 `json:"cessPercent"`
+	AdvanceTax  struct {
+		Threshold    float64 `json:"threshold"`
+		Installments []struct {
+			DueDate           string  `json:"dueDate"` // MM-DD
+			CumulativePercent float64 `json:"cumulativePercent"`
+		} `json:"installments"`
+	} `json:"advanceTax"`
+}
+
+var (
+	taxRulesOnce sync.Once
+	taxRules     map[string]TaxYearRules
+)
+
+// loadTaxRules reads TAX_RULES_PATH when set, so new budgets can be applied without a
+// release, and the bundled data/tax_rules.json otherwise
+func loadTaxRules() map[string]TaxYearRules {
+	taxRulesOnce.Do(func() {
+		data := defaultTaxRules
+		if path := os.Getenv("TAX_RULES_PATH"); path != "" {
+			if custom, err := os.ReadFile(path); err == nil {
+				data = custom
+			} else {
+				log.Printf("Error reading TAX_RULES_PATH, using bundled tax rules: %v", err)
+			}
+		}
+
+		var file struct {
+			FinancialYears map[string]TaxYearRules `json:"financialYears"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil || len(file.FinancialYears) == 0 {
+			log.Printf("Invalid tax rules, using bundled tax rules: %v", err)
+			json.Unmarshal(defaultTaxRules, &file)
+		}
+		taxRules = file.FinancialYears
+	})
+	return taxRules
+}
+
+// TaxRulesFor returns the rules for a financial year such as "2024-25", falling back
+// to the closest earlier year (or the earliest known) when it is not listed
+func TaxRulesFor(financialYear string) (TaxYearRules, string) {
+	rules := loadTaxRules()
+	if year, ok := rules[financialYear]; ok {
+		return year, financialYear
+	}
+
+	years := make([]string, 0, len(rules))
+	for year := range rules {
+		years = append(years, year)
+	}
+	sort.Strings(years)
+	chosen := years[0]
+	for _, year := range years {
+		if year <= financialYear {
+			chosen = year
+		}
+	}
+	return rules[chosen], chosen
+}
+
+// FinancialYearOf names the April-March financial year a date falls in, e.g. "2024-25"
+func FinancialYearOf(date time.Time) string {
+	start := date.Year()
+	if date.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// ParseFinancialYear turns "2024-25" into [1 Apr 2024, 1 Apr 2025) in loc
+func ParseFinancialYear(financialYear string, loc *time.Location) (time.Time, time.Time, error) {
+	parts := strings.Split(financialYear, "-")
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("financial year must look like 2024-25")
+	}
+	start, err := strconv.Atoi(parts[0])
+	if err != nil || start < 2000 || start > 2100 {
+		return time.Time{}, time.Time{}, fmt.Errorf("financial year must look like 2024-25")
+	}
+	if end, err := strconv.Atoi(parts[1]); err != nil || end != (start+1)%100 {
+		return time.Time{}, time.Time{}, fmt.Errorf("financial year must look like 2024-25")
+	}
+	from := time.Date(start, time.April, 1, 0, 0, 0, 0, loc)
+	return from, from.AddDate(1, 0, 0), nil
+}
+
+// PresumptiveIncome is a vehicle's Section 44AE income for the year: a rate per ton
+// of gross vehicle weight per month for heavy goods vehicles, a flat rate per month
+// otherwise. Any part of a month owned counts as a whole month.
+func PresumptiveIncome(vehicle models.TaxVehicle, rules TaxYearRules, start, end time.Time) models.VehiclePresumptiveIncome {
+	income := models.VehiclePresumptiveIncome{
+		VehicleNumber:        vehicle.VehicleNumber,
+		GrossVehicleWeightKg: vehicle.GrossVehicleWeightKg,
+		HeavyGoodsVehicle:    vehicle.GrossVehicleWeightKg > rules.Presumptive.HeavyGVWThresholdKg,
+		MonthsOwned:          MonthsOwned(vehicle, start, end),
+	}
+	if income.HeavyGoodsVehicle {
+		// Per ton or part of a ton
+		income.RatePerMonth = math.Ceil(vehicle.GrossVehicleWeightKg/1000) * rules.Presumptive.HeavyPerTonPerMonth
+	} else {
+		income.RatePerMonth = rules.Presumptive.OtherPerVehiclePerMonth
+	}
+	income.Income = income.RatePerMonth * float64(income.MonthsOwned)
+	return income
+}
+
+// MonthsOwned counts the calendar months of [start, end) in which the vehicle was owned for any time
+func MonthsOwned(vehicle models.TaxVehicle, start, end time.Time) int {
+	months := 0
+	for month := start; month.Before(end); month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, 0)
+		if !vehicle.OwnedFrom.Before(monthEnd) {
+			continue
+		}
+		if vehicle.OwnedTo != nil && vehicle.OwnedTo.Before(month) {
+			continue
+		}
+		months++
+	}
+	return months
+}
+
+// MaxVehiclesOwnedAtOnce is the most goods carriages owned on any one day of [start, end).
+// Ownership runs from OwnedFrom through the whole OwnedTo day.
+func MaxVehiclesOwnedAtOnce(vehicles []models.TaxVehicle, start, end time.Time) int {
+	type change struct {
+		at    time.Time
+		delta int
+	}
+	var changes []change
+	for _, vehicle := range vehicles {
+		from := vehicle.OwnedFrom
+		if from.Before(start) {
+			from = start
+		}
+		until := end
+		if vehicle.OwnedTo != nil && vehicle.OwnedTo.AddDate(0, 0, 1).Before(end) {
+			until = vehicle.OwnedTo.AddDate(0, 0, 1)
+		}
+		if !from.Before(until) {
+			continue
+		}
+		changes = append(changes, change{from, 1}, change{until, -1})
+	}
+
+	// Handle an ownership ending before one starting at the same moment: they did not overlap
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].at.Equal(changes[j].at) {
+			return changes[i].delta < changes[j].delta
+		}
+		return changes[i].at.Before(changes[j].at)
+	})
+	owned, most := 0, 0
+	for _, c := range changes {
+		owned += c.delta
+		most = max(most, owned)
+	}
+	return most
+}
+
+// ComputeIncomeTax applies the year's slabs, rebate and cess to an income rounded to
+// the nearest ten rupees. Marginal relief near the rebate limit is not modelled.
+func ComputeIncomeTax(income float64, rules TaxYearRules) models.TaxComputation {
+	taxable := math.Max(math.Round(income/10)*10, 0)
+	computation := models.TaxComputation{TaxableIncome: taxable, Slabs: []models.SlabTax{}}
+
+	lower := 0.0
+	for _, slab := range rules.Slabs {
+		upper := math.Inf(1)
+		if slab.UpTo != nil {
+			upper = *slab.UpTo
+		}
+		if taxable > lower {
+			portion := math.Min(taxable, upper) - lower
+			tax := math.Round(portion * slab.Rate / 100)
+			computation.Slabs = append(computation.Slabs, models.SlabTax{From: lower, UpTo: slab.UpTo, Rate: slab.Rate, Tax: tax})
+			computation.TaxBeforeRebate += tax
+		}
+		lower = upper
+	}
+
+	if taxable <= rules.Rebate.IncomeUpTo {
+		computation.Rebate = math.Min(computation.TaxBeforeRebate, rules.Rebate.MaxRebate)
+	}
+	afterRebate := computation.TaxBeforeRebate - computation.Rebate
+	computation.Cess = math.Round(afterRebate * rules.CessPercent / 100)
+	computation.TotalTax = afterRebate + computation.Cess
+	return computation
+}
+
+// AdvanceTaxSchedule spreads a year's tax over the installment dates. Installments
+// in June, September and December fall in the year's first calendar year, March in the second.
+func AdvanceTaxSchedule(totalTax float64, rules TaxYearRules, start time.Time, now time.Time) []models.AdvanceTaxInstallment {
+	schedule := []models.AdvanceTaxInstallment{}
+	paidBefore := 0.0
+	for _, installment := range rules.AdvanceTax.Installments {
+		var month, day int
+		if _, err := fmt.Sscanf(installment.DueDate, "%d-%d", &month, &day); err != nil {
+			continue
+		}
+		year := start.Year()
+		if time.Month(month) < time.April {
+			year++
+		}
+		due := time.Date(year, time.Month(month), day, 0, 0, 0, 0, start.Location())
+		cumulative := math.Round(totalTax * installment.CumulativePercent / 100)
+		status := models.InstallmentUpcoming
+		if due.AddDate(0, 0, 1).Before(now) {
+			status = models.InstallmentPast
+		}
+		schedule = append(schedule, models.AdvanceTaxInstallment{
+			DueDate:           due,
+			CumulativePercent: installment.CumulativePercent,
+			CumulativeAmount:  cumulative,
+			Amount:            cumulative - paidBefore,
+			Status:            status,
+		})
+		paidBefore = cumulative
+	}
+	return schedule
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"porter-saathi-backend/models"
+)
+
+func TestComputeIncomeTax(t *testing.T) {
+	rules, year := TaxRulesFor("2024-25")
+	if year != "2024-25" {
+		t.Fatalf("TaxRulesFor(2024-25) picked %s", year)
+	}
+
+	tests := []struct {
+		name             string
+		income           float64
+		wantTaxable      float64
+		wantBeforeRebate float64
+		wantRebate       float64
+		wantCess         float64
+		wantTotal        float64
+	}{
+		{"below the first slab", 250000, 250000, 0, 0, 0, 0},
+		{"covered by the rebate", 654321, 654320, 17716, 17716, 0, 0},
+		{"at the rebate limit", 700000, 700000, 20000, 20000, 0, 0},
+		{"just above the rebate limit", 700010, 700010, 20001, 0, 800, 20801},
+		{"ten lakh", 1000000, 1000000, 50000, 0, 2000, 52000},
+		{"top slab", 1600000, 1600000, 170000, 0, 6800, 176800},
+		{"loss", -50000, 0, 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeIncomeTax(tt.income, rules)
+			if got.TaxableIncome != tt.wantTaxable || got.TaxBeforeRebate != tt.wantBeforeRebate ||
+				got.Rebate != tt.wantRebate || got.Cess != tt.wantCess || got.TotalTax != tt.wantTotal {
+				t.Errorf("ComputeIncomeTax(%v) = %+v, want taxable %v, before rebate %v, rebate %v, cess %v, total %v",
+					tt.income, got, tt.wantTaxable, tt.wantBeforeRebate, tt.wantRebate, tt.wantCess, tt.wantTotal)
+			}
+			slabTotal := 0.0
+			for _, slab := range got.Slabs {
+				slabTotal += slab.Tax
+			}
+			if slabTotal != got.TaxBeforeRebate {
+				t.Errorf("slabs add up to %v, want %v", slabTotal, got.TaxBeforeRebate)
+			}
+		})
+	}
+}
+
+func TestMaxVehiclesOwnedAtOnce(t *testing.T) {
+	start := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	day := func(month time.Month, d int) time.Time {
+		year := 2024
+		if month < time.April {
+			year = 2025
+		}
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	until := func(month time.Month, d int) *time.Time {
+		value := day(month, d)
+		return &value
+	}
+
+	tests := []struct {
+		name     string
+		vehicles []models.TaxVehicle
+		want     int
+	}{
+		{"none", nil, 0},
+		{"one all year", []models.TaxVehicle{{OwnedFrom: day(time.January, 1).AddDate(-3, 0, 0)}}, 1},
+		{
+			name: "replaced the next day",
+			vehicles: []models.TaxVehicle{
+				{OwnedFrom: start, OwnedTo: until(time.September, 30)},
+				{OwnedFrom: day(time.October, 1)},
+			},
+			want: 1,
+		},
+		{
+			name: "bought on the day the other was sold",
+			vehicles: []models.TaxVehicle{
+				{OwnedFrom: start, OwnedTo: until(time.September, 30)},
+				{OwnedFrom: day(time.September, 30)},
+			},
+			want: 2,
+		},
+		{
+			name: "eleven owned but never more than ten at once",
+			vehicles: append(
+				repeatVehicles(10, models.TaxVehicle{OwnedFrom: start, OwnedTo: until(time.June, 30)}),
+				models.TaxVehicle{OwnedFrom: day(time.July, 1)},
+			),
+			want: 10,
+		},
+		{
+			name: "sold before the year",
+			vehicles: []models.TaxVehicle{
+				{OwnedFrom: start.AddDate(-2, 0, 0), OwnedTo: until(time.March, 31)},
+			},
+			want: 1,
+		},
+		{
+			name: "outside the year",
+			vehicles: []models.TaxVehicle{
+				{OwnedFrom: start.AddDate(-2, 0, 0), OwnedTo: func() *time.Time { value := start.AddDate(0, 0, -1); return &value }()},
+				{OwnedFrom: end},
+			},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaxVehiclesOwnedAtOnce(tt.vehicles, start, end); got != tt.want {
+				t.Errorf("MaxVehiclesOwnedAtOnce() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func repeatVehicles(count int, vehicle models.TaxVehicle) []models.TaxVehicle {
+	vehicles := make([]models.TaxVehicle, count)
+	for i := range vehicles {
+		vehicles[i] = vehicle
+	}
+	return vehicles
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"strings"
+	"time"
+
+	// The Alpine image has no zoneinfo files, so embed the timezone database
+	_ "time/tzdata"
+)
+
+// DefaultTimezone is used for drivers who have not chosen a timezone
+const DefaultTimezone = "Asia/Kolkata"
+
+// DefaultWeekStart is used for drivers who have not chosen a week start
+const DefaultWeekStart = time.Monday
+
+// LoadTimezone returns the named IANA zone, falling back to IST for empty or unknown names
+func LoadTimezone(name string) *time.Location {
+	if name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.FixedZone("IST", 5*60*60+30*60)
+	}
+	return loc
+}
+
+// ValidTimezone reports whether name is a loadable IANA zone
+func ValidTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return name != "" && err == nil
+}
+
+// ParseWeekday parses an English weekday name such as "monday" or "Sun"
+func ParseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// WeekStartOrDefault parses a stored week start, falling back to Monday
+func WeekStartOrDefault(name string) time.Weekday {
+	if day, ok := ParseWeekday(name); ok {
+		return day
+	}
+	return DefaultWeekStart
+}
+
+// StartOfDay returns local midnight of the day t falls on in loc
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// StartOfWeek returns local midnight of the first day of t's week
+func StartOfWeek(t time.Time, loc *time.Location, weekStart time.Weekday) time.Time {
+	day := StartOfDay(t, loc)
+	offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// ParseLocalDate parses a YYYY-MM-DD date as midnight in loc
+func ParseLocalDate(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
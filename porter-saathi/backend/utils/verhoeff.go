@@ -0,0 +1,49 @@
+package utils
+
+// Verhoeff tables used by UIDAI for the Aadhaar check digit
+var (
+	verhoeffMultiplication = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffPermutation = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// VerhoeffValid reports whether a digit string ends in a correct Verhoeff check digit
+func VerhoeffValid(number string) bool {
+	if number == "" {
+		return false
+	}
+
+	check := 0
+	for i := 0; i < len(number); i++ {
+		digit := number[len(number)-1-i]
+		if digit < '0' || digit > '9' {
+			return false
+		}
+		check = verhoeffMultiplication[check][verhoeffPermutation[i%8][digit-'0']]
+	}
+	return check == 0
+}
+
+// ValidAadhaarNumber checks length, the reserved leading digits and the check digit
+func ValidAadhaarNumber(number string) bool {
+	return len(number) == 12 && number[0] >= '2' && VerhoeffValid(number)
+}
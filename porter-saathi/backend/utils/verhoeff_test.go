@@ -0,0 +1,48 @@
+package utils
+
+import "testing"
+
+func TestVerhoeffValid(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"2363", true},
+		{"2364", false},
+		{"499118665246", true},
+		{"499118665245", false},
+		{"499118665264", false}, // transposed digits
+		{"", false},
+		{"4991 1866 5246", false},
+		{"49911866524a", false},
+	}
+
+	for _, tt := range tests {
+		if got := VerhoeffValid(tt.number); got != tt.want {
+			t.Errorf("VerhoeffValid(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestValidAadhaarNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+		want   bool
+	}{
+		{"valid", "499118665246", true},
+		{"valid starting with 2", "234123412346", true},
+		{"wrong check digit", "499118665247", false},
+		{"starts with 1", "123412341234", false},
+		{"too short", "49911866524", false},
+		{"too long", "4991186652460", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidAadhaarNumber(tt.number); got != tt.want {
+				t.Errorf("ValidAadhaarNumber(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}